@@ -13,11 +13,22 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/artistcache"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/ephemeral"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/execution"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/genreregistry"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/library"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/plan"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/playlistcache"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/service"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
 )
 
 func main() {
@@ -44,36 +55,138 @@ func main() {
 		Msg("Configuration loaded")
 
 	// Initialize Spotify client
-	spotifyClient := spotifyClient.NewClient(
+	sc := spotifyClient.NewClient(
 		cfg.Spotify.ClientID,
 		cfg.Spotify.ClientSecret,
 		cfg.Spotify.RedirectURL,
+		cfg.Spotify.UsePKCE,
+		spotifyClient.RateLimits{
+			Rate:      cfg.Spotify.RateLimit.Rate,
+			Burst:     cfg.Spotify.RateLimit.Burst,
+			UserRate:  cfg.Spotify.RateLimit.UserRate,
+			UserBurst: cfg.Spotify.RateLimit.UserBurst,
+
+			Concurrency: cfg.Spotify.RateLimit.FetchConcurrency,
+		},
 	)
-	log.Info().Msg("Spotify client initialized")
+	log.Info().
+		Bool("pkce", cfg.Spotify.UsePKCE).
+		Float64("rateLimit", cfg.Spotify.RateLimit.Rate).
+		Float64("userRateLimit", cfg.Spotify.RateLimit.UserRate).
+		Msg("Spotify client initialized")
+
+	// In demo mode Spotify is replaced by an in-process fake serving a generated library
+	if cfg.Demo.Enabled {
+		demo := fake.NewServer(fake.Options{
+			UserID:     "demo-user",
+			LikedSongs: cfg.Demo.LikedSongs,
+			Artists:    cfg.Demo.Artists,
+			Latency:    cfg.Demo.Latency,
+			Realistic:  true,
+		})
+		defer demo.Close()
+
+		sc.SetBaseURL(demo.BaseURL())
+		sc.SetAuthEndpoint(demo.AuthURL(cfg.Spotify.RedirectURL), demo.TokenURL())
+		log.Warn().
+			Int("likedSongs", cfg.Demo.LikedSongs).
+			Int("artists", cfg.Demo.Artists).
+			Msg("Demo mode, serving a generated library instead of Spotify")
+	}
+
+	// Initialize plan store
+	planStore := plan.NewStore(plan.DefaultTTL)
+	log.Info().Msg("Plan store initialized")
 
-	// Initialize session store
-	sessionStore := session.NewStore()
-	log.Info().Msg("Session store initialized")
+	// Open the database holding durable state
+	db, err := storage.Open(cfg.Storage.Path)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", cfg.Storage.Path).Msg("Failed to open database")
+	}
+	defer db.Close()
+	log.Info().Str("path", cfg.Storage.Path).Msg("Database opened")
+
+	// Initialize session store, tokens are encrypted with keys derived from the session secret
+	tokenCipher, err := session.NewTokenCipher(cfg.Session.Secret, cfg.Session.PreviousSecrets...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize token encryption")
+	}
+	sessionStore := session.NewSQLiteStore(db, tokenCipher)
+	log.Info().Int("previousKeys", len(cfg.Session.PreviousSecrets)).Msg("Session store initialized")
+
+	// Initialize token manager, it hands out Spotify clients per session and persists refreshed tokens
+	tokenManager := spotifyClient.NewTokenManager(sc, sessionStore)
+	log.Info().Msg("Token manager initialized")
+
+	// Initialize login stores. OAuth states and temporary login tokens are short-lived and bounded,
+	// so hammering the login endpoint cannot grow them without limit.
+	stateStore := ephemeral.NewSQLiteStore(db, "oauth_state", ephemeral.Options{
+		TTL:      10 * time.Minute,
+		Capacity: 10000,
+	})
+	tempTokenStore := ephemeral.NewSQLiteStore(db, "login_token", ephemeral.Options{
+		TTL:      2 * time.Minute,
+		Capacity: 10000,
+	})
+	log.Info().Msg("Login stores initialized")
+
+	// Initialize API token store
+	tokenStore := apitoken.NewSQLiteStore(db)
+	log.Info().Msg("API token store initialized")
+
+	// Initialize execution journal store
+	executionStore := execution.NewSQLiteStore(db)
+	interrupted, err := executionStore.MarkInterrupted()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to recover executions")
+	}
+	log.Info().Int("interrupted", interrupted).Msg("Execution store initialized")
+
+	// Initialize job manager
+	jobManager := job.NewManager()
+	log.Info().Msg("Job manager initialized")
 
 	// Initialize SSE broadcaster
 	broadcaster := sse.NewBroadcaster()
 	log.Info().Msg("SSE broadcaster initialized")
 
+	// Initialize artist cache, artist genres rarely change and are shared by all users
+	artistCache := artistcache.NewSQLiteStore(db, cfg.Storage.ArtistCacheTTL)
+	log.Info().Dur("ttl", cfg.Storage.ArtistCacheTTL).Msg("Artist cache initialized")
+
+	// Initialize library snapshots, later analyses only fetch songs liked since the last one
+	librarySnapshots := library.NewSQLiteStore(db)
+	log.Info().Msg("Library snapshot store initialized")
+
+	// Initialize playlist cache, managed playlists are only fetched again once their snapshot ID changed
+	playlistCache := playlistcache.NewSQLiteStore(db)
+	log.Info().Msg("Playlist cache initialized")
+
+	// Initialize genre registry, managed playlists keep their genre when renamed
+	genreRegistry := genreregistry.NewSQLiteStore(db)
+	log.Info().Msg("Genre registry initialized")
+
 	// Initialize services
-	libraryService := service.NewLibraryService(spotifyClient, broadcaster)
+	libraryService := service.NewLibraryService(sc, broadcaster, artistCache, librarySnapshots, playlistCache, genreRegistry)
 	sorterService := service.NewSorterService(libraryService)
-	executorService := service.NewExecutorService(spotifyClient, libraryService, broadcaster)
+	executorService := service.NewExecutorService(sc, libraryService, broadcaster, executionStore)
 	log.Info().Msg("Services initialized")
 
 	// Create router
 	router := api.NewRouter(
 		cfg,
-		spotifyClient,
+		sc,
+		tokenManager,
 		sessionStore,
+		stateStore,
+		tempTokenStore,
+		tokenStore,
 		broadcaster,
 		libraryService,
 		sorterService,
 		executorService,
+		planStore,
+		jobManager,
 	)
 	log.Info().Msg("Router configured")
 
@@ -105,6 +218,12 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Stop jobs first so requests waiting on them can complete.
+	// Running executions are interrupted and can be resumed after restart.
+	if err := jobManager.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Jobs did not stop in time")
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
@@ -2,44 +2,60 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/ephemeral"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
 )
 
-// stateStore holds OAuth states in memory
-var (
-	stateStore   = make(map[string]time.Time)
-	stateStoreMu sync.Mutex
-)
-
-// tempTokenStore holds temporary tokens for completing login
-var (
-	tempTokenStore   = make(map[string]string) // tempToken -> sessionID
-	tempTokenStoreMu sync.Mutex
-)
+// sessionInfo describes one of the user's sessions
+type sessionInfo struct {
+	ID         string    `json:"id"` // Public ID, never the session cookie value
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"` // Session that made the request
+}
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	spotifyClient *spotifyClient.Client
-	sessionStore  *session.Store
+	spotifyClient spotifyClient.API
+	tokens        *spotifyClient.TokenManager
+	sessionStore  session.SessionStore
+	states        ephemeral.Store // OAuth state -> PKCE verifier, empty without PKCE
+	tempTokens    ephemeral.Store // Temporary login token -> session ID
+	cookies       *middleware.Cookies
 	config        *config.Config
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(spotifyClient *spotifyClient.Client, sessionStore *session.Store, config *config.Config) *AuthHandler {
+func NewAuthHandler(
+	spotifyClient spotifyClient.API,
+	tokens *spotifyClient.TokenManager,
+	sessionStore session.SessionStore,
+	states ephemeral.Store,
+	tempTokens ephemeral.Store,
+	cookies *middleware.Cookies,
+	config *config.Config,
+) *AuthHandler {
 	return &AuthHandler{
 		spotifyClient: spotifyClient,
+		tokens:        tokens,
 		sessionStore:  sessionStore,
+		states:        states,
+		tempTokens:    tempTokens,
+		cookies:       cookies,
 		config:        config,
 	}
 }
@@ -49,14 +65,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Generate state for CSRF protection
 	state := uuid.New().String()
 
+	// With PKCE the verifier stays server-side, bound to the state, and only its challenge is sent to Spotify
+	verifier := ""
+	if h.spotifyClient.UsesPKCE() {
+		verifier = oauth2.GenerateVerifier()
+	}
+
 	// Store state server-side
-	stateStoreMu.Lock()
-	stateStore[state] = time.Now().Add(10 * time.Minute)
-	log.Info().Str("state", state).Int("storeSize", len(stateStore)).Msg("Stored OAuth state")
-	stateStoreMu.Unlock()
+	if err := h.states.Put(state, verifier); err != nil {
+		log.Error().Err(err).Msg("Failed to store OAuth state")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start login",
+		})
+		return
+	}
 
 	// Get auth URL
-	authURL := h.spotifyClient.GetAuthURL(state)
+	authURL := h.spotifyClient.GetAuthURL(state, verifier)
 
 	c.JSON(http.StatusOK, gin.H{
 		"url": authURL,
@@ -68,21 +93,13 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 	// Verify state from server-side store
 	state := c.Query("state")
 
-	stateStoreMu.Lock()
-	// Debug: log all stored states
-	storedStates := make([]string, 0, len(stateStore))
-	for k := range stateStore {
-		storedStates = append(storedStates, k)
-	}
-	log.Info().Str("receivedState", state).Strs("storedStates", storedStates).Msg("Checking OAuth state")
-	expiry, exists := stateStore[state]
-	if exists {
-		delete(stateStore, state) // Use state only once
-	}
-	stateStoreMu.Unlock()
-
-	if !exists || time.Now().After(expiry) {
-		log.Warn().Str("state", state).Bool("exists", exists).Msg("Invalid OAuth state")
+	// Use state only once
+	verifier, err := h.states.Take(state)
+	if err != nil {
+		if !errors.Is(err, ephemeral.ErrNotFound) {
+			log.Error().Err(err).Msg("Failed to check OAuth state")
+		}
+		log.Warn().Str("state", state).Msg("Invalid OAuth state")
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid state parameter",
 		})
@@ -101,7 +118,7 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	token, err := h.spotifyClient.Exchange(ctx, code)
+	token, err := h.spotifyClient.Exchange(ctx, code, verifier)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to exchange code for token")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -121,15 +138,25 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 		return
 	}
 
-	// Create session
+	// Create session, the store only learns the hash of the cookie value
 	sessionID := uuid.New().String()
-	_ = h.sessionStore.Create(sessionID, user.ID, token)
+	if _, err := h.sessionStore.Create(session.HashID(sessionID), user.ID, token, c.Request.UserAgent()); err != nil {
+		log.Error().Err(err).Msg("Failed to create session")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create session",
+		})
+		return
+	}
 
 	// Create a temporary token for the frontend to complete login
 	tempToken := uuid.New().String()
-	tempTokenStoreMu.Lock()
-	tempTokenStore[tempToken] = sessionID
-	tempTokenStoreMu.Unlock()
+	if err := h.tempTokens.Put(tempToken, sessionID); err != nil {
+		log.Error().Err(err).Msg("Failed to store temporary token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create session",
+		})
+		return
+	}
 
 	log.Info().Str("userID", user.ID).Msg("User logged in successfully")
 
@@ -158,24 +185,15 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Create Spotify client with token refresh
-	tokenSource := h.spotifyClient.TokenSource(ctx, sess.Token)
-	token, err := tokenSource.Token()
+	// Refreshed tokens are written back to the session by the token manager
+	client, err := h.tokens.ClientFor(sess)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to refresh token")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Failed to refresh token",
 		})
 		return
 	}
 
-	// Update session with refreshed token
-	if token.AccessToken != sess.Token.AccessToken {
-		sessionID, _ := c.Cookie(middleware.SessionCookieName)
-		h.sessionStore.Update(sessionID, token)
-	}
-
-	client := h.spotifyClient.NewSpotifyClient(ctx, token)
 	user, err := h.spotifyClient.GetCurrentUser(ctx, client)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get user profile")
@@ -210,14 +228,11 @@ func (h *AuthHandler) CompleteLogin(c *gin.Context) {
 	}
 
 	// Get and remove temp token
-	tempTokenStoreMu.Lock()
-	sessionID, exists := tempTokenStore[tempToken]
-	if exists {
-		delete(tempTokenStore, tempToken)
-	}
-	tempTokenStoreMu.Unlock()
-
-	if !exists {
+	sessionID, err := h.tempTokens.Take(tempToken)
+	if err != nil {
+		if !errors.Is(err, ephemeral.ErrNotFound) {
+			log.Error().Err(err).Msg("Failed to check temporary token")
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid or expired token",
 		})
@@ -225,31 +240,169 @@ func (h *AuthHandler) CompleteLogin(c *gin.Context) {
 	}
 
 	// Set session cookie on localhost
-	c.SetCookie(
-		middleware.SessionCookieName,
-		sessionID,
-		86400, // 24 hours
-		"/",
-		"",
-		false,
-		true, // HttpOnly
-	)
+	h.cookies.SetSession(c, sessionID)
+
+	// Start the new session with a fresh CSRF token
+	csrfToken, err := middleware.NewCSRFToken()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate CSRF token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to complete login",
+		})
+		return
+	}
+	h.cookies.SetCSRF(c, csrfToken)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
+		"success":   true,
+		"csrfToken": csrfToken,
+	})
+}
+
+// GetCSRFToken returns the CSRF token to send in the X-CSRF-Token header of state-changing requests.
+// It is also set as a cookie, this endpoint serves frontends that cannot read the cookie.
+func (h *AuthHandler) GetCSRFToken(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"csrfToken": middleware.GetCSRFToken(c),
 	})
 }
 
 // Logout logs out the current user
 func (h *AuthHandler) Logout(c *gin.Context) {
-	sessionID, err := c.Cookie(middleware.SessionCookieName)
+	cookie, err := c.Cookie(middleware.SessionCookieName)
 	if err == nil {
-		h.sessionStore.Delete(sessionID)
+		sessionID := session.HashID(cookie)
+		if err := h.sessionStore.Delete(sessionID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete session")
+		}
+		h.tokens.Forget(sessionID)
 	}
 
-	c.SetCookie(middleware.SessionCookieName, "", -1, "/", "", false, true)
+	h.cookies.ClearSession(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logged out successfully",
 	})
 }
+
+// ListSessions returns the user's active sessions across devices
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	current, _ := middleware.GetSession(c)
+
+	sessions, err := h.sessionStore.ListByUserID(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list sessions",
+		})
+		return
+	}
+
+	result := make([]sessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		result = append(result, sessionInfo{
+			ID:         sess.PublicID(),
+			UserAgent:  sess.UserAgent,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    current != nil && sess.ID == current.ID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": result,
+	})
+}
+
+// RevokeSession logs out one of the user's sessions. Revoking the current session also clears its cookie.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	sessions, err := h.sessionStore.ListByUserID(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+		return
+	}
+
+	// Sessions are addressed by their public ID, so only the user's own sessions can match
+	var target *session.Session
+	for _, sess := range sessions {
+		if sess.PublicID() == c.Param("id") {
+			target = sess
+			break
+		}
+	}
+
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": session.ErrSessionNotFound.Error(),
+		})
+		return
+	}
+
+	if err := h.sessionStore.Delete(target.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to delete session")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+		return
+	}
+	h.tokens.Forget(target.ID)
+
+	if current, ok := middleware.GetSession(c); ok && current.ID == target.ID {
+		h.cookies.ClearSession(c)
+	}
+
+	log.Info().Str("userID", userID).Str("session", target.PublicID()).Msg("Session revoked")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// RevokeAllSessions logs the user out on every device, including the current one
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	revoked, err := h.sessionStore.DeleteByUserID(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke sessions",
+		})
+		return
+	}
+	h.tokens.ForgetUser(userID)
+
+	h.cookies.ClearSession(c)
+
+	log.Info().Str("userID", userID).Int("sessions", revoked).Msg("All sessions revoked")
+
+	c.JSON(http.StatusOK, gin.H{
+		"revoked": revoked,
+	})
+}
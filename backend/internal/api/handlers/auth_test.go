@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/ephemeral"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
+	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestAuthHandler creates an auth handler with in-memory stores and the default configuration
+func newTestAuthHandler(sc *spotifyClient.Client, sessions session.SessionStore, states ephemeral.Store) *AuthHandler {
+	return NewAuthHandler(sc, spotifyClient.NewTokenManager(sc, sessions), sessions, states, ephemeral.NewMemoryStore(ephemeral.Options{}),
+		middleware.NewCookies(config.SessionConfig{}), &config.Config{})
+}
+
+// sessionsTestEnv serves the session routes like the router does, for a user logged in on two devices
+type sessionsTestEnv struct {
+	router   *gin.Engine
+	sessions *session.MemoryStore
+}
+
+func newSessionsTestEnv(t *testing.T) *sessionsTestEnv {
+	t.Helper()
+
+	env := &sessionsTestEnv{sessions: session.NewMemoryStore()}
+	logins := []struct{ cookie, userID, userAgent string }{
+		{"laptop-cookie", "user", "Firefox"},
+		{"phone-cookie", "user", "Safari"},
+		{"other-cookie", "other-user", "Chrome"},
+	}
+	for _, login := range logins {
+		if _, err := env.sessions.Create(session.HashID(login.cookie), login.userID, &oauth2.Token{AccessToken: "access"}, login.userAgent); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := newTestAuthHandler(nil, env.sessions, ephemeral.NewMemoryStore(ephemeral.Options{}))
+	auth := middleware.AuthMiddleware(env.sessions, apitoken.NewMemoryStore(), middleware.NewCookies(config.SessionConfig{}))
+
+	env.router = gin.New()
+	env.router.GET("/sessions", auth, h.ListSessions)
+	env.router.DELETE("/sessions", auth, h.RevokeAllSessions)
+	env.router.DELETE("/sessions/:id", auth, h.RevokeSession)
+	return env
+}
+
+// do sends a request with the session cookie
+func (e *sessionsTestEnv) do(method, path, cookie string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.AddCookie(&http.Cookie{Name: middleware.SessionCookieName, Value: cookie})
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// list returns the sessions the cookie's user sees, by user agent
+func (e *sessionsTestEnv) list(t *testing.T, cookie string) map[string]sessionInfo {
+	t.Helper()
+
+	w := e.do(http.MethodGet, "/sessions", cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list sessions: status %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "-cookie") {
+		t.Errorf("session list reveals a cookie: %s", w.Body)
+	}
+
+	var body struct {
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	byAgent := make(map[string]sessionInfo, len(body.Sessions))
+	for _, info := range body.Sessions {
+		byAgent[info.UserAgent] = info
+	}
+	return byAgent
+}
+
+func TestListSessionsAcrossDevices(t *testing.T) {
+	env := newSessionsTestEnv(t)
+
+	listed := env.list(t, "laptop-cookie")
+	if len(listed) != 2 {
+		t.Fatalf("listed %d sessions, want the laptop and the phone", len(listed))
+	}
+	if !listed["Firefox"].Current || listed["Safari"].Current {
+		t.Errorf("current flags = laptop %t, phone %t, want only the laptop", listed["Firefox"].Current, listed["Safari"].Current)
+	}
+}
+
+func TestRevokeSessionOfAnotherDevice(t *testing.T) {
+	env := newSessionsTestEnv(t)
+	phone := env.list(t, "laptop-cookie")["Safari"]
+	other := env.list(t, "other-cookie")["Chrome"]
+
+	// Sessions of other users cannot be addressed
+	if w := env.do(http.MethodDelete, "/sessions/"+other.ID, "laptop-cookie"); w.Code != http.StatusNotFound {
+		t.Errorf("revoking another user's session: status %d, want 404", w.Code)
+	}
+
+	if w := env.do(http.MethodDelete, "/sessions/"+phone.ID, "laptop-cookie"); w.Code != http.StatusOK {
+		t.Fatalf("revoking the phone: status %d", w.Code)
+	}
+	if w := env.do(http.MethodGet, "/sessions", "phone-cookie"); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked phone session: status %d, want 401", w.Code)
+	}
+	if listed := env.list(t, "laptop-cookie"); len(listed) != 1 {
+		t.Errorf("%d sessions left, want the laptop", len(listed))
+	}
+	if w := env.do(http.MethodGet, "/sessions", "other-cookie"); w.Code != http.StatusOK {
+		t.Errorf("session of another user: status %d, want 200", w.Code)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	env := newSessionsTestEnv(t)
+
+	w := env.do(http.MethodDelete, "/sessions", "laptop-cookie")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":2`) {
+		t.Fatalf("revoke all: status %d, body %s, want 2 revoked", w.Code, w.Body)
+	}
+	for _, cookie := range []string{"laptop-cookie", "phone-cookie"} {
+		if w := env.do(http.MethodGet, "/sessions", cookie); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s after revoking all: status %d, want 401", cookie, w.Code)
+		}
+	}
+	if w := env.do(http.MethodGet, "/sessions", "other-cookie"); w.Code != http.StatusOK {
+		t.Errorf("session of another user: status %d, want 200", w.Code)
+	}
+}
+
+func TestLoginBindsVerifierToState(t *testing.T) {
+	for _, pkce := range []bool{true, false} {
+		t.Run(map[bool]string{true: "pkce", false: "confidential"}[pkce], func(t *testing.T) {
+			states := ephemeral.NewMemoryStore(ephemeral.Options{})
+			sc := spotifyClient.NewClient("client-id", "", "http://localhost/callback", pkce, spotifyClient.RateLimits{Rate: 10, Burst: 10, UserRate: 10, UserBurst: 10})
+			h := newTestAuthHandler(sc, session.NewMemoryStore(), states)
+
+			router := gin.New()
+			router.GET("/login", h.Login)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+			var body struct {
+				URL string `json:"url"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			authURL, err := url.Parse(body.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := authURL.Query()
+
+			// The callback finds the verifier by the state Spotify sends back
+			verifier, err := states.Take(query.Get("state"))
+			if err != nil {
+				t.Fatalf("no verifier stored for the state: %v", err)
+			}
+			if !pkce {
+				if verifier != "" || query.Has("code_challenge") {
+					t.Errorf("login without PKCE stored verifier %q, challenge %q", verifier, query.Get("code_challenge"))
+				}
+				return
+			}
+			if verifier == "" || query.Get("code_challenge") != oauth2.S256ChallengeFromVerifier(verifier) {
+				t.Errorf("challenge %q was not made from the stored verifier", query.Get("code_challenge"))
+			}
+			if strings.Contains(body.URL, verifier) {
+				t.Error("auth URL reveals the verifier")
+			}
+		})
+	}
+}
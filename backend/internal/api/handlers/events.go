@@ -115,7 +115,7 @@ func (h *EventsHandler) TestEvent(c *gin.Context) {
 		return
 	}
 
-	h.broadcaster.SendInfo(userID, "Test event from server")
+	h.broadcaster.SendInfo(c.Request.Context(), userID, "Test event from server")
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Test event sent",
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
+	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
+)
+
+// JobsHandler handles job endpoints
+type JobsHandler struct {
+	jobs *job.Manager
+}
+
+// NewJobsHandler creates a new jobs handler
+func NewJobsHandler(jobs *job.Manager) *JobsHandler {
+	return &JobsHandler{
+		jobs: jobs,
+	}
+}
+
+// ListJobs returns the user's recent jobs
+func (h *JobsHandler) ListJobs(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": h.jobs.List(userID, 20),
+	})
+}
+
+// GetJob returns a job with its status and, once finished, its result
+func (h *JobsHandler) GetJob(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	j, err := h.jobs.Get(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(jobManagerErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, j)
+}
+
+// CancelJob requests cancellation of a running job
+func (h *JobsHandler) CancelJob(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	j, err := h.jobs.Cancel(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(jobManagerErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, j)
+}
+
+// CancelSort cancels every running analysis, plan generation, execution, resume or rollback of the user
+func (h *JobsHandler) CancelSort(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	cancelled := h.jobs.CancelAll(userID)
+	if len(cancelled) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Nothing is running",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"cancelled": cancelled,
+	})
+}
+
+// runJob starts fn as a job. With ?async=true the job is returned right away with 202 Accepted,
+// otherwise the request waits for the job and responds with its result, and the job is cancelled
+// if the client disconnects first.
+func runJob(c *gin.Context, jobs *job.Manager, userID string, spec job.Spec, fn job.Func) {
+	j, err := jobs.Start(userID, spec, fn)
+	if errors.Is(err, job.ErrMutatingJobRunning) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"jobId": j.ID,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(jobManagerErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if c.Query("async") == "true" {
+		c.JSON(http.StatusAccepted, j)
+		return
+	}
+
+	jobID := j.ID
+	j, err = jobs.Wait(c.Request.Context(), jobID, userID)
+	if err != nil {
+		// The client went away, nobody is left to receive the result
+		log.Warn().Err(err).Str("userID", userID).Str("jobID", jobID).Msg("Client disconnected, cancelling job")
+		if _, err := jobs.Cancel(jobID, userID); err != nil && !errors.Is(err, job.ErrJobFinished) {
+			log.Error().Err(err).Str("jobID", jobID).Msg("Failed to cancel job")
+		}
+		return
+	}
+
+	switch {
+	case j.Status == job.StatusFailed:
+		setRetryAfter(c, j.Err())
+		c.JSON(jobErrorStatus(j.Err()), gin.H{
+			"error": j.Error,
+			"jobId": j.ID,
+		})
+	case j.Status == job.StatusCancelled && j.Result == nil:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Job was cancelled",
+			"jobId": j.ID,
+		})
+	default:
+		c.JSON(http.StatusOK, j.Result)
+	}
+}
+
+// jobManagerErrorStatus maps job manager errors to HTTP status codes
+func jobManagerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, job.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, job.ErrJobFinished), errors.Is(err, job.ErrMutatingJobRunning):
+		return http.StatusConflict
+	case errors.Is(err, job.ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// jobErrorStatus maps the error a job failed with to an HTTP status code
+func jobErrorStatus(err error) int {
+	if errors.Is(err, spotifyClient.ErrTokenRefresh) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, job.ErrJobTimedOut) {
+		return http.StatusGatewayTimeout
+	}
+	if status := spotifyErrorStatus(err); status != http.StatusInternalServerError {
+		return status
+	}
+	if status := planErrorStatus(err); status != http.StatusInternalServerError {
+		return status
+	}
+	return executionErrorStatus(err)
+}
+
+// spotifyErrorStatus maps Spotify API errors to HTTP status codes
+func spotifyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, spotifyClient.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, spotifyClient.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, spotifyClient.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, spotifyClient.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, spotifyClient.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// setRetryAfter passes on how long Spotify asked to wait when the error is a rate limit
+func setRetryAfter(c *gin.Context, err error) {
+	var apiErr *spotifyClient.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+}
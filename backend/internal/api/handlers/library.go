@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,25 +10,28 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/service"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
 )
 
 // LibraryHandler handles library endpoints
 type LibraryHandler struct {
-	spotifyClient  *spotifyClient.Client
+	tokens         *spotifyClient.TokenManager
 	libraryService *service.LibraryService
+	jobs           *job.Manager
 }
 
 // NewLibraryHandler creates a new library handler
-func NewLibraryHandler(spotifyClient *spotifyClient.Client, libraryService *service.LibraryService) *LibraryHandler {
+func NewLibraryHandler(tokens *spotifyClient.TokenManager, libraryService *service.LibraryService, jobs *job.Manager) *LibraryHandler {
 	return &LibraryHandler{
-		spotifyClient:  spotifyClient,
+		tokens:         tokens,
 		libraryService: libraryService,
+		jobs:           jobs,
 	}
 }
 
-// GetAnalysis analyzes the user's library
+// GetAnalysis analyzes the user's library as a job
 func (h *LibraryHandler) GetAnalysis(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -45,38 +49,33 @@ func (h *LibraryHandler) GetAnalysis(c *gin.Context) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	// Create Spotify client with token refresh
-	tokenSource := h.spotifyClient.TokenSource(ctx, sess.Token)
-	token, err := tokenSource.Token()
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to refresh token")
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Failed to refresh token",
-		})
-		return
+	// ?refresh=true fetches the whole library and every artist again instead of using what is cached
+	refresh := c.Query("refresh") == "true"
+	opts := service.AnalyzeOptions{
+		RefreshArtists: refresh,
+		FullSync:       refresh,
 	}
 
-	client := h.spotifyClient.NewSpotifyClient(ctx, token)
+	runJob(c, h.jobs, userID, job.Spec{Kind: job.KindAnalysis, Timeout: 5 * time.Minute}, func(ctx context.Context) (any, error) {
+		client, err := h.tokens.ClientFor(sess)
+		if err != nil {
+			return nil, err
+		}
 
-	// Analyze library
-	log.Info().Str("userID", userID).Msg("Starting library analysis")
-	analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to analyze library")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to analyze library: " + err.Error(),
-		})
-		return
-	}
+		// Analyze library
+		log.Info().Str("userID", userID).Msg("Starting library analysis")
+		analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID, opts)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to analyze library")
+			return nil, fmt.Errorf("failed to analyze library: %w", err)
+		}
 
-	log.Info().
-		Str("userID", userID).
-		Int("totalTracks", analysis.TotalLikedSongs).
-		Int("playlists", len(analysis.Playlists)).
-		Msg("Library analysis complete")
+		log.Info().
+			Str("userID", userID).
+			Int("totalTracks", analysis.TotalLikedSongs).
+			Int("playlists", len(analysis.Playlists)).
+			Msg("Library analysis complete")
 
-	c.JSON(http.StatusOK, analysis)
+		return analysis, nil
+	})
 }
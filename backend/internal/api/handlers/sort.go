@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,31 +12,41 @@ import (
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/execution"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/genre"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/plan"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/service"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
 )
 
 // SortHandler handles sort endpoints
 type SortHandler struct {
-	spotifyClient   *spotifyClient.Client
+	tokens          *spotifyClient.TokenManager
 	libraryService  *service.LibraryService
 	sorterService   *service.SorterService
 	executorService *service.ExecutorService
+	planStore       *plan.Store
+	jobs            *job.Manager
 }
 
 // NewSortHandler creates a new sort handler
 func NewSortHandler(
-	spotifyClient *spotifyClient.Client,
+	tokens *spotifyClient.TokenManager,
 	libraryService *service.LibraryService,
 	sorterService *service.SorterService,
 	executorService *service.ExecutorService,
+	planStore *plan.Store,
+	jobs *job.Manager,
 ) *SortHandler {
 	return &SortHandler{
-		spotifyClient:   spotifyClient,
+		tokens:          tokens,
 		libraryService:  libraryService,
 		sorterService:   sorterService,
 		executorService: executorService,
+		planStore:       planStore,
+		jobs:            jobs,
 	}
 }
 
@@ -74,31 +86,24 @@ func (h *SortHandler) GeneratePlan(c *gin.Context) {
 		req.DryRun = true // Default to dry run
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	runJob(c, h.jobs, userID, job.Spec{Kind: job.KindPlan, Timeout: 5 * time.Minute}, func(ctx context.Context) (any, error) {
+		return h.generatePlan(ctx, userID, sess, req)
+	})
+}
 
-	// Create Spotify client with token refresh
-	tokenSource := h.spotifyClient.TokenSource(ctx, sess.Token)
-	token, err := tokenSource.Token()
+// generatePlan analyzes the library, generates a sort plan and stores it
+func (h *SortHandler) generatePlan(ctx context.Context, userID string, sess *session.Session, req GeneratePlanRequest) (*GeneratePlanResponse, error) {
+	client, err := h.tokens.ClientFor(sess)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to refresh token")
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Failed to refresh token",
-		})
-		return
+		return nil, err
 	}
 
-	client := h.spotifyClient.NewSpotifyClient(ctx, token)
-
 	// Analyze library
 	log.Info().Str("userID", userID).Msg("Analyzing library for sort plan")
-	analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID)
+	analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID, service.AnalyzeOptions{})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to analyze library")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to analyze library: " + err.Error(),
-		})
-		return
+		return nil, fmt.Errorf("failed to analyze library: %w", err)
 	}
 
 	// Convert enabled groups to map for easier lookup
@@ -118,10 +123,7 @@ func (h *SortHandler) GeneratePlan(c *gin.Context) {
 	plan, err := h.sorterService.GenerateSortPlan(ctx, analysis, userID, req.DryRun, enabledGroupsMap)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate sort plan")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate sort plan: " + err.Error(),
-		})
-		return
+		return nil, fmt.Errorf("failed to generate sort plan: %w", err)
 	}
 
 	// Filter out disabled playlists from playlistsToCreate
@@ -144,24 +146,28 @@ func (h *SortHandler) GeneratePlan(c *gin.Context) {
 		plan.TracksToAdd = filteredTracksToAdd
 	}
 
+	// Keep the plan so it can be executed exactly as previewed
+	h.planStore.Save(userID, plan)
+
 	log.Info().
 		Str("userID", userID).
 		Str("planID", plan.ID).
 		Int("tracksToAdd", len(plan.TracksToAdd)).
 		Int("tracksToRemove", len(plan.TracksToRemove)).
 		Int("playlistsToCreate", len(plan.PlaylistsToCreate)).
+		Int("playlistMerges", len(plan.PlaylistMerges)).
+		Int("groupMerges", len(plan.GroupMerges)).
 		Msg("Sort plan generated")
 
-	response := GeneratePlanResponse{
+	return &GeneratePlanResponse{
 		SortPlan:            plan,
 		GroupingSuggestions: analysis.GroupingSuggestions,
-	}
-
-	c.JSON(http.StatusOK, response)
+	}, nil
 }
 
 // ExecutePlanRequest represents a request to execute a sort plan
 type ExecutePlanRequest struct {
+	PlanID            string   `json:"planId"` // Execute a stored plan instead of generating a new one
 	DryRun            bool     `json:"dryRun"`
 	EnabledGroups     []string `json:"enabledGroups"`     // Parent genres to group (e.g., ["Rock", "Pop"])
 	DisabledPlaylists []string `json:"disabledPlaylists"` // Genre names to skip creating playlists for
@@ -190,69 +196,382 @@ func (h *SortHandler) ExecutePlan(c *gin.Context) {
 		req.DryRun = false // Default to actual execution
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
+	if req.PlanID != "" {
+		h.executeStoredPlan(c, userID, sess, req.PlanID, req.DryRun)
+		return
+	}
+
+	spec := job.Spec{Kind: job.KindExecution, Mutating: !req.DryRun}
+	runJob(c, h.jobs, userID, spec, func(ctx context.Context) (any, error) {
+		client, err := h.tokens.ClientFor(sess)
+		if err != nil {
+			return nil, err
+		}
+
+		// Analyze library
+		log.Info().Str("userID", userID).Msg("Analyzing library for execution")
+		analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID, service.AnalyzeOptions{})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to analyze library")
+			return nil, fmt.Errorf("failed to analyze library: %w", err)
+		}
+
+		// Convert enabled groups to map for easier lookup
+		enabledGroupsMap := make(map[string]bool)
+		for _, g := range req.EnabledGroups {
+			enabledGroupsMap[g] = true
+		}
 
-	// Create Spotify client with token refresh
-	tokenSource := h.spotifyClient.TokenSource(ctx, sess.Token)
-	token, err := tokenSource.Token()
+		// Generate sort plan
+		log.Info().Str("userID", userID).Bool("dryRun", req.DryRun).Msg("Generating sort plan for execution")
+		plan, err := h.sorterService.GenerateSortPlan(ctx, analysis, userID, req.DryRun, enabledGroupsMap)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to generate sort plan")
+			return nil, fmt.Errorf("failed to generate sort plan: %w", err)
+		}
+
+		// Execute plan
+		log.Info().Str("userID", userID).Str("planID", plan.ID).Msg("Executing sort plan")
+		result, err := h.executorService.ExecuteSortPlan(ctx, client, plan, userID)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to execute sort plan")
+			return nil, fmt.Errorf("failed to execute sort plan: %w", err)
+		}
+
+		log.Info().
+			Str("userID", userID).
+			Str("planID", plan.ID).
+			Bool("success", result.Success).
+			Int("playlistsCreated", result.PlaylistsCreated).
+			Int("tracksAdded", result.TracksAdded).
+			Int("tracksRemoved", result.TracksRemoved).
+			Msg("Sort plan executed")
+
+		return result, nil
+	})
+}
+
+// GetPlan returns a stored sort plan
+func (h *SortHandler) GetPlan(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	storedPlan, err := h.planStore.Get(c.Param("id"), userID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to refresh token")
+		c.JSON(planErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, storedPlan)
+}
+
+// UpdateSelectionRequest approves (true) or rejects (false) individual plan entries
+type UpdateSelectionRequest struct {
+	Moves         map[string]bool `json:"moves"`         // TrackMove ID -> approved (adds and removals)
+	Playlists     map[string]bool `json:"playlists"`     // Genre name from playlistsToCreate -> approved
+	Uncategorized map[string]bool `json:"uncategorized"` // Track ID from uncategorizedTracks -> approved
+	Merges        map[string]bool `json:"merges"`        // Merge ID from playlistMerges or groupMerges -> approved
+}
+
+// UpdateSelection approves or rejects entries of a stored plan before it is executed
+func (h *SortHandler) UpdateSelection(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Failed to refresh token",
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	var req UpdateSelectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid selection: " + err.Error(),
 		})
 		return
 	}
 
-	client := h.spotifyClient.NewSpotifyClient(ctx, token)
+	selection := plan.Selection{
+		Moves:         req.Moves,
+		Playlists:     req.Playlists,
+		Uncategorized: req.Uncategorized,
+		Merges:        req.Merges,
+	}
 
-	// Analyze library
-	log.Info().Str("userID", userID).Msg("Analyzing library for execution")
-	analysis, err := h.libraryService.AnalyzeLibrary(ctx, client, userID)
+	updatedPlan, err := h.planStore.Update(c.Param("id"), userID, func(p *domain.SortPlan) error {
+		return plan.ApplySelection(p, selection)
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to analyze library")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to analyze library: " + err.Error(),
+		c.JSON(planErrorStatus(err), gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
-	// Convert enabled groups to map for easier lookup
-	enabledGroupsMap := make(map[string]bool)
-	for _, g := range req.EnabledGroups {
-		enabledGroupsMap[g] = true
+	log.Info().
+		Str("userID", userID).
+		Str("planID", updatedPlan.ID).
+		Int("skipped", updatedPlan.SkippedCount()).
+		Msg("Sort plan selection updated")
+
+	c.JSON(http.StatusOK, updatedPlan)
+}
+
+// ExecuteStoredPlanRequest represents a request to execute a stored sort plan
+type ExecuteStoredPlanRequest struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// ExecuteStoredPlan executes a previously generated plan by ID
+func (h *SortHandler) ExecuteStoredPlan(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
 	}
 
-	// Generate sort plan
-	log.Info().Str("userID", userID).Bool("dryRun", req.DryRun).Msg("Generating sort plan for execution")
-	plan, err := h.sorterService.GenerateSortPlan(ctx, analysis, userID, req.DryRun, enabledGroupsMap)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate sort plan")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate sort plan: " + err.Error(),
+	sess, exists := middleware.GetSession(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "No session found",
+		})
+		return
+	}
+
+	var req ExecuteStoredPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		req.DryRun = false // Default to actual execution
+	}
+
+	h.executeStoredPlan(c, userID, sess, c.Param("id"), req.DryRun)
+}
+
+// executeStoredPlan executes a stored plan after checking it is still valid for the current library
+func (h *SortHandler) executeStoredPlan(c *gin.Context, userID string, sess *session.Session, planID string, dryRun bool) {
+	// Fail fast on unknown or expired plans before talking to Spotify
+	if _, err := h.planStore.Get(planID, userID); err != nil {
+		c.JSON(planErrorStatus(err), gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
 
+	spec := job.Spec{Kind: job.KindExecution, Mutating: !dryRun}
+	runJob(c, h.jobs, userID, spec, func(ctx context.Context) (any, error) {
+		return h.runStoredPlan(ctx, userID, sess, planID, dryRun)
+	})
+}
+
+// runStoredPlan claims a stored plan, checks it against the current library and executes it
+func (h *SortHandler) runStoredPlan(ctx context.Context, userID string, sess *session.Session, planID string, dryRun bool) (*domain.ExecutionResult, error) {
+	client, err := h.tokens.ClientFor(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	storedPlan, err := h.planStore.Claim(planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject the plan if the library changed since it was generated
+	log.Info().Str("userID", userID).Str("planID", planID).Msg("Checking library for changes since plan generation")
+	fingerprint, err := h.libraryService.CurrentFingerprint(ctx, client, userID)
+	if err != nil {
+		h.planStore.Release(planID)
+		log.Error().Err(err).Msg("Failed to fetch library")
+		return nil, fmt.Errorf("failed to fetch library: %w", err)
+	}
+
+	if fingerprint != storedPlan.LibraryFingerprint {
+		// The plan can never match the library again
+		h.planStore.Delete(planID)
+		log.Warn().Str("userID", userID).Str("planID", planID).Msg("Library changed since plan was generated")
+		return nil, plan.ErrLibraryChanged
+	}
+
+	storedPlan.DryRun = dryRun
+
 	// Execute plan
-	log.Info().Str("userID", userID).Str("planID", plan.ID).Msg("Executing sort plan")
-	result, err := h.executorService.ExecuteSortPlan(ctx, client, plan, userID)
+	log.Info().Str("userID", userID).Str("planID", planID).Bool("dryRun", dryRun).Msg("Executing stored sort plan")
+	result, err := h.executorService.ExecuteSortPlan(ctx, client, storedPlan, userID)
 	if err != nil {
+		// Changes applied before the failure alter the library fingerprint,
+		// so a partially executed plan is rejected when it is executed again
+		h.planStore.Release(planID)
 		log.Error().Err(err).Msg("Failed to execute sort plan")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to execute sort plan: " + err.Error(),
-		})
-		return
+		return nil, fmt.Errorf("failed to execute sort plan: %w", err)
+	}
+
+	if dryRun {
+		// A dry run does not consume the plan
+		h.planStore.Release(planID)
 	}
 
 	log.Info().
 		Str("userID", userID).
-		Str("planID", plan.ID).
+		Str("planID", planID).
 		Bool("success", result.Success).
 		Int("playlistsCreated", result.PlaylistsCreated).
 		Int("tracksAdded", result.TracksAdded).
 		Int("tracksRemoved", result.TracksRemoved).
-		Msg("Sort plan executed")
+		Msg("Stored sort plan executed")
+
+	return result, nil
+}
+
+// ListExecutions returns the user's recent plan executions
+func (h *SortHandler) ListExecutions(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
 
-	c.JSON(http.StatusOK, result)
+	executions, err := h.executorService.ListExecutions(userID, 20)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list executions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list executions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+	})
+}
+
+// GetExecution returns an execution with its journal
+func (h *SortHandler) GetExecution(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	exec, err := h.executorService.GetExecution(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(executionErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, exec)
+}
+
+// RollbackExecution reverts every change an execution applied to Spotify
+func (h *SortHandler) RollbackExecution(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	sess, exists := middleware.GetSession(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "No session found",
+		})
+		return
+	}
+
+	executionID := c.Param("id")
+
+	runJob(c, h.jobs, userID, job.Spec{Kind: job.KindRollback, Mutating: true}, func(ctx context.Context) (any, error) {
+		client, err := h.tokens.ClientFor(sess)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info().Str("userID", userID).Str("executionID", executionID).Msg("Rolling back execution")
+		result, err := h.executorService.RollbackExecution(ctx, client, executionID, userID)
+		if err != nil {
+			log.Error().Err(err).Str("executionID", executionID).Msg("Failed to roll back execution")
+			return nil, err
+		}
+
+		return result, nil
+	})
+}
+
+// ResumeExecution continues an interrupted or failed execution from its last checkpoint
+func (h *SortHandler) ResumeExecution(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	sess, exists := middleware.GetSession(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "No session found",
+		})
+		return
+	}
+
+	executionID := c.Param("id")
+
+	runJob(c, h.jobs, userID, job.Spec{Kind: job.KindResume, Mutating: true}, func(ctx context.Context) (any, error) {
+		client, err := h.tokens.ClientFor(sess)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info().Str("userID", userID).Str("executionID", executionID).Msg("Resuming execution")
+		result, err := h.executorService.ResumeExecution(ctx, client, executionID, userID)
+		if err != nil {
+			log.Error().Err(err).Str("executionID", executionID).Msg("Failed to resume execution")
+			return nil, err
+		}
+
+		return result, nil
+	})
+}
+
+// executionErrorStatus maps execution errors to HTTP status codes
+func executionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, execution.ErrExecutionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrExecutionInProgress), errors.Is(err, service.ErrAlreadyRolledBack), errors.Is(err, service.ErrNotResumable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// planErrorStatus maps plan store errors to HTTP status codes
+func planErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, plan.ErrPlanNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, plan.ErrUnknownEntry):
+		return http.StatusBadRequest
+	case errors.Is(err, plan.ErrPlanExpired):
+		return http.StatusGone
+	case errors.Is(err, plan.ErrPlanAlreadyExecuted), errors.Is(err, plan.ErrLibraryChanged):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
 }
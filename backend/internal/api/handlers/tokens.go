@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
+)
+
+const maxTokenNameLength = 100
+
+// TokensHandler handles personal API token endpoints
+type TokensHandler struct {
+	tokens apitoken.Store
+}
+
+// NewTokensHandler creates a new API tokens handler
+func NewTokensHandler(tokens apitoken.Store) *TokensHandler {
+	return &TokensHandler{
+		tokens: tokens,
+	}
+}
+
+// CreateTokenRequest describes a token to mint
+type CreateTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expiresInDays"` // 0 for a token that does not expire
+}
+
+// createdToken is returned once, when a token is created, and is the only time the secret is shown
+type createdToken struct {
+	*apitoken.Token
+	Secret string `json:"token"`
+}
+
+// CreateToken mints a new API token for the current session
+func (h *TokensHandler) CreateToken(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	sess, exists := middleware.GetSession(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "No session found",
+		})
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid token request: " + err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxTokenNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Token name must be between 1 and 100 characters",
+		})
+		return
+	}
+
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "expiresInDays must not be negative",
+		})
+		return
+	}
+
+	scopes, err := apitoken.ParseScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	secret, hash, hint, err := apitoken.Generate()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create API token",
+		})
+		return
+	}
+
+	token := &apitoken.Token{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		SessionID: sess.ID,
+		Name:      name,
+		Hint:      hint,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := token.CreatedAt.AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := h.tokens.Create(token, hash); err != nil {
+		log.Error().Err(err).Msg("Failed to store API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create API token",
+		})
+		return
+	}
+
+	log.Info().Str("userID", userID).Str("tokenID", token.ID).Msg("API token created")
+
+	c.JSON(http.StatusCreated, createdToken{
+		Token:  token,
+		Secret: secret,
+	})
+}
+
+// ListTokens returns the user's API tokens, without their secrets
+func (h *TokensHandler) ListTokens(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	tokens, err := h.tokens.ListByUser(userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list API tokens")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list API tokens",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+// RevokeToken deletes one of the user's API tokens
+func (h *TokensHandler) RevokeToken(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not authenticated",
+		})
+		return
+	}
+
+	if err := h.tokens.Delete(c.Param("id"), userID); err != nil {
+		if errors.Is(err, apitoken.ErrTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to delete API token")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke API token",
+		})
+		return
+	}
+
+	log.Info().Str("userID", userID).Str("tokenID", c.Param("id")).Msg("API token revoked")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API token revoked",
+	})
+}
@@ -1,24 +1,36 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
 )
 
 const (
-	SessionCookieName = "spotify_session"
-	UserIDContextKey  = "userID"
-	SessionContextKey = "session"
+	SessionCookieName  = "spotify_session"
+	UserIDContextKey   = "userID"
+	SessionContextKey  = "session"
+	APITokenContextKey = "apiToken"
 )
 
-// AuthMiddleware validates the session and adds user context
-func AuthMiddleware(store *session.Store) gin.HandlerFunc {
+// AuthMiddleware validates the session and adds user context.
+// Requests authenticate with the session cookie, or with a personal API token sent as
+// an "Authorization: Bearer" header, which acts on behalf of the session it was created from.
+func AuthMiddleware(store session.SessionStore, tokens apitoken.Store, cookies *Cookies) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); header != "" {
+			authenticateToken(c, store, tokens, header)
+			return
+		}
+
 		// Get session cookie
-		sessionID, err := c.Cookie(SessionCookieName)
+		cookie, err := c.Cookie(SessionCookieName)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "No session found",
@@ -28,11 +40,11 @@ func AuthMiddleware(store *session.Store) gin.HandlerFunc {
 		}
 
 		// Get session from store
-		sess, err := store.Get(sessionID)
+		sess, err := store.Get(session.HashID(cookie))
 		if err != nil {
 			if err == session.ErrSessionExpired {
 				// Clear expired cookie
-				c.SetCookie(SessionCookieName, "", -1, "/", "", false, true)
+				cookies.ClearSession(c)
 				c.JSON(http.StatusUnauthorized, gin.H{
 					"error": "Session expired",
 				})
@@ -46,7 +58,7 @@ func AuthMiddleware(store *session.Store) gin.HandlerFunc {
 		}
 
 		// Refresh session expiration
-		if err := store.Refresh(sessionID); err != nil {
+		if err := store.Refresh(sess.ID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to refresh session",
 			})
@@ -62,12 +74,102 @@ func AuthMiddleware(store *session.Store) gin.HandlerFunc {
 	}
 }
 
+// authenticateToken validates a bearer API token and adds user context
+func authenticateToken(c *gin.Context, store session.SessionStore, tokens apitoken.Store, header string) {
+	credential, found := strings.CutPrefix(header, "Bearer ")
+	if !found || !apitoken.IsToken(credential) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid API token",
+		})
+		c.Abort()
+		return
+	}
+
+	token, err := tokens.GetByHash(apitoken.Hash(credential))
+	if err != nil {
+		if !errors.Is(err, apitoken.ErrTokenNotFound) {
+			log.Error().Err(err).Msg("Failed to load API token")
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid API token",
+		})
+		c.Abort()
+		return
+	}
+
+	if token.Expired() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": apitoken.ErrTokenExpired.Error(),
+		})
+		c.Abort()
+		return
+	}
+
+	// The token uses the Spotify credentials of the session it was created from
+	sess, err := store.Get(token.SessionID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "The session this API token belongs to has ended, log in again and create a new token",
+		})
+		c.Abort()
+		return
+	}
+
+	if err := store.Refresh(token.SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to refresh session",
+		})
+		c.Abort()
+		return
+	}
+
+	if err := tokens.Touch(token.ID); err != nil {
+		log.Warn().Err(err).Str("tokenID", token.ID).Msg("Failed to record API token use")
+	}
+
+	c.Set(UserIDContextKey, sess.UserID)
+	c.Set(SessionContextKey, sess)
+	c.Set(APITokenContextKey, token)
+
+	c.Next()
+}
+
+// RequireScope rejects requests made with an API token that lacks scope.
+// Requests authenticated with the session cookie may do everything.
+func RequireScope(scope apitoken.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, ok := GetAPIToken(c); ok && !token.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API token lacks the " + string(scope) + " scope",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RejectAPITokens only lets requests authenticated with the session cookie through.
+// Used for managing sessions and tokens, so a leaked token cannot mint new ones.
+func RejectAPITokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetAPIToken(c); ok {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API tokens cannot manage sessions or tokens",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // OptionalAuthMiddleware adds user context if session exists, but doesn't require it
-func OptionalAuthMiddleware(store *session.Store) gin.HandlerFunc {
+func OptionalAuthMiddleware(store session.SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sessionID, err := c.Cookie(SessionCookieName)
+		cookie, err := c.Cookie(SessionCookieName)
 		if err == nil {
-			sess, err := store.Get(sessionID)
+			sess, err := store.Get(session.HashID(cookie))
 			if err == nil {
 				c.Set(UserIDContextKey, sess.UserID)
 				c.Set(SessionContextKey, sess)
@@ -94,3 +196,12 @@ func GetSession(c *gin.Context) (*session.Session, bool) {
 	}
 	return sess.(*session.Session), true
 }
+
+// GetAPIToken retrieves the API token the request authenticated with, if any
+func GetAPIToken(c *gin.Context) (*apitoken.Token, bool) {
+	token, exists := c.Get(APITokenContextKey)
+	if !exists {
+		return nil, false
+	}
+	return token.(*apitoken.Token), true
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// authTestEnv serves a route per scope behind AuthMiddleware, like the router does
+type authTestEnv struct {
+	router   *gin.Engine
+	sessions *session.MemoryStore
+	tokens   *apitoken.MemoryStore
+	cookie   string
+}
+
+func newAuthTestEnv(t *testing.T) *authTestEnv {
+	t.Helper()
+
+	env := &authTestEnv{
+		sessions: session.NewMemoryStore(),
+		tokens:   apitoken.NewMemoryStore(),
+		cookie:   "cookie-value",
+	}
+	if _, err := env.sessions.Create(session.HashID(env.cookie), "user", &oauth2.Token{AccessToken: "access"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		c.String(http.StatusOK, userID)
+	}
+	auth := AuthMiddleware(env.sessions, env.tokens, NewCookies(config.SessionConfig{}))
+
+	env.router = gin.New()
+	env.router.GET("/read", auth, RequireScope(apitoken.ScopeRead), ok)
+	env.router.POST("/execute", auth, RequireScope(apitoken.ScopeExecute), ok)
+	env.router.POST("/tokens", auth, RejectAPITokens(), ok)
+	return env
+}
+
+// token creates an API token for the test session and returns its secret
+func (e *authTestEnv) token(t *testing.T, expiresAt *time.Time, scopes ...apitoken.Scope) string {
+	t.Helper()
+
+	secret, hash, hint, err := apitoken.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.tokens.Create(&apitoken.Token{
+		ID:        uuid.New().String(),
+		UserID:    "user",
+		SessionID: session.HashID(e.cookie),
+		Hint:      hint,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// do sends a request with the given Authorization header, or the session cookie without one
+func (e *authTestEnv) do(method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	} else {
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: e.cookie})
+	}
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	env := newAuthTestEnv(t)
+	readOnly := "Bearer " + env.token(t, nil, apitoken.ScopeRead)
+	full := "Bearer " + env.token(t, nil, apitoken.ScopeRead, apitoken.ScopeExecute)
+
+	tests := []struct {
+		name          string
+		method, path  string
+		authorization string
+		want          int
+	}{
+		{"read token reads", http.MethodGet, "/read", readOnly, http.StatusOK},
+		{"read token cannot execute", http.MethodPost, "/execute", readOnly, http.StatusForbidden},
+		{"execute token executes", http.MethodPost, "/execute", full, http.StatusOK},
+		{"token cannot manage tokens", http.MethodPost, "/tokens", full, http.StatusForbidden},
+		{"cookie may do everything", http.MethodPost, "/execute", "", http.StatusOK},
+		{"cookie manages tokens", http.MethodPost, "/tokens", "", http.StatusOK},
+		{"unknown token", http.MethodGet, "/read", "Bearer " + apitoken.Prefix + "unknown", http.StatusUnauthorized},
+		{"not a token", http.MethodGet, "/read", "Bearer something", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := env.do(tt.method, tt.path, tt.authorization)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != "user" {
+				t.Errorf("request acted as %q, want the token's user", rec.Body)
+			}
+		})
+	}
+}
+
+func TestAPITokenEndsWithItsSession(t *testing.T) {
+	env := newAuthTestEnv(t)
+	expired := time.Now().Add(-time.Minute)
+	expiredToken := "Bearer " + env.token(t, &expired, apitoken.ScopeRead)
+	token := "Bearer " + env.token(t, nil, apitoken.ScopeRead)
+
+	if rec := env.do(http.MethodGet, "/read", expiredToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired token: status = %d, want 401", rec.Code)
+	}
+
+	if err := env.sessions.Delete(session.HashID(env.cookie)); err != nil {
+		t.Fatal(err)
+	}
+	if rec := env.do(http.MethodGet, "/read", token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("token of a deleted session: status = %d, want 401", rec.Code)
+	}
+}
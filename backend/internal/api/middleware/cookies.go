@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+
+	// cookieMaxAge matches the session lifetime
+	cookieMaxAge = 24 * 60 * 60
+)
+
+// Cookies sets the session and CSRF cookies with the configured Secure and SameSite attributes
+type Cookies struct {
+	secure   bool
+	sameSite http.SameSite
+}
+
+// NewCookies creates cookie settings from the session configuration
+func NewCookies(cfg config.SessionConfig) *Cookies {
+	return &Cookies{
+		secure:   cfg.CookieSecure,
+		sameSite: parseSameSite(cfg.CookieSameSite),
+	}
+}
+
+// SetSession sets the HTTP-only session cookie
+func (k *Cookies) SetSession(c *gin.Context, sessionID string) {
+	k.set(c, SessionCookieName, sessionID, cookieMaxAge, true)
+}
+
+// ClearSession removes the session cookie
+func (k *Cookies) ClearSession(c *gin.Context) {
+	k.set(c, SessionCookieName, "", -1, true)
+}
+
+// SetCSRF sets the CSRF cookie. It is readable by scripts, so the frontend can echo it in a header.
+func (k *Cookies) SetCSRF(c *gin.Context, token string) {
+	k.set(c, CSRFCookieName, token, cookieMaxAge, false)
+}
+
+func (k *Cookies) set(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   k.secure,
+		HttpOnly: httpOnly,
+		SameSite: k.sameSite,
+	})
+}
+
+// parseSameSite maps the configured SameSite value, which config.Load has validated
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+)
+
+func newCSRFTestRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(CSRFMiddleware(NewCookies(config.SessionConfig{})))
+
+	ok := func(c *gin.Context) {
+		c.String(http.StatusOK, GetCSRFToken(c))
+	}
+	router.GET("/", ok)
+	router.POST("/", ok)
+	return router
+}
+
+// csrfCookie returns the CSRF cookie a response sets, if any
+func csrfCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == CSRFCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestCSRFIssuesTokenOnSafeRequests(t *testing.T) {
+	router := newCSRFTestRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookie := csrfCookie(rec)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("GET did not set a CSRF cookie")
+	}
+	if cookie.HttpOnly {
+		t.Error("CSRF cookie is HTTP-only, the frontend cannot echo it")
+	}
+	if rec.Body.String() != cookie.Value {
+		t.Errorf("token in context = %q, want the cookie value", rec.Body)
+	}
+
+	// A request that already has a token keeps it
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if csrfCookie(rec) != nil || rec.Body.String() != cookie.Value {
+		t.Error("GET with a CSRF cookie issued a new token")
+	}
+}
+
+func TestCSRFRejectsUnsafeRequestsWithoutToken(t *testing.T) {
+	router := newCSRFTestRouter()
+	token, err := NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		cookie        string
+		header        string
+		authorization string
+		want          int
+	}{
+		{"matching header", token, token, "", http.StatusOK},
+		{"no header", token, "", "", http.StatusForbidden},
+		{"wrong header", token, other, "", http.StatusForbidden},
+		{"no cookie", "", token, "", http.StatusForbidden},
+		{"neither", "", "", "", http.StatusForbidden},
+		{"bearer token", "", "", "Bearer sps_token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -5,7 +5,11 @@ import (
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/handlers"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/api/middleware"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/apitoken"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/config"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/ephemeral"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/plan"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/service"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
@@ -15,12 +19,18 @@ import (
 // Router sets up and returns the Gin router
 func NewRouter(
 	cfg *config.Config,
-	spotifyClient *spotifyClient.Client,
-	sessionStore *session.Store,
+	spotifyClient spotifyClient.API,
+	tokenManager *spotifyClient.TokenManager,
+	sessionStore session.SessionStore,
+	states ephemeral.Store,
+	tempTokens ephemeral.Store,
+	tokenStore apitoken.Store,
 	broadcaster *sse.Broadcaster,
 	libraryService *service.LibraryService,
 	sorterService *service.SorterService,
 	executorService *service.ExecutorService,
+	planStore *plan.Store,
+	jobs *job.Manager,
 ) *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
@@ -30,10 +40,14 @@ func NewRouter(
 	// Apply CORS middleware
 	router.Use(middleware.CORSMiddleware(cfg.Server.AllowOrigins))
 
+	cookies := middleware.NewCookies(cfg.Session)
+
 	// Create handlers
-	authHandler := handlers.NewAuthHandler(spotifyClient, sessionStore, cfg)
-	libraryHandler := handlers.NewLibraryHandler(spotifyClient, libraryService)
-	sortHandler := handlers.NewSortHandler(spotifyClient, libraryService, sorterService, executorService)
+	authHandler := handlers.NewAuthHandler(spotifyClient, tokenManager, sessionStore, states, tempTokens, cookies, cfg)
+	libraryHandler := handlers.NewLibraryHandler(tokenManager, libraryService, jobs)
+	sortHandler := handlers.NewSortHandler(tokenManager, libraryService, sorterService, executorService, planStore, jobs)
+	jobsHandler := handlers.NewJobsHandler(jobs)
+	tokensHandler := handlers.NewTokensHandler(tokenStore)
 	eventsHandler := handlers.NewEventsHandler(broadcaster)
 
 	// Health check
@@ -43,8 +57,14 @@ func NewRouter(
 		})
 	})
 
+	// Requests authenticate with the session cookie or a personal API token
+	requireAuth := middleware.AuthMiddleware(sessionStore, tokenStore, cookies)
+	read := middleware.RequireScope(apitoken.ScopeRead)
+	execute := middleware.RequireScope(apitoken.ScopeExecute)
+
 	// API routes
 	api := router.Group("/api")
+	api.Use(middleware.CSRFMiddleware(cookies))
 	{
 		// Auth routes (no auth required)
 		auth := api.Group("/auth")
@@ -52,32 +72,61 @@ func NewRouter(
 			auth.GET("/login", authHandler.Login)
 			auth.GET("/callback", authHandler.Callback)
 			auth.GET("/complete", authHandler.CompleteLogin)
-			auth.POST("/logout", middleware.AuthMiddleware(sessionStore), authHandler.Logout)
-			auth.GET("/me", middleware.AuthMiddleware(sessionStore), authHandler.GetMe)
+			auth.GET("/csrf", authHandler.GetCSRFToken)
+			auth.POST("/logout", requireAuth, authHandler.Logout)
+			auth.GET("/me", requireAuth, authHandler.GetMe)
+
+			// Managing sessions and API tokens requires the session cookie
+			account := auth.Group("")
+			account.Use(requireAuth, middleware.RejectAPITokens())
+			{
+				account.GET("/sessions", authHandler.ListSessions)
+				account.DELETE("/sessions", authHandler.RevokeAllSessions)
+				account.DELETE("/sessions/:id", authHandler.RevokeSession)
+				account.POST("/tokens", tokensHandler.CreateToken)
+				account.GET("/tokens", tokensHandler.ListTokens)
+				account.DELETE("/tokens/:id", tokensHandler.RevokeToken)
+			}
 		}
 
 		// Protected routes (require authentication)
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(sessionStore))
+		protected.Use(requireAuth)
 		{
 			// Library routes
 			library := protected.Group("/library")
 			{
-				library.GET("/analysis", libraryHandler.GetAnalysis)
+				library.GET("/analysis", read, libraryHandler.GetAnalysis)
 			}
 
-			// Sort routes
+			// Sort routes. Generating and reviewing plans is read-only, changing the library needs the execute scope.
 			sort := protected.Group("/sort")
 			{
-				sort.POST("/plan", sortHandler.GeneratePlan)
-				sort.POST("/execute", sortHandler.ExecutePlan)
+				sort.POST("/plan", read, sortHandler.GeneratePlan)
+				sort.POST("/execute", execute, sortHandler.ExecutePlan)
+				sort.POST("/cancel", execute, jobsHandler.CancelSort)
+				sort.GET("/plans/:id", read, sortHandler.GetPlan)
+				sort.PATCH("/plans/:id/selection", read, sortHandler.UpdateSelection)
+				sort.POST("/plans/:id/execute", execute, sortHandler.ExecuteStoredPlan)
+				sort.GET("/executions", read, sortHandler.ListExecutions)
+				sort.GET("/executions/:id", read, sortHandler.GetExecution)
+				sort.POST("/executions/:id/rollback", execute, sortHandler.RollbackExecution)
+				sort.POST("/executions/:id/resume", execute, sortHandler.ResumeExecution)
+			}
+
+			// Job routes
+			jobRoutes := protected.Group("/jobs")
+			{
+				jobRoutes.GET("", read, jobsHandler.ListJobs)
+				jobRoutes.GET("/:id", read, jobsHandler.GetJob)
+				jobRoutes.POST("/:id/cancel", execute, jobsHandler.CancelJob)
 			}
 
 			// Events routes (SSE)
 			events := protected.Group("/events")
 			{
-				events.GET("", eventsHandler.StreamEvents)
-				events.POST("/test", eventsHandler.TestEvent)
+				events.GET("", read, eventsHandler.StreamEvents)
+				events.POST("/test", read, eventsHandler.TestEvent)
 			}
 		}
 	}
@@ -0,0 +1,130 @@
+package apitoken
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps API tokens in memory. Tokens are lost on restart.
+type MemoryStore struct {
+	tokens map[string]*Token // hash -> Token
+	mu     sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory API token store
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		tokens: make(map[string]*Token),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredTokens()
+
+	return store
+}
+
+// Create stores a new token
+func (s *MemoryStore) Create(token *Token, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[hash] = cloneToken(token)
+	return nil
+}
+
+// GetByHash retrieves a copy of the token with the given hash
+func (s *MemoryStore) GetByHash(hash string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, exists := s.tokens[hash]
+	if !exists {
+		return nil, ErrTokenNotFound
+	}
+
+	return cloneToken(token), nil
+}
+
+// ListByUser returns the user's tokens, newest first
+func (s *MemoryStore) ListByUser(userID string) ([]*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Token{}
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			result = append(result, cloneToken(token))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// Delete removes a token owned by the user
+func (s *MemoryStore) Delete(tokenID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.tokens {
+		if token.ID == tokenID && token.UserID == userID {
+			delete(s.tokens, hash)
+			return nil
+		}
+	}
+
+	return ErrTokenNotFound
+}
+
+// Touch records that a token was used
+func (s *MemoryStore) Touch(tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.ID == tokenID {
+			now := time.Now()
+			token.LastUsedAt = &now
+			return nil
+		}
+	}
+
+	return ErrTokenNotFound
+}
+
+// cleanupExpiredTokens periodically removes expired tokens
+func (s *MemoryStore) cleanupExpiredTokens() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+
+		for hash, token := range s.tokens {
+			if token.Expired() {
+				delete(s.tokens, hash)
+			}
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// cloneToken copies a token so callers cannot modify the stored version
+func cloneToken(token *Token) *Token {
+	clone := *token
+	clone.Scopes = append([]Scope(nil), token.Scopes...)
+	if token.LastUsedAt != nil {
+		lastUsedAt := *token.LastUsedAt
+		clone.LastUsedAt = &lastUsedAt
+	}
+	if token.ExpiresAt != nil {
+		expiresAt := *token.ExpiresAt
+		clone.ExpiresAt = &expiresAt
+	}
+	return &clone
+}
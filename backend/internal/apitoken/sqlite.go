@@ -0,0 +1,175 @@
+package apitoken
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// tokenColumns are the columns scanToken reads, in order
+const tokenColumns = `id, user_id, session_id, name, hint, scopes, created_at, last_used_at, expires_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// SQLiteStore keeps API tokens in the embedded database
+type SQLiteStore struct {
+	db *storage.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed API token store
+func NewSQLiteStore(db *storage.DB) *SQLiteStore {
+	store := &SQLiteStore{
+		db: db,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredTokens()
+
+	return store
+}
+
+// Create stores a new token
+func (s *SQLiteStore) Create(token *Token, hash string) error {
+	var expiresAt int64
+	if token.ExpiresAt != nil {
+		expiresAt = token.ExpiresAt.UnixNano()
+	}
+
+	_, err := s.db.Exec(
+		`INSERT INTO api_tokens (id, hash, user_id, session_id, name, hint, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, hash, token.UserID, token.SessionID, token.Name, token.Hint,
+		joinScopes(token.Scopes), token.CreatedAt.UnixNano(), expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert API token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByHash retrieves the token with the given hash
+func (s *SQLiteStore) GetByHash(hash string) (*Token, error) {
+	row := s.db.QueryRow(`SELECT `+tokenColumns+` FROM api_tokens WHERE hash = ?`, hash)
+	return scanToken(row)
+}
+
+// ListByUser returns the user's tokens, newest first
+func (s *SQLiteStore) ListByUser(userID string) ([]*Token, error) {
+	rows, err := s.db.Query(
+		`SELECT `+tokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Delete removes a token owned by the user
+func (s *SQLiteStore) Delete(tokenID, userID string) error {
+	res, err := s.db.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// Touch records that a token was used
+func (s *SQLiteStore) Touch(tokenID string) error {
+	res, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, time.Now().UnixNano(), tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// cleanupExpiredTokens periodically removes expired tokens
+func (s *SQLiteStore) cleanupExpiredTokens() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(
+			`DELETE FROM api_tokens WHERE expires_at > 0 AND expires_at < ?`,
+			time.Now().UnixNano(),
+		); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up expired API tokens")
+		}
+	}
+}
+
+// scanToken reads a token row
+func scanToken(row rowScanner) (*Token, error) {
+	var (
+		token                            Token
+		scopes                           string
+		createdAt, lastUsedAt, expiresAt int64
+	)
+
+	err := row.Scan(&token.ID, &token.UserID, &token.SessionID, &token.Name, &token.Hint, &scopes, &createdAt, &lastUsedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API token: %w", err)
+	}
+
+	for _, scope := range strings.Split(scopes, ",") {
+		token.Scopes = append(token.Scopes, Scope(scope))
+	}
+
+	token.CreatedAt = time.Unix(0, createdAt)
+	if lastUsedAt > 0 {
+		t := time.Unix(0, lastUsedAt)
+		token.LastUsedAt = &t
+	}
+	if expiresAt > 0 {
+		t := time.Unix(0, expiresAt)
+		token.ExpiresAt = &t
+	}
+
+	return &token, nil
+}
+
+// joinScopes encodes scopes for storage
+func joinScopes(scopes []Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
+
+// requireAffected reports ErrTokenNotFound when a statement matched no token
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
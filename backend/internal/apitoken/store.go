@@ -0,0 +1,15 @@
+package apitoken
+
+// Store keeps personal API tokens by the hash of their secret
+type Store interface {
+	// Create stores a new token under the hash of its secret
+	Create(token *Token, hash string) error
+	// GetByHash retrieves the token whose secret has the given hash
+	GetByHash(hash string) (*Token, error)
+	// ListByUser returns the user's tokens, newest first
+	ListByUser(userID string) ([]*Token, error)
+	// Delete removes a token owned by the user
+	Delete(tokenID, userID string) error
+	// Touch records that a token was used
+	Touch(tokenID string) error
+}
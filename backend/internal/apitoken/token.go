@@ -0,0 +1,98 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Prefix marks personal API tokens, so they are easy to recognize in scripts and secret scanners
+const Prefix = "sps_"
+
+var (
+	ErrTokenNotFound = errors.New("API token not found")
+	ErrTokenExpired  = errors.New("API token expired")
+	ErrInvalidScope  = errors.New("invalid API token scope")
+)
+
+// Scope limits what an API token can do
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"    // Analyze the library, generate plans and read plans, executions and jobs
+	ScopeExecute Scope = "execute" // Execute, resume and roll back plans, and cancel running jobs
+)
+
+// Scopes lists every valid scope
+var Scopes = []Scope{ScopeRead, ScopeExecute}
+
+// Token is a personal API token. Only its hash is stored, the secret is shown once when it is created.
+type Token struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	SessionID  string     `json:"-"` // Session whose Spotify credentials the token uses
+	Name       string     `json:"name"`
+	Hint       string     `json:"hint"` // First characters of the secret, to tell tokens apart
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"` // Nil if the token does not expire
+}
+
+// HasScope reports whether the token grants scope
+func (t *Token) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token is past its expiration
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// Generate creates a new token secret and returns it together with its hash and display hint
+func Generate() (secret, hash, hint string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	secret = Prefix + base64.RawURLEncoding.EncodeToString(raw)
+	return secret, Hash(secret), secret[:len(Prefix)+6], nil
+}
+
+// Hash returns the value stored for a token secret
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsToken reports whether a credential looks like a personal API token
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// ParseScopes validates scope names
+func ParseScopes(names []string) ([]Scope, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	scopes := []Scope{}
+	for _, name := range names {
+		scope := Scope(name)
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, name)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
@@ -0,0 +1,81 @@
+package artistcache
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore caches artists in memory. The cache is lost on restart.
+type MemoryStore struct {
+	ttl     time.Duration
+	artists map[string]*Artist
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory artist cache
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	store := &MemoryStore{
+		ttl:     withDefault(ttl),
+		artists: make(map[string]*Artist),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupStaleArtists()
+
+	return store
+}
+
+// GetMany returns the fresh cached artists among ids
+func (s *MemoryStore) GetMany(ids []string) (map[string]*Artist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]*Artist)
+	for _, id := range ids {
+		if artist, exists := s.artists[id]; exists && fresh(artist.FetchedAt, s.ttl) {
+			copied := *artist
+			copied.Genres = append([]string(nil), artist.Genres...)
+			result[id] = &copied
+		}
+	}
+
+	return result, nil
+}
+
+// PutMany stores artists, replacing cached entries with the same ID
+func (s *MemoryStore) PutMany(artists []*Artist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, artist := range artists {
+		copied := *artist
+		copied.Genres = append([]string(nil), artist.Genres...)
+		s.artists[artist.ID] = &copied
+	}
+
+	return nil
+}
+
+// Len returns the number of cached artists
+func (s *MemoryStore) Len() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.artists), nil
+}
+
+// cleanupStaleArtists periodically removes stale artists
+func (s *MemoryStore) cleanupStaleArtists() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for id, artist := range s.artists {
+			if !fresh(artist.FetchedAt, s.ttl) {
+				delete(s.artists, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
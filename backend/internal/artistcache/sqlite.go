@@ -0,0 +1,142 @@
+package artistcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// maxQueryIDs bounds the IDs looked up per query, SQLite limits the number of parameters
+const maxQueryIDs = 500
+
+// SQLiteStore caches artists in the embedded database, so the cache survives a restart
+type SQLiteStore struct {
+	db  *storage.DB
+	ttl time.Duration
+}
+
+// NewSQLiteStore creates a new SQLite-backed artist cache
+func NewSQLiteStore(db *storage.DB, ttl time.Duration) *SQLiteStore {
+	store := &SQLiteStore{
+		db:  db,
+		ttl: withDefault(ttl),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupStaleArtists()
+
+	return store
+}
+
+// GetMany returns the fresh cached artists among ids
+func (s *SQLiteStore) GetMany(ids []string) (map[string]*Artist, error) {
+	result := make(map[string]*Artist)
+	freshAfter := time.Now().Add(-s.ttl).UnixNano()
+
+	for start := 0; start < len(ids); start += maxQueryIDs {
+		chunk := ids[start:min(start+maxQueryIDs, len(ids))]
+
+		args := make([]any, 0, len(chunk)+1)
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+		args = append(args, freshAfter)
+
+		rows, err := s.db.Query(
+			`SELECT id, name, genres, fetched_at FROM artists
+			WHERE id IN (?`+strings.Repeat(`, ?`, len(chunk)-1)+`) AND fetched_at > ?`,
+			args...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load artists: %w", err)
+		}
+
+		for rows.Next() {
+			var (
+				artist    Artist
+				genres    string
+				fetchedAt int64
+			)
+			if err := rows.Scan(&artist.ID, &artist.Name, &genres, &fetchedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to load artist: %w", err)
+			}
+			if err := json.Unmarshal([]byte(genres), &artist.Genres); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to decode artist genres: %w", err)
+			}
+			artist.FetchedAt = time.Unix(0, fetchedAt)
+			result[artist.ID] = &artist
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// PutMany stores artists, replacing cached entries with the same ID
+func (s *SQLiteStore) PutMany(artists []*Artist) error {
+	if len(artists) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO artists (id, name, genres, fetched_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, genres = excluded.genres, fetched_at = excluded.fetched_at`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, artist := range artists {
+		genres := artist.Genres
+		if genres == nil {
+			genres = []string{}
+		}
+		genresData, err := json.Marshal(genres)
+		if err != nil {
+			return fmt.Errorf("failed to encode artist genres: %w", err)
+		}
+
+		if _, err := stmt.Exec(artist.ID, artist.Name, string(genresData), artist.FetchedAt.UnixNano()); err != nil {
+			return fmt.Errorf("failed to store artist: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Len returns the number of cached artists
+func (s *SQLiteStore) Len() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM artists`).Scan(&count)
+	return count, err
+}
+
+// cleanupStaleArtists periodically removes stale artists
+func (s *SQLiteStore) cleanupStaleArtists() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(`DELETE FROM artists WHERE fetched_at <= ?`, time.Now().Add(-s.ttl).UnixNano()); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up stale artists")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package artistcache
+
+import "time"
+
+// DefaultTTL is how long cached artists stay fresh when no TTL is configured.
+// Artist genres rarely change, so a week keeps them current enough.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// cleanupInterval is how often stale artists are evicted in the background
+const cleanupInterval = 1 * time.Hour
+
+// Artist is the part of a Spotify artist the analysis needs
+type Artist struct {
+	ID        string
+	Name      string
+	Genres    []string
+	FetchedAt time.Time
+}
+
+// Store caches artist genres across analyses and users.
+// Artists fetched longer ago than the store's TTL are stale and treated as missing.
+type Store interface {
+	// GetMany returns the fresh cached artists among ids, by ID. Missing and stale artists are left out.
+	GetMany(ids []string) (map[string]*Artist, error)
+	// PutMany stores artists, replacing cached entries with the same ID
+	PutMany(artists []*Artist) error
+	// Len returns the number of cached artists, including stale ones not yet evicted
+	Len() (int, error)
+}
+
+// fresh reports whether an artist fetched at fetchedAt is still within ttl
+func fresh(fetchedAt time.Time, ttl time.Duration) bool {
+	return time.Since(fetchedAt) < ttl
+}
+
+// withDefault returns ttl, or DefaultTTL when unset
+func withDefault(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return DefaultTTL
+	}
+	return ttl
+}
@@ -0,0 +1,92 @@
+package artistcache
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// stores runs a test against every Store implementation
+func stores(t *testing.T, ttl time.Duration, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore(ttl))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		test(t, NewSQLiteStore(db, ttl))
+	})
+}
+
+func TestStoreReturnsFreshArtists(t *testing.T) {
+	stores(t, time.Hour, func(t *testing.T, store Store) {
+		now := time.Now()
+		err := store.PutMany([]*Artist{
+			{ID: "fresh", Name: "Fresh", Genres: []string{"rock", "indie rock"}, FetchedAt: now},
+			{ID: "stale", Name: "Stale", Genres: []string{"jazz"}, FetchedAt: now.Add(-2 * time.Hour)},
+			{ID: "none", Name: "No Genres", Genres: []string{}, FetchedAt: now},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		artists, err := store.GetMany([]string{"fresh", "stale", "none", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(artists) != 2 {
+			t.Fatalf("GetMany returned %d artists, want the 2 fresh ones", len(artists))
+		}
+		if a := artists["fresh"]; a == nil || a.Name != "Fresh" || len(a.Genres) != 2 || a.Genres[1] != "indie rock" {
+			t.Errorf("fresh artist = %+v", a)
+		}
+		// Artists without genres are cached too, so they are not fetched again
+		if a := artists["none"]; a == nil || len(a.Genres) != 0 {
+			t.Errorf("artist without genres = %+v", a)
+		}
+
+		// Fetching a stale artist again replaces it
+		if err := store.PutMany([]*Artist{{ID: "stale", Name: "Stale", Genres: []string{"bebop"}, FetchedAt: now}}); err != nil {
+			t.Fatal(err)
+		}
+		artists, err = store.GetMany([]string{"stale"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := artists["stale"]; a == nil || len(a.Genres) != 1 || a.Genres[0] != "bebop" {
+			t.Errorf("refetched artist = %+v, want its new genres", a)
+		}
+		if n, err := store.Len(); err != nil || n != 3 {
+			t.Errorf("Len = %d, %v, want 3", n, err)
+		}
+	})
+}
+
+func TestStoreLooksUpManyArtists(t *testing.T) {
+	stores(t, time.Hour, func(t *testing.T, store Store) {
+		// More artists than SQLite allows parameters in one query
+		ids := make([]string, 2*maxQueryIDs+1)
+		artists := make([]*Artist, len(ids))
+		for i := range ids {
+			ids[i] = fmt.Sprintf("artist%d", i)
+			artists[i] = &Artist{ID: ids[i], Name: ids[i], Genres: []string{"pop"}, FetchedAt: time.Now()}
+		}
+		if err := store.PutMany(artists); err != nil {
+			t.Fatal(err)
+		}
+
+		cached, err := store.GetMany(ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cached) != len(ids) {
+			t.Errorf("GetMany returned %d artists, want %d", len(cached), len(ids))
+		}
+	})
+}
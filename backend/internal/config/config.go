@@ -1,6 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/caarlos0/env/v10"
 )
 
@@ -8,6 +13,8 @@ type Config struct {
 	Server  ServerConfig
 	Spotify SpotifyConfig
 	Session SessionConfig
+	Storage StorageConfig
+	Demo    DemoConfig
 }
 
 type ServerConfig struct {
@@ -17,13 +24,43 @@ type ServerConfig struct {
 }
 
 type SpotifyConfig struct {
-	ClientID     string `env:"SPOTIFY_CLIENT_ID,required"`
-	ClientSecret string `env:"SPOTIFY_CLIENT_SECRET,required"`
+	ClientID     string `env:"SPOTIFY_CLIENT_ID"`     // Required unless DemoMode is set
+	ClientSecret string `env:"SPOTIFY_CLIENT_SECRET"` // Required unless UsePKCE is set
 	RedirectURL  string `env:"SPOTIFY_REDIRECT_URL" envDefault:"http://localhost:3001/api/auth/callback"`
+	UsePKCE      bool   `env:"SPOTIFY_USE_PKCE" envDefault:"false"`
+	RateLimit    RateLimitConfig
+}
+
+// RateLimitConfig caps how fast the app calls the Spotify API. The rates are upper bounds,
+// the client slows down by itself when Spotify rate limits it.
+type RateLimitConfig struct {
+	Rate      float64 `env:"SPOTIFY_RATE_LIMIT" envDefault:"10"` // Requests per second across all users
+	Burst     int     `env:"SPOTIFY_RATE_BURST" envDefault:"10"`
+	UserRate  float64 `env:"SPOTIFY_USER_RATE_LIMIT" envDefault:"2"` // Requests per second of a single user
+	UserBurst int     `env:"SPOTIFY_USER_RATE_BURST" envDefault:"5"`
+
+	FetchConcurrency int `env:"SPOTIFY_FETCH_CONCURRENCY" envDefault:"4"` // Pages of a single fetch requested at once
 }
 
 type SessionConfig struct {
-	Secret string `env:"SESSION_SECRET,required"`
+	Secret          string   `env:"SESSION_SECRET,required"`
+	PreviousSecrets []string `env:"SESSION_SECRET_PREVIOUS" envSeparator:","` // Still accepted for decryption during key rotation
+	CookieSecure    bool     `env:"COOKIE_SECURE" envDefault:"false"`         // Only send cookies over HTTPS
+	CookieSameSite  string   `env:"COOKIE_SAMESITE" envDefault:"lax"`         // lax, strict or none
+}
+
+type StorageConfig struct {
+	Path           string        `env:"DATABASE_PATH" envDefault:"data/sorter.db"`
+	ArtistCacheTTL time.Duration `env:"ARTIST_CACHE_TTL" envDefault:"168h"` // How long cached artist genres are used before fetching them again
+}
+
+// DemoConfig runs the app against an in-process fake Spotify serving a generated library,
+// so it can be tried and tested without a Spotify app or account
+type DemoConfig struct {
+	Enabled    bool          `env:"DEMO_MODE" envDefault:"false"`
+	LikedSongs int           `env:"DEMO_LIKED_SONGS" envDefault:"3000"`
+	Artists    int           `env:"DEMO_ARTISTS" envDefault:"900"`
+	Latency    time.Duration `env:"DEMO_LATENCY" envDefault:"50ms"` // Added to every fake Spotify response
 }
 
 func Load() (*Config, error) {
@@ -31,5 +68,31 @@ func Load() (*Config, error) {
 	if err := env.Parse(&cfg); err != nil {
 		return nil, err
 	}
+	if cfg.Demo.Enabled {
+		if cfg.Demo.LikedSongs < 0 || cfg.Demo.Artists < 1 || cfg.Demo.Latency < 0 {
+			return nil, errors.New("DEMO_LIKED_SONGS, DEMO_ARTISTS and DEMO_LATENCY must not be negative, and there must be at least one artist")
+		}
+	} else {
+		if cfg.Spotify.ClientID == "" {
+			return nil, errors.New("SPOTIFY_CLIENT_ID is required unless DEMO_MODE is enabled")
+		}
+		if cfg.Spotify.ClientSecret == "" && !cfg.Spotify.UsePKCE {
+			return nil, errors.New("SPOTIFY_CLIENT_SECRET is required unless SPOTIFY_USE_PKCE is enabled")
+		}
+	}
+	limits := cfg.Spotify.RateLimit
+	if limits.Rate <= 0 || limits.UserRate <= 0 || limits.Burst < 1 || limits.UserBurst < 1 || limits.FetchConcurrency < 1 {
+		return nil, errors.New("SPOTIFY_RATE_LIMIT, SPOTIFY_RATE_BURST, SPOTIFY_USER_RATE_LIMIT, SPOTIFY_USER_RATE_BURST and SPOTIFY_FETCH_CONCURRENCY must be positive")
+	}
+	switch strings.ToLower(cfg.Session.CookieSameSite) {
+	case "lax", "strict":
+	case "none":
+		// Browsers reject SameSite=None cookies that are not Secure
+		if !cfg.Session.CookieSecure {
+			return nil, errors.New("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+		}
+	default:
+		return nil, fmt.Errorf("COOKIE_SAMESITE must be lax, strict or none, got %q", cfg.Session.CookieSameSite)
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"confidential client", map[string]string{"SPOTIFY_CLIENT_ID": "id", "SPOTIFY_CLIENT_SECRET": "secret"}, false},
+		{"PKCE without secret", map[string]string{"SPOTIFY_CLIENT_ID": "id", "SPOTIFY_USE_PKCE": "true"}, false},
+		{"no client ID", map[string]string{"SPOTIFY_CLIENT_SECRET": "secret"}, true},
+		{"no secret without PKCE", map[string]string{"SPOTIFY_CLIENT_ID": "id"}, true},
+		{"demo mode without credentials", map[string]string{"DEMO_MODE": "true"}, false},
+		{"demo mode without artists", map[string]string{"DEMO_MODE": "true", "DEMO_ARTISTS": "0"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"SPOTIFY_CLIENT_ID", "SPOTIFY_CLIENT_SECRET", "SPOTIFY_USE_PKCE", "DEMO_MODE", "DEMO_ARTISTS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			t.Setenv("SESSION_SECRET", "secret")
+
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && cfg.Demo.Enabled != (tt.env["DEMO_MODE"] == "true") {
+				t.Errorf("demo mode = %t", cfg.Demo.Enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package domain
+
+import "time"
+
+// ExecutionStatus describes where an execution of a sort plan stands
+type ExecutionStatus string
+
+const (
+	ExecutionRunning        ExecutionStatus = "running"
+	ExecutionCompleted      ExecutionStatus = "completed"
+	ExecutionFailed         ExecutionStatus = "failed"
+	ExecutionInterrupted    ExecutionStatus = "interrupted"
+	ExecutionCancelled      ExecutionStatus = "cancelled"
+	ExecutionRolledBack     ExecutionStatus = "rolled_back"
+	ExecutionRollbackFailed ExecutionStatus = "rollback_failed"
+)
+
+// JournalOperation is a single kind of mutation applied to Spotify
+type JournalOperation string
+
+const (
+	JournalPlaylistCreated    JournalOperation = "playlist_created"
+	JournalTracksAdded        JournalOperation = "tracks_added"
+	JournalTracksRemoved      JournalOperation = "tracks_removed"
+	JournalPlaylistUnfollowed JournalOperation = "playlist_unfollowed"
+	JournalPlaylistRenamed    JournalOperation = "playlist_renamed"
+)
+
+// JournalEntry records one mutation so it can be reverted
+type JournalEntry struct {
+	Seq          int              `json:"seq"`
+	Operation    JournalOperation `json:"operation"`
+	PlaylistID   string           `json:"playlistId"`
+	PlaylistName string           `json:"playlistName"`
+	TrackIDs     []string         `json:"trackIds,omitempty"`
+	Positions    []int            `json:"positions,omitempty"` // Set for removals, where each track was in the playlist (-1 if it was not)
+	At           time.Time        `json:"at"`
+	RolledBack   bool             `json:"rolledBack"` // Set once the entry was reverted
+
+	// Set for renames, a rollback restores them
+	PreviousName        string `json:"previousName,omitempty"`
+	PreviousDescription string `json:"previousDescription,omitempty"`
+}
+
+// Execution is the record of one sort plan execution and every mutation it applied
+type Execution struct {
+	ID           string           `json:"id"`
+	PlanID       string           `json:"planId"`
+	UserID       string           `json:"userId"`
+	Status       ExecutionStatus  `json:"status"`
+	StartedAt    time.Time        `json:"startedAt"`
+	FinishedAt   *time.Time       `json:"finishedAt,omitempty"`
+	RolledBackAt *time.Time       `json:"rolledBackAt,omitempty"`
+	Journal      []JournalEntry   `json:"journal"`
+	Result       *ExecutionResult `json:"result,omitempty"`
+	Checkpoint   Checkpoint       `json:"checkpoint"`
+	Plan         *SortPlan        `json:"-"` // The plan as it was claimed for execution, needed to resume
+}
+
+// Checkpoint records the progress of an execution so it can be resumed
+type Checkpoint struct {
+	CompletedSteps          []string          `json:"completedSteps"`
+	CreatedPlaylists        map[string]string `json:"createdPlaylists"`        // Genre -> playlist ID
+	RenamedPlaylists        map[string]string `json:"renamedPlaylists"`        // Parent genre -> child playlist renamed to it
+	UncategorizedPlaylistID string            `json:"uncategorizedPlaylistId"` // Set once the Uncategorized playlist is known
+	CompletedBatches        map[string]bool   `json:"completedBatches"`        // Batch key -> done
+}
+
+// Clone deep-copies a checkpoint
+func (c Checkpoint) Clone() Checkpoint {
+	clone := c
+	clone.CompletedSteps = append([]string(nil), c.CompletedSteps...)
+
+	clone.CreatedPlaylists = make(map[string]string, len(c.CreatedPlaylists))
+	for k, v := range c.CreatedPlaylists {
+		clone.CreatedPlaylists[k] = v
+	}
+
+	clone.RenamedPlaylists = make(map[string]string, len(c.RenamedPlaylists))
+	for k, v := range c.RenamedPlaylists {
+		clone.RenamedPlaylists[k] = v
+	}
+
+	clone.CompletedBatches = make(map[string]bool, len(c.CompletedBatches))
+	for k, v := range c.CompletedBatches {
+		clone.CompletedBatches[k] = v
+	}
+
+	return clone
+}
+
+// StepCompleted reports whether a step finished in a previous run
+func (c *Checkpoint) StepCompleted(step string) bool {
+	for _, s := range c.CompletedSteps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Resumable reports whether an execution can be continued
+func (e *Execution) Resumable() bool {
+	return e.Status == ExecutionInterrupted || e.Status == ExecutionCancelled || e.Status == ExecutionFailed
+}
+
+// RollbackResult summarizes the replay of an execution journal
+type RollbackResult struct {
+	ExecutionID       string           `json:"executionId"`
+	Success           bool             `json:"success"`
+	EntriesReverted   int              `json:"entriesReverted"`
+	PlaylistsRemoved  int              `json:"playlistsRemoved"`
+	PlaylistsRestored int              `json:"playlistsRestored"`
+	PlaylistsRenamed  int              `json:"playlistsRenamed"` // Renamed back to their previous name
+	TracksRestored    int              `json:"tracksRestored"`
+	TracksRemoved     int              `json:"tracksRemoved"`
+	Errors            []ExecutionError `json:"errors"`
+}
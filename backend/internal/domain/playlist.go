@@ -1,7 +1,50 @@
 package domain
 
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
 const ManagedTag = "[Managed by SpotifyPlaylistSorter]"
 
+// ManagedMarkerVersion is the version of the marker written to new managed playlists
+const ManagedMarkerVersion = 1
+
+// managedMarkerRegex matches markers like [sps:v1 genre=indie%20rock]
+var managedMarkerRegex = regexp.MustCompile(`\[sps:v(\d+) genre=([^\]\s]*)\]`)
+
+// ManagedMarker returns the machine-readable marker that records the genre of a managed playlist.
+// It sits next to ManagedTag in the description, so the genre survives renames.
+func ManagedMarker(genreKey string) string {
+	return fmt.Sprintf("[sps:v%d genre=%s]", ManagedMarkerVersion, url.PathEscape(genreKey))
+}
+
+// ManagedDescription returns the description of a managed playlist: the text, ManagedTag and the marker
+func ManagedDescription(description, genreKey string) string {
+	return description + " " + ManagedTag + " " + ManagedMarker(genreKey)
+}
+
+// ParseManagedMarker returns the genre key and version of the marker in a playlist description
+func ParseManagedMarker(description string) (genreKey string, version int, ok bool) {
+	match := managedMarkerRegex.FindStringSubmatch(description)
+	if match == nil {
+		return "", 0, false
+	}
+
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return "", 0, false
+	}
+	genreKey, err = url.PathUnescape(match[2])
+	if err != nil || genreKey == "" {
+		return "", 0, false
+	}
+
+	return genreKey, version, true
+}
+
 type Playlist struct {
 	ID            string   `json:"id"`
 	Name          string   `json:"name"`
@@ -12,6 +55,7 @@ type Playlist struct {
 	ManagedByApp  bool     `json:"managedByApp"`  // Has our tag in description
 	AssignedGenre string   `json:"assignedGenre"` // Genre this playlist represents
 	TrackIDs      []string `json:"trackIds"`
+	SnapshotID    string   `json:"snapshotId"` // Changes whenever the playlist's tracks change
 }
 
 func (p *Playlist) IsManagedByApp() bool {
@@ -10,22 +10,177 @@ type SortPlan struct {
 	TracksToAdd         []TrackMove     `json:"tracksToAdd"`
 	TracksToRemove      []TrackMove     `json:"tracksToRemove"`
 	PlaylistsToCreate   []string        `json:"playlistsToCreate"` // Genre names
+	PlaylistMerges      []PlaylistMerge `json:"playlistMerges"`    // Managed playlists sharing a genre
+	GroupMerges         []GroupMerge    `json:"groupMerges"`       // Child genre playlists consolidated into an enabled parent group
 	UncategorizedTracks []Track         `json:"uncategorizedTracks"`
 	GenreStats          []GenreStat     `json:"genreStats"`
-	EnabledGroups       map[string]bool `json:"enabledGroups"` // Parent genres that are enabled for grouping
+	EnabledGroups       map[string]bool `json:"enabledGroups"`      // Parent genres that are enabled for grouping
+	ExpiresAt           time.Time       `json:"expiresAt"`          // Plan can no longer be executed after this time
+	LibraryFingerprint  string          `json:"libraryFingerprint"` // Library contents the plan was generated from
+
+	// Entries the user rejected before execution. Moves carry their own Approved flag.
+	RejectedPlaylists     map[string]bool `json:"rejectedPlaylists"`     // Genre names from PlaylistsToCreate
+	RejectedUncategorized map[string]bool `json:"rejectedUncategorized"` // Track IDs from UncategorizedTracks
 }
 
 type TrackMove struct {
-	TrackID        string `json:"trackId"`
-	TrackName      string `json:"trackName"`
-	ArtistName     string `json:"artistName"`
-	AlbumImage     string `json:"albumImage"`
-	Genre          string `json:"genre"`
-	FromPlaylist   string `json:"fromPlaylist"`   // Playlist ID (empty if from liked songs)
+	ID               string `json:"id"` // Stable within a plan, used to approve or reject the move
+	TrackID          string `json:"trackId"`
+	TrackName        string `json:"trackName"`
+	ArtistName       string `json:"artistName"`
+	AlbumImage       string `json:"albumImage"`
+	Genre            string `json:"genre"`
+	FromPlaylist     string `json:"fromPlaylist"` // Playlist ID (empty if from liked songs)
 	FromPlaylistName string `json:"fromPlaylistName"`
-	ToPlaylist     string `json:"toPlaylist"`     // Playlist ID or genre name if new
-	ToPlaylistName string `json:"toPlaylistName"`
-	Reason         string `json:"reason"`
+	ToPlaylist       string `json:"toPlaylist"` // Playlist ID or genre name if new
+	ToPlaylistName   string `json:"toPlaylistName"`
+	Reason           string `json:"reason"`
+	Approved         bool   `json:"approved"` // Only approved moves are executed
+}
+
+// PlaylistMerge consolidates managed playlists of the same genre into one survivor.
+// Tracks of the duplicates are added to the survivor, then the duplicates are unfollowed.
+type PlaylistMerge struct {
+	ID           string           `json:"id"`    // Stable within a plan, used to approve or reject the merge
+	Genre        string           `json:"genre"` // Normalized genre shared by the playlists
+	SurvivorID   string           `json:"survivorId"`
+	SurvivorName string           `json:"survivorName"`
+	Duplicates   []MergedPlaylist `json:"duplicates"`
+	TrackIDs     []string         `json:"trackIds"` // Tracks of the duplicates missing from the survivor
+	Approved     bool             `json:"approved"` // Only approved merges are executed
+}
+
+// MergedPlaylist is a duplicate playlist that is unfollowed by a merge
+type MergedPlaylist struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Genre      string `json:"genre,omitempty"` // Normalized genre, set for the children of a group merge
+	TrackCount int    `json:"trackCount"`
+}
+
+// GroupMerge consolidates the managed playlists of child genres into a single playlist of their enabled
+// parent group. An existing playlist of the parent genre is reused, otherwise the largest child playlist
+// is renamed to the parent genre. The tracks of the other children are added to it in bulk, then the
+// children are unfollowed. With a rename, the parent genre is also in PlaylistsToCreate: it is created
+// instead when the rename is rejected or fails.
+type GroupMerge struct {
+	ID                string           `json:"id"`          // Stable within a plan, used to approve or reject the merge
+	ParentGenre       string           `json:"parentGenre"` // Name of the parent group, e.g. "Rock"
+	TargetID          string           `json:"targetId"`
+	TargetName        string           `json:"targetName"`                  // Name of the target before the merge
+	TargetDescription string           `json:"targetDescription,omitempty"` // Description of the target before the merge
+	Rename            bool             `json:"rename"`                      // The target is a child playlist renamed to ParentGenre
+	Children          []MergedPlaylist `json:"children"`
+	TrackIDs          []string         `json:"trackIds"` // Tracks of the children missing from the target
+	Approved          bool             `json:"approved"` // Only approved merges are executed
+}
+
+// ApprovedPlaylistsToCreate returns the playlists that were not rejected
+func (p *SortPlan) ApprovedPlaylistsToCreate() []string {
+	approved := []string{}
+	for _, name := range p.PlaylistsToCreate {
+		if !p.RejectedPlaylists[name] {
+			approved = append(approved, name)
+		}
+	}
+	return approved
+}
+
+// ApprovedTracksToAdd returns the approved additions.
+// Additions to a new playlist whose creation was rejected are left out as well, unless an approved
+// group merge renames a child playlist to it.
+func (p *SortPlan) ApprovedTracksToAdd() []TrackMove {
+	rejectedTargets := p.rejectedTargets()
+
+	approved := []TrackMove{}
+	for _, move := range p.TracksToAdd {
+		if move.Approved && !(move.ToPlaylist == "" && rejectedTargets[move.ToPlaylistName]) {
+			approved = append(approved, move)
+		}
+	}
+	return approved
+}
+
+// ApprovedTracksToRemove returns the approved removals.
+// A track whose addition is left out because its target playlist was rejected keeps its
+// current playlists, so it is not removed from a playlist without being added to another.
+func (p *SortPlan) ApprovedTracksToRemove() []TrackMove {
+	rejectedTargets := p.rejectedTargets()
+	stranded := make(map[string]bool) // Track ID -> addition left out
+	for _, move := range p.TracksToAdd {
+		if move.ToPlaylist == "" && rejectedTargets[move.ToPlaylistName] {
+			stranded[move.TrackID] = true
+		}
+	}
+
+	approved := []TrackMove{}
+	for _, move := range p.TracksToRemove {
+		if move.Approved && !stranded[move.TrackID] {
+			approved = append(approved, move)
+		}
+	}
+	return approved
+}
+
+// rejectedTargets returns the names of new playlists that will not exist: their creation was rejected
+// and no approved group merge renames a child playlist to them
+func (p *SortPlan) rejectedTargets() map[string]bool {
+	rejected := make(map[string]bool)
+	for _, name := range p.PlaylistsToCreate {
+		if p.RejectedPlaylists[name] {
+			rejected[name] = true
+		}
+	}
+	for _, merge := range p.GroupMerges {
+		if merge.Rename && merge.Approved {
+			delete(rejected, merge.ParentGenre)
+		}
+	}
+	return rejected
+}
+
+// ApprovedUncategorizedTracks returns the uncategorized tracks that were not rejected
+func (p *SortPlan) ApprovedUncategorizedTracks() []Track {
+	approved := []Track{}
+	for _, track := range p.UncategorizedTracks {
+		if !p.RejectedUncategorized[track.ID] {
+			approved = append(approved, track)
+		}
+	}
+	return approved
+}
+
+// ApprovedPlaylistMerges returns the approved merges
+func (p *SortPlan) ApprovedPlaylistMerges() []PlaylistMerge {
+	approved := []PlaylistMerge{}
+	for _, merge := range p.PlaylistMerges {
+		if merge.Approved {
+			approved = append(approved, merge)
+		}
+	}
+	return approved
+}
+
+// ApprovedGroupMerges returns the approved group merges
+func (p *SortPlan) ApprovedGroupMerges() []GroupMerge {
+	approved := []GroupMerge{}
+	for _, merge := range p.GroupMerges {
+		if merge.Approved {
+			approved = append(approved, merge)
+		}
+	}
+	return approved
+}
+
+// SkippedCount returns how many plan entries will not be executed
+func (p *SortPlan) SkippedCount() int {
+	skipped := len(p.PlaylistsToCreate) - len(p.ApprovedPlaylistsToCreate())
+	skipped += len(p.TracksToAdd) - len(p.ApprovedTracksToAdd())
+	skipped += len(p.TracksToRemove) - len(p.ApprovedTracksToRemove())
+	skipped += len(p.UncategorizedTracks) - len(p.ApprovedUncategorizedTracks())
+	skipped += len(p.PlaylistMerges) - len(p.ApprovedPlaylistMerges())
+	skipped += len(p.GroupMerges) - len(p.ApprovedGroupMerges())
+	return skipped
 }
 
 type GenreStat struct {
@@ -36,12 +191,19 @@ type GenreStat struct {
 }
 
 type ExecutionResult struct {
-	Success           bool              `json:"success"`
-	PlaylistsCreated  int               `json:"playlistsCreated"`
-	PlaylistsDeleted  int               `json:"playlistsDeleted"`
-	TracksAdded       int               `json:"tracksAdded"`
-	TracksRemoved     int               `json:"tracksRemoved"`
-	Errors            []ExecutionError  `json:"errors"`
+	ExecutionID      string           `json:"executionId,omitempty"` // Empty for dry runs
+	Status           ExecutionStatus  `json:"status,omitempty"`
+	Success          bool             `json:"success"`
+	PlaylistsCreated int              `json:"playlistsCreated"`
+	PlaylistsDeleted int              `json:"playlistsDeleted"`
+	PlaylistsMerged  int              `json:"playlistsMerged"` // Duplicates and child playlists unfollowed after their tracks moved
+	GroupsMerged     int              `json:"groupsMerged"`    // Parent groups whose child playlists were consolidated
+	TracksAdded      int              `json:"tracksAdded"`
+	TracksRemoved    int              `json:"tracksRemoved"`
+	Skipped          int              `json:"skipped"` // Plan entries rejected before execution
+	Cancelled        bool             `json:"cancelled"`
+	CompletedSteps   []string         `json:"completedSteps"` // Steps that finished, a cancelled execution stops part way
+	Errors           []ExecutionError `json:"errors"`
 }
 
 type ExecutionError struct {
@@ -0,0 +1,71 @@
+package domain
+
+import "testing"
+
+func TestApprovedTracksToRemoveKeepsTracksOfRejectedPlaylists(t *testing.T) {
+	plan := &SortPlan{
+		TracksToAdd: []TrackMove{
+			{ID: "add:t1", TrackID: "t1", ToPlaylistName: "jazz", Approved: true},
+			{ID: "add:t2", TrackID: "t2", ToPlaylist: "p-rock", ToPlaylistName: "Rock", Approved: true},
+		},
+		TracksToRemove: []TrackMove{
+			{ID: "remove:t1:p-pop", TrackID: "t1", FromPlaylist: "p-pop", Approved: true},
+			{ID: "remove:t2:p-pop", TrackID: "t2", FromPlaylist: "p-pop", Approved: true},
+		},
+		PlaylistsToCreate: []string{"jazz"},
+		RejectedPlaylists: map[string]bool{"jazz": true},
+	}
+
+	adds := plan.ApprovedTracksToAdd()
+	if len(adds) != 1 || adds[0].TrackID != "t2" {
+		t.Errorf("approved additions = %v, want only t2", adds)
+	}
+
+	removals := plan.ApprovedTracksToRemove()
+	if len(removals) != 1 || removals[0].TrackID != "t2" {
+		t.Errorf("approved removals = %v, want only t2", removals)
+	}
+
+	if got := plan.SkippedCount(); got != 3 {
+		t.Errorf("skipped = %d, want 3", got)
+	}
+
+	// Approving the playlist again brings the removal back
+	delete(plan.RejectedPlaylists, "jazz")
+	if got := len(plan.ApprovedTracksToRemove()); got != 2 {
+		t.Errorf("approved removals after approving the playlist = %d, want 2", got)
+	}
+}
+
+func TestApprovedRenameReplacesRejectedPlaylist(t *testing.T) {
+	plan := &SortPlan{
+		TracksToAdd: []TrackMove{
+			{ID: "add:t1", TrackID: "t1", ToPlaylistName: "Rock", Approved: true},
+		},
+		TracksToRemove: []TrackMove{
+			{ID: "remove:t1:p-pop", TrackID: "t1", FromPlaylist: "p-pop", Approved: true},
+		},
+		PlaylistsToCreate: []string{"Rock"},
+		RejectedPlaylists: map[string]bool{"Rock": true},
+		GroupMerges: []GroupMerge{
+			{ID: "group:rock", ParentGenre: "Rock", TargetID: "p-indie", Rename: true, Approved: true},
+		},
+	}
+
+	// The renamed child takes the songs of the group
+	if got := plan.ApprovedTracksToAdd(); len(got) != 1 {
+		t.Errorf("approved additions = %v, want t1", got)
+	}
+	if got := plan.ApprovedTracksToRemove(); len(got) != 1 {
+		t.Errorf("approved removals = %v, want t1", got)
+	}
+
+	// Without the rename nothing takes them
+	plan.GroupMerges[0].Approved = false
+	if got := plan.ApprovedTracksToAdd(); len(got) != 0 {
+		t.Errorf("approved additions = %v, want none", got)
+	}
+	if got := plan.ApprovedTracksToRemove(); len(got) != 0 {
+		t.Errorf("approved removals = %v, want none", got)
+	}
+}
@@ -1,14 +1,17 @@
 package domain
 
+import "time"
+
 type Track struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Artists      []Artist `json:"artists"`
-	AlbumName    string   `json:"albumName"`
-	AlbumImage   string   `json:"albumImage"`
-	Duration     int      `json:"duration"` // milliseconds
-	PrimaryGenre string   `json:"primaryGenre"`
-	InPlaylists  []string `json:"inPlaylists"` // Playlist IDs
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Artists      []Artist  `json:"artists"`
+	AlbumName    string    `json:"albumName"`
+	AlbumImage   string    `json:"albumImage"`
+	Duration     int       `json:"duration"` // milliseconds
+	AddedAt      time.Time `json:"addedAt"`  // When the track was liked
+	PrimaryGenre string    `json:"primaryGenre"`
+	InPlaylists  []string  `json:"inPlaylists"` // Playlist IDs
 }
 
 type Artist struct {
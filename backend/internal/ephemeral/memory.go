@@ -0,0 +1,116 @@
+package ephemeral
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// item is a stored value with its expiration
+type item struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// MemoryStore keeps entries in memory. Entries are lost on restart and are not shared between instances.
+type MemoryStore struct {
+	opts    Options
+	entries map[string]*list.Element // key -> element in order
+	order   *list.List               // Items by expiration, soonest first
+	mu      sync.Mutex
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore(opts Options) *MemoryStore {
+	store := &MemoryStore{
+		opts:    opts.withDefaults(),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredEntries()
+
+	return store
+}
+
+// Put stores a value under key, evicting the oldest entries when the store is full
+func (s *MemoryStore) Put(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.entries[key]; exists {
+		s.order.Remove(elem)
+		delete(s.entries, key)
+	}
+
+	s.evictExpired(time.Now())
+	for s.order.Len() >= s.opts.Capacity {
+		s.remove(s.order.Front())
+	}
+
+	// Every entry has the same TTL, so appending keeps the list ordered by expiration
+	s.entries[key] = s.order.PushBack(&item{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(s.opts.TTL),
+	})
+
+	return nil
+}
+
+// Take returns the value stored under key and removes it
+func (s *MemoryStore) Take(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.entries[key]
+	if !exists {
+		return "", ErrNotFound
+	}
+	s.remove(elem)
+
+	it := elem.Value.(*item)
+	if time.Now().After(it.expiresAt) {
+		return "", ErrNotFound
+	}
+
+	return it.value, nil
+}
+
+// Len returns the number of stored entries
+func (s *MemoryStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len(), nil
+}
+
+// evictExpired removes entries that expired before now. The caller must hold the lock.
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		if !now.After(elem.Value.(*item).expiresAt) {
+			return
+		}
+		s.remove(elem)
+	}
+}
+
+// remove deletes an entry. The caller must hold the lock.
+func (s *MemoryStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*item).key)
+}
+
+// cleanupExpiredEntries periodically removes expired entries
+func (s *MemoryStore) cleanupExpiredEntries() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		s.evictExpired(time.Now())
+		s.mu.Unlock()
+	}
+}
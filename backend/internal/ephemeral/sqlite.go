@@ -0,0 +1,124 @@
+package ephemeral
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// SQLiteStore keeps entries in the embedded database, so they survive a restart and are shared
+// by every instance using the same database. Each store owns a namespace in the shared table.
+type SQLiteStore struct {
+	db        *storage.DB
+	namespace string
+	opts      Options
+}
+
+// NewSQLiteStore creates a new SQLite-backed store for the namespace
+func NewSQLiteStore(db *storage.DB, namespace string, opts Options) *SQLiteStore {
+	store := &SQLiteStore{
+		db:        db,
+		namespace: namespace,
+		opts:      opts.withDefaults(),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredEntries()
+
+	return store
+}
+
+// Put stores a value under key, evicting the oldest entries when the store is full
+func (s *SQLiteStore) Put(key, value string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(
+		`DELETE FROM ephemeral WHERE namespace = ? AND (key = ? OR expires_at < ?)`,
+		s.namespace, key, now.UnixNano(),
+	); err != nil {
+		return fmt.Errorf("failed to evict entries: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM ephemeral WHERE namespace = ?`, s.namespace).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	if excess := count - s.opts.Capacity + 1; excess > 0 {
+		if _, err := tx.Exec(
+			`DELETE FROM ephemeral WHERE namespace = ? AND key IN (
+				SELECT key FROM ephemeral WHERE namespace = ? ORDER BY expires_at LIMIT ?
+			)`,
+			s.namespace, s.namespace, excess,
+		); err != nil {
+			return fmt.Errorf("failed to evict entries: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO ephemeral (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)`,
+		s.namespace, key, value, now.Add(s.opts.TTL).UnixNano(),
+	); err != nil {
+		return fmt.Errorf("failed to insert entry: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Take returns the value stored under key and removes it
+func (s *SQLiteStore) Take(key string) (string, error) {
+	var (
+		value     string
+		expiresAt int64
+	)
+
+	// Deleting and reading in one statement keeps two instances from using the same entry
+	err := s.db.QueryRow(
+		`DELETE FROM ephemeral WHERE namespace = ? AND key = ? RETURNING value, expires_at`,
+		s.namespace, key,
+	).Scan(&value, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to take entry: %w", err)
+	}
+
+	if time.Now().After(time.Unix(0, expiresAt)) {
+		return "", ErrNotFound
+	}
+
+	return value, nil
+}
+
+// Len returns the number of stored entries
+func (s *SQLiteStore) Len() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM ephemeral WHERE namespace = ?`, s.namespace).Scan(&count)
+	return count, err
+}
+
+// cleanupExpiredEntries periodically removes expired entries
+func (s *SQLiteStore) cleanupExpiredEntries() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(
+			`DELETE FROM ephemeral WHERE namespace = ? AND expires_at < ?`,
+			s.namespace, time.Now().UnixNano(),
+		); err != nil {
+			log.Error().Err(err).Str("namespace", s.namespace).Msg("Failed to clean up expired entries")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ephemeral
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("entry not found or expired")
+
+// Store keeps short-lived single-use values, such as OAuth states and login tokens.
+// Entries expire after the store's TTL. When the store is full, the oldest entries are evicted
+// to make room, so flooding it cannot grow memory or the database without limit.
+type Store interface {
+	// Put stores a value under key, replacing any existing value
+	Put(key, value string) error
+	// Take returns the value stored under key and removes it, so it can be used only once
+	Take(key string) (string, error)
+	// Len returns the number of stored entries, including expired ones not yet evicted
+	Len() (int, error)
+}
+
+// Options configure a store
+type Options struct {
+	TTL      time.Duration // How long entries stay valid
+	Capacity int           // Maximum number of entries
+}
+
+const (
+	// cleanupInterval is how often expired entries are evicted in the background
+	cleanupInterval = 1 * time.Minute
+
+	defaultTTL      = 10 * time.Minute
+	defaultCapacity = 10000
+)
+
+// withDefaults fills in unset options
+func (o Options) withDefaults() Options {
+	if o.TTL <= 0 {
+		o.TTL = defaultTTL
+	}
+	if o.Capacity <= 0 {
+		o.Capacity = defaultCapacity
+	}
+	return o
+}
@@ -0,0 +1,124 @@
+package ephemeral
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// stores runs a test against every Store implementation
+func stores(t *testing.T, opts Options, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore(opts))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		test(t, NewSQLiteStore(db, "test", opts))
+	})
+}
+
+func TestStoreTakeOnce(t *testing.T) {
+	stores(t, Options{}, func(t *testing.T, store Store) {
+		if err := store.Put("key", "first"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put("key", "second"); err != nil {
+			t.Fatal(err)
+		}
+
+		value, err := store.Take("key")
+		if err != nil || value != "second" {
+			t.Fatalf("Take = %q, %v, want the replaced value", value, err)
+		}
+		if _, err := store.Take("key"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second Take: error = %v, want ErrNotFound", err)
+		}
+		if _, err := store.Take("missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Take of a missing key: error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreExpires(t *testing.T) {
+	stores(t, Options{TTL: 20 * time.Millisecond}, func(t *testing.T, store Store) {
+		if err := store.Put("old", "value"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(30 * time.Millisecond)
+
+		if _, err := store.Take("old"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Take of an expired entry: error = %v, want ErrNotFound", err)
+		}
+
+		// Expired entries are evicted when new ones come in
+		if err := store.Put("expired", "value"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if err := store.Put("new", "value"); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := store.Len(); err != nil || n != 1 {
+			t.Errorf("Len = %d, %v, want 1", n, err)
+		}
+	})
+}
+
+func TestStoreEvictsOldestWhenFull(t *testing.T) {
+	stores(t, Options{Capacity: 3}, func(t *testing.T, store Store) {
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			if err := store.Put(key, key); err != nil {
+				t.Fatal(err)
+			}
+			// Expirations must differ for the order to be defined
+			time.Sleep(time.Millisecond)
+		}
+
+		if n, err := store.Len(); err != nil || n != 3 {
+			t.Errorf("Len = %d, %v, want the capacity of 3", n, err)
+		}
+		for _, key := range []string{"a", "b"} {
+			if _, err := store.Take(key); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Take(%q): error = %v, want the oldest entries evicted", key, err)
+			}
+		}
+		for _, key := range []string{"c", "d", "e"} {
+			if value, err := store.Take(key); err != nil || value != key {
+				t.Errorf("Take(%q) = %q, %v, want the newest entries kept", key, value, err)
+			}
+		}
+	})
+}
+
+func TestSQLiteStoreNamespaces(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	states := NewSQLiteStore(db, "states", Options{Capacity: 1})
+	tokens := NewSQLiteStore(db, "tokens", Options{Capacity: 1})
+
+	if err := states.Put("key", "state"); err != nil {
+		t.Fatal(err)
+	}
+	// A full namespace does not evict entries of another
+	if err := tokens.Put("key", "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := states.Take("key"); err != nil || value != "state" {
+		t.Errorf("states.Take = %q, %v, want state", value, err)
+	}
+	if value, err := tokens.Take("key"); err != nil || value != "token" {
+		t.Errorf("tokens.Take = %q, %v, want token", value, err)
+	}
+}
@@ -0,0 +1,181 @@
+package execution
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+// MemoryStore keeps execution records and their journals in memory
+type MemoryStore struct {
+	executions map[string]*domain.Execution // executionID -> Execution
+	mu         sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory execution store
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		executions: make(map[string]*domain.Execution),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupOldExecutions()
+
+	return store
+}
+
+// Create stores a new execution record
+func (s *MemoryStore) Create(exec *domain.Execution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.executions[exec.ID] = cloneExecution(exec)
+	return nil
+}
+
+// Get retrieves a copy of an execution owned by the user
+func (s *MemoryStore) Get(executionID, userID string) (*domain.Execution, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exec, exists := s.executions[executionID]
+	if !exists || exec.UserID != userID {
+		return nil, ErrExecutionNotFound
+	}
+
+	return cloneExecution(exec), nil
+}
+
+// ListByUser returns the user's executions, most recent first, without journals
+func (s *MemoryStore) ListByUser(userID string, limit int) ([]*domain.Execution, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*domain.Execution{}
+	for _, exec := range s.executions {
+		if exec.UserID == userID {
+			clone := cloneExecution(exec)
+			clone.Journal = nil
+			clone.Plan = nil
+			result = append(result, clone)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// AppendJournal appends an entry to an execution's journal and assigns its sequence number
+func (s *MemoryStore) AppendJournal(executionID string, entry domain.JournalEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exec, exists := s.executions[executionID]
+	if !exists {
+		return ErrExecutionNotFound
+	}
+
+	entry.Seq = len(exec.Journal) + 1
+	entry.TrackIDs = append([]string(nil), entry.TrackIDs...)
+	exec.Journal = append(exec.Journal, entry)
+
+	return nil
+}
+
+// MarkReverted flags a journal entry as rolled back
+func (s *MemoryStore) MarkReverted(executionID string, seq int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exec, exists := s.executions[executionID]
+	if !exists {
+		return ErrExecutionNotFound
+	}
+
+	for i := range exec.Journal {
+		if exec.Journal[i].Seq == seq {
+			exec.Journal[i].RolledBack = true
+		}
+	}
+
+	return nil
+}
+
+// MarkInterrupted flags running executions as interrupted
+func (s *MemoryStore) MarkInterrupted() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, exec := range s.executions {
+		if exec.Status == domain.ExecutionRunning {
+			exec.Status = domain.ExecutionInterrupted
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Update applies fn to a stored execution
+func (s *MemoryStore) Update(executionID string, fn func(exec *domain.Execution)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exec, exists := s.executions[executionID]
+	if !exists {
+		return ErrExecutionNotFound
+	}
+
+	fn(exec)
+	return nil
+}
+
+// cleanupOldExecutions periodically removes executions past the retention period
+func (s *MemoryStore) cleanupOldExecutions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		cutoff := time.Now().Add(-retention)
+
+		for executionID, exec := range s.executions {
+			if exec.StartedAt.Before(cutoff) {
+				delete(s.executions, executionID)
+			}
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// cloneExecution copies an execution so callers cannot modify the stored version
+func cloneExecution(exec *domain.Execution) *domain.Execution {
+	clone := *exec
+
+	clone.Journal = make([]domain.JournalEntry, len(exec.Journal))
+	for i, entry := range exec.Journal {
+		entry.TrackIDs = append([]string(nil), entry.TrackIDs...)
+		clone.Journal[i] = entry
+	}
+
+	if exec.Result != nil {
+		result := *exec.Result
+		result.Errors = append([]domain.ExecutionError(nil), exec.Result.Errors...)
+		clone.Result = &result
+	}
+
+	clone.Checkpoint = exec.Checkpoint.Clone()
+
+	return &clone
+}
@@ -0,0 +1,341 @@
+package execution
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// SQLiteStore keeps execution records, journals and checkpoints in the embedded database,
+// so interrupted executions survive a restart
+type SQLiteStore struct {
+	db *storage.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed execution store
+func NewSQLiteStore(db *storage.DB) *SQLiteStore {
+	store := &SQLiteStore{
+		db: db,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupOldExecutions()
+
+	return store
+}
+
+// Create stores a new execution record together with its plan
+func (s *SQLiteStore) Create(exec *domain.Execution) error {
+	data, err := marshalRecord(exec)
+	if err != nil {
+		return err
+	}
+
+	planData, err := json.Marshal(exec.Plan)
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO executions (id, user_id, plan_id, status, started_at, data, plan) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		exec.ID, exec.UserID, exec.PlanID, string(exec.Status), exec.StartedAt.UnixNano(), data, string(planData),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert execution: %w", err)
+	}
+
+	for _, entry := range exec.Journal {
+		if err := insertJournalEntry(tx, exec.ID, entry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get retrieves an execution owned by the user, including its journal and plan
+func (s *SQLiteStore) Get(executionID, userID string) (*domain.Execution, error) {
+	var data, planData string
+	err := s.db.QueryRow(
+		`SELECT data, plan FROM executions WHERE id = ? AND user_id = ?`,
+		executionID, userID,
+	).Scan(&data, &planData)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load execution: %w", err)
+	}
+
+	exec, err := unmarshalRecord(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(planData), &exec.Plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		`SELECT data FROM execution_journal WHERE execution_id = ? ORDER BY seq`,
+		executionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+	defer rows.Close()
+
+	exec.Journal = []domain.JournalEntry{}
+	for rows.Next() {
+		var entryData string
+		if err := rows.Scan(&entryData); err != nil {
+			return nil, err
+		}
+
+		var entry domain.JournalEntry
+		if err := json.Unmarshal([]byte(entryData), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode journal entry: %w", err)
+		}
+		exec.Journal = append(exec.Journal, entry)
+	}
+
+	return exec, rows.Err()
+}
+
+// ListByUser returns the user's executions, most recent first, without journals
+func (s *SQLiteStore) ListByUser(userID string, limit int) ([]*domain.Execution, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as no limit
+	}
+
+	rows, err := s.db.Query(
+		`SELECT data FROM executions WHERE user_id = ? ORDER BY started_at DESC LIMIT ?`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	defer rows.Close()
+
+	result := []*domain.Execution{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		exec, err := unmarshalRecord(data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, exec)
+	}
+
+	return result, rows.Err()
+}
+
+// AppendJournal appends an entry to an execution's journal and assigns its sequence number
+func (s *SQLiteStore) AppendJournal(executionID string, entry domain.JournalEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM executions WHERE id = ?`, executionID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrExecutionNotFound
+	}
+
+	if err := tx.QueryRow(
+		`SELECT COALESCE(MAX(seq), 0) + 1 FROM execution_journal WHERE execution_id = ?`,
+		executionID,
+	).Scan(&entry.Seq); err != nil {
+		return err
+	}
+
+	if err := insertJournalEntry(tx, executionID, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkReverted flags a journal entry as rolled back
+func (s *SQLiteStore) MarkReverted(executionID string, seq int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRow(
+		`SELECT data FROM execution_journal WHERE execution_id = ? AND seq = ?`,
+		executionID, seq,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrExecutionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var entry domain.JournalEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return fmt.Errorf("failed to decode journal entry: %w", err)
+	}
+	entry.RolledBack = true
+
+	updated, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE execution_journal SET data = ? WHERE execution_id = ? AND seq = ?`,
+		string(updated), executionID, seq,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update applies fn to an execution record. fn must not modify the journal or the plan.
+func (s *SQLiteStore) Update(executionID string, fn func(exec *domain.Execution)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRow(`SELECT data FROM executions WHERE id = ?`, executionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrExecutionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	exec, err := unmarshalRecord(data)
+	if err != nil {
+		return err
+	}
+
+	fn(exec)
+
+	updated, err := marshalRecord(exec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE executions SET status = ?, data = ? WHERE id = ?`,
+		string(exec.Status), updated, executionID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkInterrupted flags executions left running by a previous process
+func (s *SQLiteStore) MarkInterrupted() (int, error) {
+	rows, err := s.db.Query(`SELECT id FROM executions WHERE status = ?`, string(domain.ExecutionRunning))
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		err := s.Update(id, func(exec *domain.Execution) {
+			exec.Status = domain.ExecutionInterrupted
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// cleanupOldExecutions periodically removes executions past the retention period
+func (s *SQLiteStore) cleanupOldExecutions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-retention).UnixNano()
+		if _, err := s.db.Exec(`DELETE FROM executions WHERE started_at < ?`, cutoff); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up old executions")
+		}
+	}
+}
+
+// insertJournalEntry writes a journal row inside a transaction
+func insertJournalEntry(tx *sql.Tx, executionID string, entry domain.JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO execution_journal (execution_id, seq, data) VALUES (?, ?, ?)`,
+		executionID, entry.Seq, string(data),
+	); err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// marshalRecord encodes an execution without its journal, which lives in its own table
+func marshalRecord(exec *domain.Execution) (string, error) {
+	record := *exec
+	record.Journal = nil
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode execution: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// unmarshalRecord decodes an execution record
+func unmarshalRecord(data string) (*domain.Execution, error) {
+	var exec domain.Execution
+	if err := json.Unmarshal([]byte(data), &exec); err != nil {
+		return nil, fmt.Errorf("failed to decode execution: %w", err)
+	}
+
+	return &exec, nil
+}
@@ -0,0 +1,31 @@
+package execution
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+// retention is how long execution journals are kept for rollback
+const retention = 7 * 24 * time.Hour
+
+var ErrExecutionNotFound = errors.New("execution not found")
+
+// Store persists execution records, their journals and checkpoints
+type Store interface {
+	// Create stores a new execution record together with its plan
+	Create(exec *domain.Execution) error
+	// Get retrieves an execution owned by the user, including its journal and plan
+	Get(executionID, userID string) (*domain.Execution, error)
+	// ListByUser returns the user's executions, most recent first, without journals
+	ListByUser(userID string, limit int) ([]*domain.Execution, error)
+	// AppendJournal appends an entry to an execution's journal and assigns its sequence number
+	AppendJournal(executionID string, entry domain.JournalEntry) error
+	// MarkReverted flags a journal entry as rolled back
+	MarkReverted(executionID string, seq int) error
+	// Update applies fn to an execution record. fn must not modify the journal or the plan.
+	Update(executionID string, fn func(exec *domain.Execution)) error
+	// MarkInterrupted flags executions left running by a previous process and returns how many there were
+	MarkInterrupted() (int, error)
+}
@@ -0,0 +1,57 @@
+package genreregistry
+
+import "sync"
+
+// MemoryStore keeps registrations in memory. They are lost on restart.
+type MemoryStore struct {
+	users map[string]map[string]string // userID -> genre key -> playlist ID
+	mu    sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory genre registry
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users: make(map[string]map[string]string),
+	}
+}
+
+// Get returns the user's registered playlist IDs by genre key
+func (s *MemoryStore) Get(userID string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]string, len(s.users[userID]))
+	for genreKey, playlistID := range s.users[userID] {
+		result[genreKey] = playlistID
+	}
+
+	return result, nil
+}
+
+// Put registers the playlist of a genre
+func (s *MemoryStore) Put(userID, genreKey, playlistID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.users[userID] == nil {
+		s.users[userID] = make(map[string]string)
+	}
+	s.users[userID][genreKey] = playlistID
+	return nil
+}
+
+// RemovePlaylist drops the registrations of a playlist
+func (s *MemoryStore) RemovePlaylist(userID, playlistID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for genreKey, id := range s.users[userID] {
+		if id == playlistID {
+			delete(s.users[userID], genreKey)
+		}
+	}
+	if len(s.users[userID]) == 0 {
+		delete(s.users, userID)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package genreregistry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// SQLiteStore keeps registrations in the embedded database, so they survive a restart
+type SQLiteStore struct {
+	db *storage.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed genre registry
+func NewSQLiteStore(db *storage.DB) *SQLiteStore {
+	return &SQLiteStore{
+		db: db,
+	}
+}
+
+// Get returns the user's registered playlist IDs by genre key
+func (s *SQLiteStore) Get(userID string) (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT genre_key, playlist_id FROM genre_playlists WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load genre playlists: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var genreKey, playlistID string
+		if err := rows.Scan(&genreKey, &playlistID); err != nil {
+			return nil, fmt.Errorf("failed to load genre playlists: %w", err)
+		}
+		result[genreKey] = playlistID
+	}
+
+	return result, rows.Err()
+}
+
+// Put registers the playlist of a genre
+func (s *SQLiteStore) Put(userID, genreKey, playlistID string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO genre_playlists (user_id, genre_key, playlist_id, registered_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, genre_key) DO UPDATE SET playlist_id = excluded.playlist_id, registered_at = excluded.registered_at`,
+		userID, genreKey, playlistID, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register genre playlist: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePlaylist drops the registrations of a playlist
+func (s *SQLiteStore) RemovePlaylist(userID, playlistID string) error {
+	if _, err := s.db.Exec(`DELETE FROM genre_playlists WHERE user_id = ? AND playlist_id = ?`, userID, playlistID); err != nil {
+		return fmt.Errorf("failed to unregister genre playlist: %w", err)
+	}
+
+	return nil
+}
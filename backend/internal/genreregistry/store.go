@@ -0,0 +1,14 @@
+// Package genreregistry remembers which managed playlist holds which genre for every user.
+// Playlists are registered by ID, so a playlist keeps its genre when the user renames it.
+package genreregistry
+
+// Store maps a user's genre keys, normalized genre names, to playlist IDs.
+// Registrations are kept until the playlist is unregistered, there is no expiry.
+type Store interface {
+	// Get returns the user's registered playlist IDs by genre key
+	Get(userID string) (map[string]string, error)
+	// Put registers the playlist of a genre, replacing the playlist registered before
+	Put(userID, genreKey, playlistID string) error
+	// RemovePlaylist drops the registrations of a playlist, for example after it was deleted
+	RemovePlaylist(userID, playlistID string) error
+}
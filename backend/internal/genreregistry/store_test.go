@@ -0,0 +1,81 @@
+package genreregistry
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// stores runs a test against every Store implementation
+func stores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		test(t, NewSQLiteStore(db))
+	})
+}
+
+func TestStoreReplacesPlaylistOfGenre(t *testing.T) {
+	stores(t, func(t *testing.T, store Store) {
+		for _, r := range []struct{ userID, genreKey, playlistID string }{
+			{"user", "indie rock", "p1"},
+			{"user", "jazz", "p2"},
+			{"other", "jazz", "p3"},
+			{"user", "indie rock", "p4"},
+		} {
+			if err := store.Put(r.userID, r.genreKey, r.playlistID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := store.Get("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"indie rock": "p4", "jazz": "p2"}; !maps.Equal(got, want) {
+			t.Errorf("Get() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestStoreRemovesPlaylist(t *testing.T) {
+	stores(t, func(t *testing.T, store Store) {
+		for _, r := range []struct{ userID, genreKey, playlistID string }{
+			{"user", "indie rock", "p1"},
+			{"user", "jazz", "p2"},
+			{"other", "jazz", "p2"},
+		} {
+			if err := store.Put(r.userID, r.genreKey, r.playlistID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := store.RemovePlaylist("user", "p2"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.Get("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"indie rock": "p1"}; !maps.Equal(got, want) {
+			t.Errorf("Get() = %v, want %v", got, want)
+		}
+		// Registrations of other users are left alone
+		other, err := store.Get("other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"jazz": "p2"}; !maps.Equal(other, want) {
+			t.Errorf("Get() of another user = %v, want %v", other, want)
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
+)
+
+const (
+	// retention is how long finished jobs stay visible
+	retention = 1 * time.Hour
+
+	defaultTimeout   = 10 * time.Minute
+	defaultListLimit = 20
+)
+
+var (
+	ErrJobNotFound        = errors.New("job not found")
+	ErrJobFinished        = errors.New("job already finished")
+	ErrMutatingJobRunning = errors.New("another job that modifies your library is already running")
+	ErrShuttingDown       = errors.New("server is shutting down")
+	ErrJobTimedOut        = errors.New("job timed out")
+	ErrCancelled          = errors.New("job cancelled") // Context cause when a user cancels a job
+	errJobPanicked        = errors.New("job panicked")
+)
+
+// Kind identifies the operation a job runs
+type Kind string
+
+const (
+	KindAnalysis  Kind = "analysis"
+	KindPlan      Kind = "plan"
+	KindExecution Kind = "execution"
+	KindResume    Kind = "resume"
+	KindRollback  Kind = "rollback"
+)
+
+// Status describes where a job stands
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Func is the work a job runs. The context is cancelled when the job is cancelled or times out,
+// and carries the job ID so progress events are tagged with it. context.Cause tells the reasons apart:
+// ErrCancelled when a user cancelled the job, ErrShuttingDown on server shutdown,
+// and context.DeadlineExceeded on timeout.
+type Func func(ctx context.Context) (any, error)
+
+// Spec describes a job to start
+type Spec struct {
+	Kind     Kind
+	Mutating bool          // Mutating jobs change the user's library, only one runs per user at a time
+	Timeout  time.Duration // Defaults to 10 minutes
+}
+
+// Job is a long-running operation owned by a user
+type Job struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Kind       Kind       `json:"kind"`
+	Mutating   bool       `json:"mutating"`
+	Status     Status     `json:"status"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+
+	err       error // Error returned by the job, kept for status mapping
+	cancel    context.CancelCauseFunc
+	stop      context.CancelFunc // Releases the timeout
+	cancelled bool
+	done      chan struct{}
+}
+
+// Err returns the error the job failed with
+func (j *Job) Err() error {
+	return j.err
+}
+
+// Finished reports whether the job is no longer running
+func (j *Job) Finished() bool {
+	return j.Status != StatusRunning
+}
+
+// Manager runs jobs in the background and keeps their status and result
+type Manager struct {
+	jobs     map[string]*Job // jobID -> job
+	closed   bool
+	mu       sync.RWMutex
+	inFlight sync.WaitGroup
+}
+
+// NewManager creates a new job manager
+func NewManager() *Manager {
+	manager := &Manager{
+		jobs: make(map[string]*Job),
+	}
+
+	// Start cleanup goroutine
+	go manager.cleanupFinishedJobs()
+
+	return manager
+}
+
+// Start runs fn as a new job for the user.
+// If the job is mutating and the user already has a mutating job running,
+// that job is returned together with ErrMutatingJobRunning.
+func (m *Manager) Start(userID string, spec Spec, fn Func) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrShuttingDown
+	}
+
+	if spec.Mutating {
+		for _, existing := range m.jobs {
+			if existing.UserID == userID && existing.Mutating && !existing.Finished() {
+				return cloneJob(existing), ErrMutatingJobRunning
+			}
+		}
+	}
+
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	j := &Job{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Kind:      spec.Kind,
+		Mutating:  spec.Mutating,
+		Status:    StatusRunning,
+		CreatedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	// Jobs outlive the request that started them
+	timeoutCtx, stop := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithCancelCause(timeoutCtx)
+	ctx = sse.ContextWithJobID(ctx, j.ID)
+	j.cancel = cancel
+	j.stop = stop
+
+	m.jobs[j.ID] = j
+	m.inFlight.Add(1)
+	go m.run(ctx, j, fn)
+
+	log.Info().Str("jobID", j.ID).Str("userID", userID).Str("kind", string(spec.Kind)).Msg("Job started")
+
+	return cloneJob(j), nil
+}
+
+// run executes a job and records its outcome
+func (m *Manager) run(ctx context.Context, j *Job, fn Func) {
+	defer m.inFlight.Done()
+	defer j.stop()
+	defer j.cancel(nil)
+
+	result, err := m.call(ctx, j, fn)
+
+	m.mu.Lock()
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Result = result
+	if err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// The job stopped early because it ran out of time, its partial result is kept
+		err = ErrJobTimedOut
+	}
+	switch {
+	case j.cancelled || m.closed:
+		j.Status = StatusCancelled
+		if err != nil {
+			j.err = err
+			j.Error = err.Error()
+		}
+	case err != nil:
+		j.Status = StatusFailed
+		j.err = err
+		j.Error = err.Error()
+	default:
+		j.Status = StatusSucceeded
+	}
+	status := j.Status
+	m.mu.Unlock()
+
+	close(j.done)
+
+	log.Info().Str("jobID", j.ID).Str("kind", string(j.Kind)).Str("status", string(status)).Err(err).Msg("Job finished")
+}
+
+// call runs fn, turning a panic into a job failure
+func (m *Manager) call(ctx context.Context, j *Job, fn Func) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("jobID", j.ID).Interface("panic", r).Msg("Job panicked")
+			result, err = nil, errJobPanicked
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// Get retrieves a job owned by the user
+func (m *Manager) Get(jobID, userID string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	j, exists := m.jobs[jobID]
+	if !exists || j.UserID != userID {
+		// Jobs of other users are reported as missing
+		return nil, ErrJobNotFound
+	}
+
+	return cloneJob(j), nil
+}
+
+// Wait blocks until the job finishes or ctx is done, and returns the job
+func (m *Manager) Wait(ctx context.Context, jobID, userID string) (*Job, error) {
+	m.mu.RLock()
+	j, exists := m.jobs[jobID]
+	m.mu.RUnlock()
+
+	if !exists || j.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return m.Get(jobID, userID)
+}
+
+// List returns the user's most recent jobs, newest first
+func (m *Manager) List(userID string, limit int) []*Job {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := []*Job{}
+	for _, j := range m.jobs {
+		if j.UserID == userID {
+			result = append(result, cloneJob(j))
+		}
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].CreatedAt.After(result[k].CreatedAt)
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// Cancel requests cancellation of a running job.
+// The job stops at its next cancellation point and ends up cancelled.
+func (m *Manager) Cancel(jobID, userID string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, exists := m.jobs[jobID]
+	if !exists || j.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+
+	if j.Finished() {
+		return nil, ErrJobFinished
+	}
+
+	j.cancelled = true
+	j.cancel(ErrCancelled)
+
+	log.Info().Str("jobID", jobID).Str("userID", userID).Msg("Job cancellation requested")
+
+	return cloneJob(j), nil
+}
+
+// CancelAll requests cancellation of every running job of the user and returns those jobs
+func (m *Manager) CancelAll(userID string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cancelled := []*Job{}
+	for _, j := range m.jobs {
+		if j.UserID != userID || j.Finished() {
+			continue
+		}
+
+		j.cancelled = true
+		j.cancel(ErrCancelled)
+		cancelled = append(cancelled, cloneJob(j))
+	}
+
+	if len(cancelled) > 0 {
+		log.Info().Str("userID", userID).Int("jobs", len(cancelled)).Msg("Job cancellation requested")
+	}
+
+	return cancelled
+}
+
+// Shutdown stops accepting jobs, cancels running ones and waits for them to finish or for ctx to end
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	for _, j := range m.jobs {
+		if !j.Finished() {
+			j.cancel(ErrShuttingDown)
+		}
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cleanupFinishedJobs periodically removes finished jobs past the retention period
+func (m *Manager) cleanupFinishedJobs() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.mu.Lock()
+		cutoff := time.Now().Add(-retention)
+
+		for jobID, j := range m.jobs {
+			if j.FinishedAt != nil && j.FinishedAt.Before(cutoff) {
+				delete(m.jobs, jobID)
+			}
+		}
+
+		m.mu.Unlock()
+	}
+}
+
+// cloneJob copies a job so callers can read it without holding the lock
+func cloneJob(j *Job) *Job {
+	clone := *j
+	if j.FinishedAt != nil {
+		finishedAt := *j.FinishedAt
+		clone.FinishedAt = &finishedAt
+	}
+	return &clone
+}
@@ -0,0 +1,269 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// wait waits for a job to finish, failing the test when it takes too long
+func wait(t *testing.T, m *Manager, j *Job) *Job {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	finished, err := m.Wait(ctx, j.ID, j.UserID)
+	if err != nil {
+		t.Fatalf("wait for job: %v", err)
+	}
+	return finished
+}
+
+func TestJobRunsInBackground(t *testing.T) {
+	m := NewManager()
+
+	var jobID string
+	j, err := m.Start("user", Spec{Kind: KindAnalysis}, func(ctx context.Context) (any, error) {
+		jobID = sse.JobIDFromContext(ctx)
+		return "result", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := wait(t, m, j)
+	if finished.Status != StatusSucceeded || finished.Result != "result" || finished.FinishedAt == nil {
+		t.Errorf("finished job = %+v, want succeeded with its result", finished)
+	}
+	if jobID != j.ID {
+		t.Errorf("job context carries ID %q, want %q", jobID, j.ID)
+	}
+
+	// Other users cannot see the job
+	if _, err := m.Get(j.ID, "other"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get by another user: error = %v, want ErrJobNotFound", err)
+	}
+	if jobs := m.List("other", 0); len(jobs) != 0 {
+		t.Errorf("another user lists %d jobs, want 0", len(jobs))
+	}
+}
+
+func TestJobFailures(t *testing.T) {
+	m := NewManager()
+	failure := errors.New("failure")
+
+	tests := []struct {
+		name string
+		fn   Func
+		want error
+	}{
+		{"error", func(ctx context.Context) (any, error) { return nil, failure }, failure},
+		{"panic", func(ctx context.Context) (any, error) { panic("boom") }, errJobPanicked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := m.Start("user", Spec{Kind: KindPlan}, tt.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			finished := wait(t, m, j)
+			if finished.Status != StatusFailed || !errors.Is(finished.Err(), tt.want) || finished.Error != tt.want.Error() {
+				t.Errorf("finished job = %+v, want failed with %v", finished, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutatingJobsRunOneAtATimePerUser(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	block := func(ctx context.Context) (any, error) {
+		<-release
+		return nil, nil
+	}
+
+	running, err := m.Start("user", Spec{Kind: KindExecution, Mutating: true}, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err := m.Start("user", Spec{Kind: KindRollback, Mutating: true}, block)
+	if !errors.Is(err, ErrMutatingJobRunning) || existing == nil || existing.ID != running.ID {
+		t.Errorf("second mutating job: job %+v, error %v, want the running job and ErrMutatingJobRunning", existing, err)
+	}
+
+	// Reading jobs and other users are not blocked
+	reading, err := m.Start("user", Spec{Kind: KindAnalysis}, block)
+	if err != nil {
+		t.Errorf("non-mutating job: %v", err)
+	}
+	other, err := m.Start("other", Spec{Kind: KindExecution, Mutating: true}, block)
+	if err != nil {
+		t.Errorf("mutating job of another user: %v", err)
+	}
+
+	close(release)
+	wait(t, m, running)
+	wait(t, m, reading)
+	wait(t, m, other)
+
+	// Once the first one finished the next may start
+	next, err := m.Start("user", Spec{Kind: KindRollback, Mutating: true}, block)
+	if err != nil {
+		t.Fatalf("mutating job after the previous finished: %v", err)
+	}
+	wait(t, m, next)
+}
+
+func TestJobTimeout(t *testing.T) {
+	m := NewManager()
+
+	var cause error
+	j, err := m.Start("user", Spec{Kind: KindAnalysis, Timeout: 10 * time.Millisecond}, func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return "partial", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := wait(t, m, j)
+	if !errors.Is(cause, context.DeadlineExceeded) {
+		t.Errorf("context cause = %v, want context.DeadlineExceeded", cause)
+	}
+	if finished.Status != StatusFailed || !errors.Is(finished.Err(), ErrJobTimedOut) || finished.Result != "partial" {
+		t.Errorf("finished job = %+v, want failed with ErrJobTimedOut and its partial result", finished)
+	}
+}
+
+func TestListNewestFirst(t *testing.T) {
+	m := NewManager()
+
+	var jobs []*Job
+	for i := 0; i < 3; i++ {
+		j, err := m.Start("user", Spec{Kind: KindAnalysis}, func(ctx context.Context) (any, error) { return nil, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		wait(t, m, j)
+		jobs = append(jobs, j)
+		time.Sleep(time.Millisecond)
+	}
+
+	listed := m.List("user", 2)
+	if len(listed) != 2 || listed[0].ID != jobs[2].ID || listed[1].ID != jobs[1].ID {
+		t.Errorf("List returned %d jobs, want the 2 newest first", len(listed))
+	}
+}
+
+func TestCancelStopsJob(t *testing.T) {
+	m := NewManager()
+
+	started := make(chan struct{})
+	var cause error
+	j, err := m.Start("user", Spec{Kind: KindExecution, Mutating: true}, func(ctx context.Context) (any, error) {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return "partial", ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	if _, err := m.Cancel(j.ID, "other"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("cancel by another user: error = %v, want ErrJobNotFound", err)
+	}
+	if _, err := m.Cancel(j.ID, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	finished := wait(t, m, j)
+	if !errors.Is(cause, ErrCancelled) {
+		t.Errorf("context cause = %v, want ErrCancelled", cause)
+	}
+	if finished.Status != StatusCancelled || finished.Result != "partial" {
+		t.Errorf("finished job = %+v, want cancelled with its partial result", finished)
+	}
+
+	if _, err := m.Cancel(j.ID, "user"); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("cancelling a finished job: error = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestCancelAllOnlyCancelsUsersJobs(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	block := func(ctx context.Context) (any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return nil, nil
+		}
+	}
+
+	analysis, _ := m.Start("user", Spec{Kind: KindAnalysis}, block)
+	execution, _ := m.Start("user", Spec{Kind: KindExecution, Mutating: true}, block)
+	other, _ := m.Start("other", Spec{Kind: KindAnalysis}, block)
+
+	if cancelled := m.CancelAll("user"); len(cancelled) != 2 {
+		t.Errorf("CancelAll cancelled %d jobs, want 2", len(cancelled))
+	}
+	close(release)
+
+	for _, j := range []*Job{analysis, execution} {
+		if finished := wait(t, m, j); finished.Status != StatusCancelled {
+			t.Errorf("%s job ended %s, want cancelled", j.Kind, finished.Status)
+		}
+	}
+	if finished := wait(t, m, other); finished.Status != StatusSucceeded {
+		t.Errorf("job of another user ended %s, want succeeded", finished.Status)
+	}
+}
+
+func TestShutdownCancelsRunningJobs(t *testing.T) {
+	m := NewManager()
+
+	var cause error
+	j, err := m.Start("user", Spec{Kind: KindAnalysis}, func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(cause, ErrShuttingDown) {
+		t.Errorf("context cause = %v, want ErrShuttingDown", cause)
+	}
+	if finished, _ := m.Get(j.ID, "user"); finished.Status != StatusCancelled {
+		t.Errorf("job ended %s, want cancelled", finished.Status)
+	}
+	if _, err := m.Start("user", Spec{Kind: KindAnalysis}, func(ctx context.Context) (any, error) { return nil, nil }); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("starting a job after shutdown: error = %v, want ErrShuttingDown", err)
+	}
+}
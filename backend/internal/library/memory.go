@@ -0,0 +1,78 @@
+package library
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps snapshots in memory. Snapshots are lost on restart.
+type MemoryStore struct {
+	snapshots map[string]*Snapshot
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory snapshot store
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		snapshots: make(map[string]*Snapshot),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupUnusedSnapshots()
+
+	return store
+}
+
+// Get returns the user's snapshot
+func (s *MemoryStore) Get(userID string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, exists := s.snapshots[userID]
+	if !exists {
+		return nil, ErrSnapshotNotFound
+	}
+
+	copied := *snapshot
+	copied.Tracks = stripTracks(snapshot.Tracks)
+	copied.Unliked = stripTracks(snapshot.Unliked)
+	return &copied, nil
+}
+
+// Save stores the user's snapshot
+func (s *MemoryStore) Save(snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *snapshot
+	copied.Tracks = stripTracks(snapshot.Tracks)
+	copied.Unliked = stripTracks(snapshot.Unliked)
+	s.snapshots[snapshot.UserID] = &copied
+
+	return nil
+}
+
+// Delete removes the user's snapshot
+func (s *MemoryStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.snapshots, userID)
+	return nil
+}
+
+// cleanupUnusedSnapshots periodically removes snapshots that were not synced for a long time
+func (s *MemoryStore) cleanupUnusedSnapshots() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for userID, snapshot := range s.snapshots {
+			if time.Since(snapshot.SyncedAt) > snapshotTTL {
+				delete(s.snapshots, userID)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,108 @@
+package library
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// SQLiteStore keeps snapshots in the embedded database, so incremental syncs survive a restart
+type SQLiteStore struct {
+	db *storage.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed snapshot store
+func NewSQLiteStore(db *storage.DB) *SQLiteStore {
+	store := &SQLiteStore{
+		db: db,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupUnusedSnapshots()
+
+	return store
+}
+
+// Get returns the user's snapshot
+func (s *SQLiteStore) Get(userID string) (*Snapshot, error) {
+	var (
+		snapshot             Snapshot
+		tracks, unliked      string
+		syncedAt, fullSyncAt int64
+	)
+
+	err := s.db.QueryRow(
+		`SELECT user_id, tracks, unliked, synced_at, full_sync_at FROM library_snapshots WHERE user_id = ?`,
+		userID,
+	).Scan(&snapshot.UserID, &tracks, &unliked, &syncedAt, &fullSyncAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load library snapshot: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(tracks), &snapshot.Tracks); err != nil {
+		return nil, fmt.Errorf("failed to decode library snapshot: %w", err)
+	}
+	if err := json.Unmarshal([]byte(unliked), &snapshot.Unliked); err != nil {
+		return nil, fmt.Errorf("failed to decode library snapshot: %w", err)
+	}
+	snapshot.SyncedAt = time.Unix(0, syncedAt)
+	snapshot.FullSyncAt = time.Unix(0, fullSyncAt)
+
+	return &snapshot, nil
+}
+
+// Save stores the user's snapshot
+func (s *SQLiteStore) Save(snapshot *Snapshot) error {
+	tracks, err := json.Marshal(stripTracks(snapshot.Tracks))
+	if err != nil {
+		return fmt.Errorf("failed to encode library snapshot: %w", err)
+	}
+	unliked, err := json.Marshal(stripTracks(snapshot.Unliked))
+	if err != nil {
+		return fmt.Errorf("failed to encode library snapshot: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO library_snapshots (user_id, tracks, unliked, synced_at, full_sync_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET tracks = excluded.tracks, unliked = excluded.unliked,
+			synced_at = excluded.synced_at, full_sync_at = excluded.full_sync_at`,
+		snapshot.UserID, string(tracks), string(unliked), snapshot.SyncedAt.UnixNano(), snapshot.FullSyncAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save library snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the user's snapshot
+func (s *SQLiteStore) Delete(userID string) error {
+	if _, err := s.db.Exec(`DELETE FROM library_snapshots WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete library snapshot: %w", err)
+	}
+	return nil
+}
+
+// cleanupUnusedSnapshots periodically removes snapshots that were not synced for a long time
+func (s *SQLiteStore) cleanupUnusedSnapshots() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(
+			`DELETE FROM library_snapshots WHERE synced_at < ?`,
+			time.Now().Add(-snapshotTTL).UnixNano(),
+		); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up library snapshots")
+		}
+	}
+}
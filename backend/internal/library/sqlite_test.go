@@ -0,0 +1,45 @@
+package library
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+func TestSQLiteStoreKeepsUnliked(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := NewSQLiteStore(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err = store.Save(&Snapshot{
+		UserID:     "user",
+		Tracks:     []domain.Track{{ID: "liked", Name: "Liked"}},
+		Unliked:    []domain.Track{{ID: "unliked", Name: "Unliked", PrimaryGenre: "rock", InPlaylists: []string{"p1"}}},
+		SyncedAt:   now,
+		FullSyncAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := store.Get("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Tracks) != 1 || snapshot.Tracks[0].ID != "liked" {
+		t.Errorf("tracks = %+v, want only liked", snapshot.Tracks)
+	}
+	if len(snapshot.Unliked) != 1 || snapshot.Unliked[0].ID != "unliked" {
+		t.Fatalf("unliked = %+v, want only unliked", snapshot.Unliked)
+	}
+	if u := snapshot.Unliked[0]; u.PrimaryGenre != "" || u.InPlaylists != nil {
+		t.Errorf("unliked track kept derived fields: %+v", u)
+	}
+}
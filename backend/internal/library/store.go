@@ -0,0 +1,57 @@
+package library
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+const (
+	// snapshotTTL is how long a snapshot is kept without being synced
+	snapshotTTL = 30 * 24 * time.Hour
+
+	// cleanupInterval is how often unused snapshots are removed in the background
+	cleanupInterval = 1 * time.Hour
+)
+
+var ErrSnapshotNotFound = errors.New("library snapshot not found")
+
+// Snapshot is the last known state of a user's liked songs, so later analyses
+// only need to fetch what changed
+type Snapshot struct {
+	UserID     string
+	Tracks     []domain.Track // Newest first, without genres or playlist membership
+	Unliked    []domain.Track // Songs unliked since they were synced, kept while managed playlists still hold them
+	SyncedAt   time.Time      // Last sync, incremental or full
+	FullSyncAt time.Time      // Last time the whole library was fetched
+}
+
+// Store keeps one liked songs snapshot per user
+type Store interface {
+	// Get returns the user's snapshot
+	Get(userID string) (*Snapshot, error)
+	// Save stores the user's snapshot, replacing the previous one
+	Save(snapshot *Snapshot) error
+	// Delete removes the user's snapshot
+	Delete(userID string) error
+}
+
+// stripTracks copies tracks without the fields an analysis derives, genres change independently
+// of the library and playlist membership is fetched again every time
+func stripTracks(tracks []domain.Track) []domain.Track {
+	stripped := make([]domain.Track, len(tracks))
+	for i, track := range tracks {
+		track.PrimaryGenre = ""
+		track.InPlaylists = nil
+
+		artists := make([]domain.Artist, len(track.Artists))
+		for j, artist := range track.Artists {
+			artists[j] = domain.Artist{ID: artist.ID, Name: artist.Name}
+		}
+		track.Artists = artists
+
+		stripped[i] = track
+	}
+	return stripped
+}
@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+var ErrUnknownEntry = errors.New("unknown plan entry")
+
+// Selection approves (true) or rejects (false) individual plan entries
+type Selection struct {
+	Moves         map[string]bool // TrackMove ID -> approved (adds and removals)
+	Playlists     map[string]bool // Genre name from PlaylistsToCreate -> approved
+	Uncategorized map[string]bool // Track ID from UncategorizedTracks -> approved
+	Merges        map[string]bool // PlaylistMerge or GroupMerge ID -> approved
+}
+
+// ApplySelection applies a selection to a plan.
+// The whole selection is rejected if it references an entry that is not in the plan.
+func ApplySelection(p *domain.SortPlan, sel Selection) error {
+	moveIndex := make(map[string]*domain.TrackMove)
+	for i := range p.TracksToAdd {
+		moveIndex[p.TracksToAdd[i].ID] = &p.TracksToAdd[i]
+	}
+	for i := range p.TracksToRemove {
+		moveIndex[p.TracksToRemove[i].ID] = &p.TracksToRemove[i]
+	}
+
+	playlists := make(map[string]bool)
+	for _, name := range p.PlaylistsToCreate {
+		playlists[name] = true
+	}
+
+	uncategorized := make(map[string]bool)
+	for _, track := range p.UncategorizedTracks {
+		uncategorized[track.ID] = true
+	}
+
+	mergeIndex := make(map[string]*bool) // Merge ID -> its Approved flag
+	for i := range p.PlaylistMerges {
+		mergeIndex[p.PlaylistMerges[i].ID] = &p.PlaylistMerges[i].Approved
+	}
+	for i := range p.GroupMerges {
+		mergeIndex[p.GroupMerges[i].ID] = &p.GroupMerges[i].Approved
+	}
+
+	// Validate everything first so a bad entry leaves the plan untouched
+	for id := range sel.Moves {
+		if _, ok := moveIndex[id]; !ok {
+			return fmt.Errorf("%w: move %s", ErrUnknownEntry, id)
+		}
+	}
+	for name := range sel.Playlists {
+		if !playlists[name] {
+			return fmt.Errorf("%w: playlist %s", ErrUnknownEntry, name)
+		}
+	}
+	for trackID := range sel.Uncategorized {
+		if !uncategorized[trackID] {
+			return fmt.Errorf("%w: uncategorized track %s", ErrUnknownEntry, trackID)
+		}
+	}
+	for id := range sel.Merges {
+		if _, ok := mergeIndex[id]; !ok {
+			return fmt.Errorf("%w: merge %s", ErrUnknownEntry, id)
+		}
+	}
+
+	for id, approved := range sel.Moves {
+		moveIndex[id].Approved = approved
+	}
+	for id, approved := range sel.Merges {
+		*mergeIndex[id] = approved
+	}
+
+	if p.RejectedPlaylists == nil {
+		p.RejectedPlaylists = make(map[string]bool)
+	}
+	for name, approved := range sel.Playlists {
+		if approved {
+			delete(p.RejectedPlaylists, name)
+		} else {
+			p.RejectedPlaylists[name] = true
+		}
+	}
+
+	if p.RejectedUncategorized == nil {
+		p.RejectedUncategorized = make(map[string]bool)
+	}
+	for trackID, approved := range sel.Uncategorized {
+		if approved {
+			delete(p.RejectedUncategorized, trackID)
+		} else {
+			p.RejectedUncategorized[trackID] = true
+		}
+	}
+
+	return nil
+}
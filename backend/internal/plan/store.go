@@ -0,0 +1,206 @@
+package plan
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+// DefaultTTL is how long a generated plan stays executable
+const DefaultTTL = 30 * time.Minute
+
+var (
+	ErrPlanNotFound        = errors.New("plan not found")
+	ErrPlanExpired         = errors.New("plan expired")
+	ErrPlanAlreadyExecuted = errors.New("plan already executed")
+	ErrLibraryChanged      = errors.New("library changed since plan was generated")
+)
+
+// entry is a stored plan together with its owner
+type entry struct {
+	userID     string
+	plan       *domain.SortPlan
+	executedAt *time.Time
+}
+
+// Store keeps generated sort plans in memory, keyed by plan ID and user
+type Store struct {
+	plans map[string]*entry // planID -> entry
+	ttl   time.Duration
+	mu    sync.RWMutex
+}
+
+// NewStore creates a new plan store
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	store := &Store{
+		plans: make(map[string]*entry),
+		ttl:   ttl,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredPlans()
+
+	return store
+}
+
+// Save stores a plan for a user and stamps its expiration
+func (s *Store) Save(userID string, p *domain.SortPlan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.ExpiresAt = time.Now().Add(s.ttl)
+	s.plans[p.ID] = &entry{
+		userID: userID,
+		plan:   p,
+	}
+}
+
+// Get retrieves a copy of a plan owned by the user
+func (s *Store) Get(planID, userID string) (*domain.SortPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, err := s.lookup(planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return clonePlan(e.plan), nil
+}
+
+// Claim marks a plan as executed and returns a copy of it.
+// A plan can only be claimed once, so concurrent executions of the same plan are rejected.
+func (s *Store) Claim(planID, userID string) (*domain.SortPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.lookup(planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.executedAt != nil {
+		return nil, ErrPlanAlreadyExecuted
+	}
+
+	now := time.Now()
+	e.executedAt = &now
+
+	return clonePlan(e.plan), nil
+}
+
+// Update applies fn to a stored plan that has not been executed yet and returns a copy of the result
+func (s *Store) Update(planID, userID string, fn func(p *domain.SortPlan) error) (*domain.SortPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.lookup(planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.executedAt != nil {
+		return nil, ErrPlanAlreadyExecuted
+	}
+
+	// Work on a copy so a failing update leaves the stored plan untouched
+	updated := clonePlan(e.plan)
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	e.plan = updated
+
+	return clonePlan(updated), nil
+}
+
+// Release makes a claimed plan executable again (used when execution never started)
+func (s *Store) Release(planID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, exists := s.plans[planID]; exists {
+		e.executedAt = nil
+	}
+}
+
+// Delete removes a plan
+func (s *Store) Delete(planID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.plans, planID)
+}
+
+// lookup finds a plan owned by the user. Callers must hold the lock.
+func (s *Store) lookup(planID, userID string) (*entry, error) {
+	e, exists := s.plans[planID]
+	if !exists || e.userID != userID {
+		// Plans of other users are reported as missing
+		return nil, ErrPlanNotFound
+	}
+
+	if time.Now().After(e.plan.ExpiresAt) {
+		return nil, ErrPlanExpired
+	}
+
+	return e, nil
+}
+
+// cleanupExpiredPlans periodically removes expired plans
+func (s *Store) cleanupExpiredPlans() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+
+		for planID, e := range s.plans {
+			if now.After(e.plan.ExpiresAt) {
+				delete(s.plans, planID)
+			}
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// Count returns the number of stored plans
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.plans)
+}
+
+// clonePlan copies a plan so callers can modify it without affecting the stored version
+func clonePlan(p *domain.SortPlan) *domain.SortPlan {
+	clone := *p
+	clone.TracksToAdd = append([]domain.TrackMove(nil), p.TracksToAdd...)
+	clone.TracksToRemove = append([]domain.TrackMove(nil), p.TracksToRemove...)
+	clone.PlaylistsToCreate = append([]string(nil), p.PlaylistsToCreate...)
+	clone.PlaylistMerges = append([]domain.PlaylistMerge(nil), p.PlaylistMerges...)
+	clone.GroupMerges = append([]domain.GroupMerge(nil), p.GroupMerges...)
+	clone.UncategorizedTracks = append([]domain.Track(nil), p.UncategorizedTracks...)
+	clone.GenreStats = append([]domain.GenreStat(nil), p.GenreStats...)
+
+	clone.EnabledGroups = cloneSet(p.EnabledGroups)
+	clone.RejectedPlaylists = cloneSet(p.RejectedPlaylists)
+	clone.RejectedUncategorized = cloneSet(p.RejectedUncategorized)
+
+	return &clone
+}
+
+func cloneSet(m map[string]bool) map[string]bool {
+	clone := make(map[string]bool, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,166 @@
+package plan
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+func testPlan() *domain.SortPlan {
+	return &domain.SortPlan{
+		ID: "plan-1",
+		TracksToAdd: []domain.TrackMove{
+			{ID: "add:t1", TrackID: "t1", ToPlaylist: "rock", Approved: true},
+			{ID: "add:t2", TrackID: "t2", ToPlaylist: "jazz", Approved: true},
+		},
+		TracksToRemove: []domain.TrackMove{
+			{ID: "remove:t1", TrackID: "t1", FromPlaylist: "p-jazz", Approved: true},
+		},
+		PlaylistsToCreate:   []string{"rock", "jazz"},
+		PlaylistMerges:      []domain.PlaylistMerge{{ID: "merge:jazz", Genre: "jazz", Approved: true}},
+		UncategorizedTracks: []domain.Track{{ID: "t3"}},
+	}
+}
+
+func TestStoreClaimOnce(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Save("user-1", testPlan())
+
+	if _, err := store.Claim("plan-1", "user-2"); !errors.Is(err, ErrPlanNotFound) {
+		t.Errorf("claim by another user: err = %v, want ErrPlanNotFound", err)
+	}
+
+	if _, err := store.Claim("plan-1", "user-1"); err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if _, err := store.Claim("plan-1", "user-1"); !errors.Is(err, ErrPlanAlreadyExecuted) {
+		t.Errorf("second claim: err = %v, want ErrPlanAlreadyExecuted", err)
+	}
+	if _, err := store.Update("plan-1", "user-1", func(*domain.SortPlan) error { return nil }); !errors.Is(err, ErrPlanAlreadyExecuted) {
+		t.Errorf("update of a claimed plan: err = %v, want ErrPlanAlreadyExecuted", err)
+	}
+
+	store.Release("plan-1")
+	if _, err := store.Claim("plan-1", "user-1"); err != nil {
+		t.Errorf("claim after release: %v", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := NewStore(10 * time.Millisecond)
+	store.Save("user-1", testPlan())
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := store.Get("plan-1", "user-1"); !errors.Is(err, ErrPlanExpired) {
+		t.Errorf("get: err = %v, want ErrPlanExpired", err)
+	}
+	if _, err := store.Claim("plan-1", "user-1"); !errors.Is(err, ErrPlanExpired) {
+		t.Errorf("claim: err = %v, want ErrPlanExpired", err)
+	}
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Save("user-1", testPlan())
+
+	p, err := store.Get("plan-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TracksToAdd[0].Approved = false
+	p.PlaylistsToCreate[0] = "changed"
+
+	stored, err := store.Get("plan-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.TracksToAdd[0].Approved || stored.PlaylistsToCreate[0] != "rock" {
+		t.Error("modifying a returned plan changed the stored plan")
+	}
+}
+
+func TestStoreUpdateSelection(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Save("user-1", testPlan())
+
+	updated, err := store.Update("plan-1", "user-1", func(p *domain.SortPlan) error {
+		return ApplySelection(p, Selection{
+			Moves:         map[string]bool{"add:t2": false},
+			Playlists:     map[string]bool{"jazz": false},
+			Uncategorized: map[string]bool{"t3": false},
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TracksToAdd[1].Approved {
+		t.Error("rejected move is still approved")
+	}
+	if got := updated.ApprovedPlaylistsToCreate(); len(got) != 1 || got[0] != "rock" {
+		t.Errorf("approved playlists = %v, want [rock]", got)
+	}
+	if got := updated.ApprovedUncategorizedTracks(); len(got) != 0 {
+		t.Errorf("approved uncategorized tracks = %v, want none", got)
+	}
+
+	// Approving again undoes the rejection
+	updated, err = store.Update("plan-1", "user-1", func(p *domain.SortPlan) error {
+		return ApplySelection(p, Selection{Playlists: map[string]bool{"jazz": true}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := updated.ApprovedPlaylistsToCreate(); len(got) != 2 {
+		t.Errorf("approved playlists = %v, want both", got)
+	}
+}
+
+func TestStoreUpdateRejectsUnknownEntry(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Save("user-1", testPlan())
+
+	_, err := store.Update("plan-1", "user-1", func(p *domain.SortPlan) error {
+		return ApplySelection(p, Selection{
+			Moves: map[string]bool{"add:t1": false, "add:missing": false},
+		})
+	})
+	if !errors.Is(err, ErrUnknownEntry) {
+		t.Fatalf("err = %v, want ErrUnknownEntry", err)
+	}
+
+	stored, err := store.Get("plan-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.TracksToAdd[0].Approved {
+		t.Error("a rejected selection changed the stored plan")
+	}
+}
+
+func TestStoreUpdateMergeSelection(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Save("user-1", testPlan())
+
+	before, err := store.Get("plan-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := store.Update("plan-1", "user-1", func(p *domain.SortPlan) error {
+		return ApplySelection(p, Selection{Merges: map[string]bool{"merge:jazz": false}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := updated.ApprovedPlaylistMerges(); len(got) != 0 {
+		t.Errorf("approved merges = %v, want none", got)
+	}
+
+	// Copies handed out earlier keep their state
+	if !before.PlaylistMerges[0].Approved {
+		t.Error("updating the stored plan changed a copy handed out before")
+	}
+}
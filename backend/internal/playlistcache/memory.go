@@ -0,0 +1,72 @@
+package playlistcache
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore caches playlist contents in memory. The cache is lost on restart.
+type MemoryStore struct {
+	entries map[string]*Entry
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory playlist cache
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		entries: make(map[string]*Entry),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupOldEntries()
+
+	return store
+}
+
+// GetMany returns the cached entries among playlistIDs
+func (s *MemoryStore) GetMany(playlistIDs []string) (map[string]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]*Entry)
+	for _, id := range playlistIDs {
+		if entry, exists := s.entries[id]; exists {
+			result[id] = copyEntry(entry)
+		}
+	}
+
+	return result, nil
+}
+
+// Put stores an entry
+func (s *MemoryStore) Put(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.PlaylistID] = copyEntry(entry)
+	return nil
+}
+
+// Len returns the number of cached playlists
+func (s *MemoryStore) Len() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.entries), nil
+}
+
+// cleanupOldEntries periodically removes entries fetched long ago
+func (s *MemoryStore) cleanupOldEntries() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for id, entry := range s.entries {
+			if time.Since(entry.FetchedAt) > entryTTL {
+				delete(s.entries, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
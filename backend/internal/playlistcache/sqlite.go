@@ -0,0 +1,122 @@
+package playlistcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// maxQueryIDs bounds the IDs looked up per query, SQLite limits the number of parameters
+const maxQueryIDs = 500
+
+// SQLiteStore caches playlist contents in the embedded database, so the cache survives a restart
+type SQLiteStore struct {
+	db *storage.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed playlist cache
+func NewSQLiteStore(db *storage.DB) *SQLiteStore {
+	store := &SQLiteStore{
+		db: db,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupOldEntries()
+
+	return store
+}
+
+// GetMany returns the cached entries among playlistIDs
+func (s *SQLiteStore) GetMany(playlistIDs []string) (map[string]*Entry, error) {
+	result := make(map[string]*Entry)
+
+	for start := 0; start < len(playlistIDs); start += maxQueryIDs {
+		chunk := playlistIDs[start:min(start+maxQueryIDs, len(playlistIDs))]
+
+		args := make([]any, 0, len(chunk))
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+
+		rows, err := s.db.Query(
+			`SELECT playlist_id, snapshot_id, track_ids, fetched_at FROM playlist_contents
+			WHERE playlist_id IN (?`+strings.Repeat(`, ?`, len(chunk)-1)+`)`,
+			args...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load playlist contents: %w", err)
+		}
+
+		for rows.Next() {
+			var (
+				entry     Entry
+				trackIDs  string
+				fetchedAt int64
+			)
+			if err := rows.Scan(&entry.PlaylistID, &entry.SnapshotID, &trackIDs, &fetchedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to load playlist contents: %w", err)
+			}
+			if err := json.Unmarshal([]byte(trackIDs), &entry.TrackIDs); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to decode playlist contents: %w", err)
+			}
+			entry.FetchedAt = time.Unix(0, fetchedAt)
+			result[entry.PlaylistID] = &entry
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Put stores an entry
+func (s *SQLiteStore) Put(entry *Entry) error {
+	trackIDs := entry.TrackIDs
+	if trackIDs == nil {
+		trackIDs = []string{}
+	}
+	trackIDsData, err := json.Marshal(trackIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode playlist contents: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO playlist_contents (playlist_id, snapshot_id, track_ids, fetched_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (playlist_id) DO UPDATE SET snapshot_id = excluded.snapshot_id, track_ids = excluded.track_ids, fetched_at = excluded.fetched_at`,
+		entry.PlaylistID, entry.SnapshotID, string(trackIDsData), entry.FetchedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store playlist contents: %w", err)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached playlists
+func (s *SQLiteStore) Len() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM playlist_contents`).Scan(&count)
+	return count, err
+}
+
+// cleanupOldEntries periodically removes entries fetched long ago
+func (s *SQLiteStore) cleanupOldEntries() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(`DELETE FROM playlist_contents WHERE fetched_at < ?`, time.Now().Add(-entryTTL).UnixNano()); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up cached playlist contents")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package playlistcache
+
+import "time"
+
+const (
+	// entryTTL is how long an entry is kept after it was fetched
+	entryTTL = 30 * 24 * time.Hour
+
+	// cleanupInterval is how often old entries are removed in the background
+	cleanupInterval = 1 * time.Hour
+)
+
+// Entry is the track list of a playlist at one snapshot.
+// Spotify gives a playlist a new snapshot ID whenever its tracks change.
+type Entry struct {
+	PlaylistID string
+	SnapshotID string
+	TrackIDs   []string
+	FetchedAt  time.Time
+}
+
+// Store caches playlist contents, so a playlist is only fetched again once its snapshot ID changed
+type Store interface {
+	// GetMany returns the cached entries among playlistIDs, by playlist ID
+	GetMany(playlistIDs []string) (map[string]*Entry, error)
+	// Put stores an entry, replacing the cached contents of the playlist
+	Put(entry *Entry) error
+	// Len returns the number of cached playlists
+	Len() (int, error)
+}
+
+// copyEntry copies an entry, so callers cannot change the cached track list
+func copyEntry(entry *Entry) *Entry {
+	copied := *entry
+	copied.TrackIDs = append([]string(nil), entry.TrackIDs...)
+	return &copied
+}
@@ -0,0 +1,83 @@
+package playlistcache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// stores runs a test against every Store implementation
+func stores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		test(t, NewSQLiteStore(db))
+	})
+}
+
+func TestStoreKeepsLatestSnapshot(t *testing.T) {
+	stores(t, func(t *testing.T, store Store) {
+		now := time.Now()
+		for _, entry := range []*Entry{
+			{PlaylistID: "p1", SnapshotID: "s1", TrackIDs: []string{"a", "b"}, FetchedAt: now},
+			{PlaylistID: "p2", SnapshotID: "s1", TrackIDs: []string{}, FetchedAt: now},
+			{PlaylistID: "p1", SnapshotID: "s2", TrackIDs: []string{"b", "c", "a"}, FetchedAt: now},
+		} {
+			if err := store.Put(entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entries, err := store.GetMany([]string{"p1", "p2", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("GetMany returned %d entries, want 2", len(entries))
+		}
+
+		// The newer snapshot replaced the older one, in playlist order
+		p1 := entries["p1"]
+		if p1 == nil || p1.SnapshotID != "s2" || len(p1.TrackIDs) != 3 || p1.TrackIDs[0] != "b" || p1.TrackIDs[2] != "a" {
+			t.Errorf("p1 = %+v, want snapshot s2 with [b c a]", p1)
+		}
+		if p2 := entries["p2"]; p2 == nil || len(p2.TrackIDs) != 0 {
+			t.Errorf("empty playlist = %+v", p2)
+		}
+		if n, err := store.Len(); err != nil || n != 2 {
+			t.Errorf("Len = %d, %v, want 2", n, err)
+		}
+	})
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	stores(t, func(t *testing.T, store Store) {
+		trackIDs := []string{"a", "b"}
+		if err := store.Put(&Entry{PlaylistID: "p1", SnapshotID: "s1", TrackIDs: trackIDs, FetchedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		trackIDs[0] = "changed"
+
+		entries, err := store.GetMany([]string{"p1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries["p1"].TrackIDs[1] = "changed"
+
+		entries, err = store.GetMany([]string{"p1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := entries["p1"].TrackIDs; got[0] != "a" || got[1] != "b" {
+			t.Errorf("cached tracks = %v, want [a b] unchanged by callers", got)
+		}
+	})
+}
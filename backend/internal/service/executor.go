@@ -2,35 +2,74 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/zmb3/spotify/v2"
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/execution"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/genre"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
 )
 
 // ExecutorService executes sort plans
 type ExecutorService struct {
-	spotifyClient  *spotifyClient.Client
+	spotifyClient  spotifyClient.API
 	libraryService *LibraryService
 	broadcaster    *sse.Broadcaster
+	executions     execution.Store
+	active         sync.Map // executionID -> struct{}, executions running in this process
 }
 
 // NewExecutorService creates a new executor service
-func NewExecutorService(client *spotifyClient.Client, libraryService *LibraryService, broadcaster *sse.Broadcaster) *ExecutorService {
+func NewExecutorService(client spotifyClient.API, libraryService *LibraryService, broadcaster *sse.Broadcaster, executions execution.Store) *ExecutorService {
 	return &ExecutorService{
 		spotifyClient:  client,
 		libraryService: libraryService,
 		broadcaster:    broadcaster,
+		executions:     executions,
 	}
 }
 
+// playlistBatchSize is the maximum number of tracks Spotify accepts per playlist mutation
+const playlistBatchSize = 100
+
+// uncategorizedGenreKey is the genre key of the playlist collecting songs without a genre
+const uncategorizedGenreKey = "uncategorized"
+
+// Execution steps, recorded in the checkpoint once they finished without errors
+const (
+	stepCreatePlaylists = "create_playlists"
+	stepAddTracks       = "add_tracks"
+	stepUncategorized   = "uncategorized"
+	stepRemoveTracks    = "remove_tracks"
+	stepMergeGroups     = "merge_groups"
+	stepMergePlaylists  = "merge_playlists"
+	stepRemoveEmpty     = "remove_empty_playlists"
+)
+
+// executionSteps lists the steps in the order they run
+var executionSteps = []string{stepCreatePlaylists, stepAddTracks, stepUncategorized, stepRemoveTracks, stepMergeGroups, stepMergePlaylists, stepRemoveEmpty}
+
+// executionRun is the state of one run of an execution
+type executionRun struct {
+	exec   *domain.Execution
+	client spotifyClient.Conn
+
+	snapshots map[string]string   // Playlist ID -> snapshot ID at the first lookup of playlist contents
+	contents  map[string][]string // Playlist ID -> track IDs in playlist order, kept up to date by the run
+}
+
 // ExecuteSortPlan executes a sort plan
-func (s *ExecutorService) ExecuteSortPlan(ctx context.Context, client *spotify.Client, plan *domain.SortPlan, userID string) (*domain.ExecutionResult, error) {
+func (s *ExecutorService) ExecuteSortPlan(ctx context.Context, client spotifyClient.Conn, plan *domain.SortPlan, userID string) (*domain.ExecutionResult, error) {
 	log.Info().Str("planID", plan.ID).Str("userID", userID).Msg("Executing sort plan")
 
 	result := &domain.ExecutionResult{
@@ -43,72 +82,277 @@ func (s *ExecutorService) ExecuteSortPlan(ctx context.Context, client *spotify.C
 	}
 
 	if plan.DryRun {
-		s.broadcaster.SendInfo(userID, "Dry run mode - no changes will be made")
+		s.broadcaster.SendInfo(ctx, userID, "Dry run mode - no changes will be made")
 		return result, nil
 	}
 
-	// Step 1: Create new playlists
-	if len(plan.PlaylistsToCreate) > 0 {
-		s.broadcaster.SendProgress(userID, sse.PhaseCreatingPlaylists, 0, len(plan.PlaylistsToCreate), "Creating new playlists...")
+	// Every mutation is journaled so the execution can be rolled back,
+	// and progress is checkpointed so it can be resumed
+	exec := &domain.Execution{
+		ID:        uuid.New().String(),
+		PlanID:    plan.ID,
+		UserID:    userID,
+		Status:    domain.ExecutionRunning,
+		StartedAt: time.Now(),
+		Checkpoint: domain.Checkpoint{
+			CreatedPlaylists: make(map[string]string),
+			RenamedPlaylists: make(map[string]string),
+			CompletedBatches: make(map[string]bool),
+		},
+		Plan: plan,
+	}
+	if err := s.executions.Create(exec); err != nil {
+		return nil, fmt.Errorf("failed to record execution: %w", err)
+	}
+	result.ExecutionID = exec.ID
+
+	s.active.Store(exec.ID, struct{}{})
+	defer s.active.Delete(exec.ID)
 
-		createdPlaylists, err := s.createPlaylists(ctx, client, plan.PlaylistsToCreate, userID)
-		if err != nil {
-			result.Success = false
-			result.Errors = append(result.Errors, domain.ExecutionError{
-				Operation: "create_playlists",
-				Error:     err.Error(),
-			})
-			return result, err
+	// Only approved entries are executed
+	result.Skipped = plan.SkippedCount()
+	if result.Skipped > 0 {
+		s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Skipping %d rejected changes", result.Skipped))
+	}
+
+	return s.run(ctx, &executionRun{exec: exec, client: client}, result)
+}
+
+// ResumeExecution continues an interrupted, cancelled or failed execution from its last checkpoint.
+// Batches that completed in an earlier run are skipped, and tracks already in a playlist are not added again.
+func (s *ExecutorService) ResumeExecution(ctx context.Context, client spotifyClient.Conn, executionID, userID string) (*domain.ExecutionResult, error) {
+	exec, err := s.executions.Get(executionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, running := s.active.LoadOrStore(executionID, struct{}{}); running || exec.Status == domain.ExecutionRunning {
+		if !running {
+			s.active.Delete(executionID)
 		}
+		return nil, ErrExecutionInProgress
+	}
+	defer s.active.Delete(executionID)
+
+	if !exec.Resumable() || exec.Plan == nil {
+		return nil, ErrNotResumable
+	}
+
+	log.Info().Str("executionID", executionID).Str("userID", userID).Strs("completedSteps", exec.Checkpoint.CompletedSteps).Msg("Resuming execution")
 
-		result.PlaylistsCreated = len(createdPlaylists)
+	if exec.Checkpoint.CreatedPlaylists == nil {
+		exec.Checkpoint.CreatedPlaylists = make(map[string]string)
+	}
+	if exec.Checkpoint.RenamedPlaylists == nil {
+		exec.Checkpoint.RenamedPlaylists = make(map[string]string)
+	}
+	if exec.Checkpoint.CompletedBatches == nil {
+		exec.Checkpoint.CompletedBatches = make(map[string]bool)
+	}
+
+	// Counts carry over from earlier runs, errors are retried
+	result := &domain.ExecutionResult{
+		ExecutionID: executionID,
+		Success:     true,
+		Skipped:     exec.Plan.SkippedCount(),
+		Errors:      []domain.ExecutionError{},
+	}
+	if exec.Result != nil {
+		result.PlaylistsCreated = exec.Result.PlaylistsCreated
+		result.PlaylistsDeleted = exec.Result.PlaylistsDeleted
+		result.PlaylistsMerged = exec.Result.PlaylistsMerged
+		result.GroupsMerged = exec.Result.GroupsMerged
+		result.TracksAdded = exec.Result.TracksAdded
+		result.TracksRemoved = exec.Result.TracksRemoved
+	}
 
-		// Update plan with created playlist IDs
-		s.updatePlanWithCreatedPlaylists(plan, createdPlaylists)
+	if err := s.executions.Update(executionID, func(e *domain.Execution) {
+		e.Status = domain.ExecutionRunning
+		e.FinishedAt = nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update execution: %w", err)
 	}
+	exec.Status = domain.ExecutionRunning
+
+	s.broadcaster.SendInfo(ctx, userID, "Resuming sort from last checkpoint...")
+
+	return s.run(ctx, &executionRun{exec: exec, client: client}, result)
+}
+
+// run executes the steps of an execution that have not completed yet
+func (s *ExecutorService) run(ctx context.Context, run *executionRun, result *domain.ExecutionResult) (*domain.ExecutionResult, error) {
+	plan := run.exec.Plan
+	checkpoint := &run.exec.Checkpoint
+	userID := run.exec.UserID
+
+	// Step 1: Rename child playlists to their group, then create new playlists
+	if !checkpoint.StepCompleted(stepCreatePlaylists) {
+		s.renameGroupPlaylists(ctx, run, plan.ApprovedGroupMerges())
+		if ctx.Err() != nil {
+			return s.interrupt(ctx, run, result), nil
+		}
+
+		playlistsToCreate := plan.ApprovedPlaylistsToCreate()
+		if len(playlistsToCreate) > 0 {
+			s.broadcaster.SendProgress(ctx, userID, sse.PhaseCreatingPlaylists, 0, len(playlistsToCreate), "Creating new playlists...")
+
+			created, err := s.createPlaylists(ctx, run, playlistsToCreate)
+			result.PlaylistsCreated += created
+			if err != nil {
+				if ctx.Err() != nil {
+					return s.interrupt(ctx, run, result), nil
+				}
+				result.Success = false
+				result.Errors = append(result.Errors, domain.ExecutionError{
+					Operation: "create_playlists",
+					Error:     err.Error(),
+				})
+				s.finishExecution(run, domain.ExecutionFailed, result)
+				return result, err
+			}
+		}
+
+		s.completeStep(run, stepCreatePlaylists)
+	}
+
+	// Update plan with created and renamed playlist IDs, including those from earlier runs
+	s.updatePlanWithCreatedPlaylists(plan, checkpoint.RenamedPlaylists)
+	s.updatePlanWithCreatedPlaylists(plan, checkpoint.CreatedPlaylists)
 
 	// Step 2: Add tracks to playlists
-	if len(plan.TracksToAdd) > 0 {
-		s.broadcaster.SendProgress(userID, sse.PhaseAddingTracks, 0, len(plan.TracksToAdd), "Adding tracks to playlists...")
+	if !checkpoint.StepCompleted(stepAddTracks) {
+		tracksToAdd := plan.ApprovedTracksToAdd()
+		if len(tracksToAdd) > 0 {
+			s.broadcaster.SendProgress(ctx, userID, sse.PhaseAddingTracks, 0, len(tracksToAdd), "Adding tracks to playlists...")
 
-		added, errors := s.addTracksToPlaylists(ctx, client, plan.TracksToAdd, userID)
-		result.TracksAdded = added
-		result.Errors = append(result.Errors, errors...)
+			added, errors := s.addTracksToPlaylists(ctx, run, tracksToAdd)
+			result.TracksAdded += added
+			result.Errors = append(result.Errors, errors...)
+			if ctx.Err() != nil {
+				return s.interrupt(ctx, run, result), nil
+			}
+			if len(errors) == 0 {
+				s.completeStep(run, stepAddTracks)
+			}
+		} else {
+			s.completeStep(run, stepAddTracks)
+		}
 	}
 
 	// Step 3: Handle uncategorized tracks
-	if len(plan.UncategorizedTracks) > 0 {
-		s.broadcaster.SendInfo(userID, fmt.Sprintf("Processing %d uncategorized tracks...", len(plan.UncategorizedTracks)))
+	if !checkpoint.StepCompleted(stepUncategorized) {
+		uncategorizedTracks := plan.ApprovedUncategorizedTracks()
+		if len(uncategorizedTracks) > 0 {
+			s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Processing %d uncategorized tracks...", len(uncategorizedTracks)))
 
-		added, errors := s.handleUncategorizedTracks(ctx, client, plan.UncategorizedTracks, userID)
-		result.TracksAdded += added
-		result.Errors = append(result.Errors, errors...)
+			added, errors := s.handleUncategorizedTracks(ctx, run, uncategorizedTracks)
+			result.TracksAdded += added
+			result.Errors = append(result.Errors, errors...)
+			if ctx.Err() != nil {
+				return s.interrupt(ctx, run, result), nil
+			}
+			if len(errors) == 0 {
+				s.completeStep(run, stepUncategorized)
+			}
+		} else {
+			s.completeStep(run, stepUncategorized)
+		}
 	}
 
 	// Step 4: Remove tracks from wrong playlists
-	if len(plan.TracksToRemove) > 0 {
-		s.broadcaster.SendProgress(userID, sse.PhaseRemovingTracks, 0, len(plan.TracksToRemove), "Removing tracks from incorrect playlists...")
+	if !checkpoint.StepCompleted(stepRemoveTracks) {
+		tracksToRemove := plan.ApprovedTracksToRemove()
+		if len(tracksToRemove) > 0 {
+			s.broadcaster.SendProgress(ctx, userID, sse.PhaseRemovingTracks, 0, len(tracksToRemove), "Removing tracks from incorrect playlists...")
 
-		removed, errors := s.removeTracksFromPlaylists(ctx, client, plan.TracksToRemove, userID)
-		result.TracksRemoved = removed
-		result.Errors = append(result.Errors, errors...)
+			removed, errors := s.removeTracksFromPlaylists(ctx, run, tracksToRemove)
+			result.TracksRemoved += removed
+			result.Errors = append(result.Errors, errors...)
+			if ctx.Err() != nil {
+				return s.interrupt(ctx, run, result), nil
+			}
+			if len(errors) == 0 {
+				s.completeStep(run, stepRemoveTracks)
+			}
+		} else {
+			s.completeStep(run, stepRemoveTracks)
+		}
+	}
+
+	// Step 5: Consolidate child genre playlists into their enabled groups
+	if !checkpoint.StepCompleted(stepMergeGroups) {
+		merges := plan.ApprovedGroupMerges()
+		if len(merges) > 0 {
+			s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Merging playlists of %d genre groups...", len(merges)))
+
+			added, merged, groups, errors := s.mergeGroups(ctx, run, merges)
+			result.TracksAdded += added
+			result.PlaylistsMerged += merged
+			result.GroupsMerged += groups
+			result.Errors = append(result.Errors, errors...)
+			if ctx.Err() != nil {
+				return s.interrupt(ctx, run, result), nil
+			}
+			if len(errors) == 0 {
+				s.completeStep(run, stepMergeGroups)
+			}
+		} else {
+			s.completeStep(run, stepMergeGroups)
+		}
 	}
 
-	// Step 5: Remove empty playlists
-	s.broadcaster.SendInfo(userID, "Checking for empty playlists...")
-	deleted, errors := s.removeEmptyPlaylists(ctx, client, userID)
-	result.PlaylistsDeleted = deleted
-	result.Errors = append(result.Errors, errors...)
+	// Step 6: Merge duplicate playlists of a genre
+	if !checkpoint.StepCompleted(stepMergePlaylists) {
+		merges := plan.ApprovedPlaylistMerges()
+		if len(merges) > 0 {
+			s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Merging duplicate playlists of %d genres...", len(merges)))
 
+			added, merged, errors := s.mergePlaylists(ctx, run, merges)
+			result.TracksAdded += added
+			result.PlaylistsMerged += merged
+			result.Errors = append(result.Errors, errors...)
+			if ctx.Err() != nil {
+				return s.interrupt(ctx, run, result), nil
+			}
+			if len(errors) == 0 {
+				s.completeStep(run, stepMergePlaylists)
+			}
+		} else {
+			s.completeStep(run, stepMergePlaylists)
+		}
+	}
+
+	// Step 7: Remove empty playlists
+	if !checkpoint.StepCompleted(stepRemoveEmpty) {
+		s.broadcaster.SendInfo(ctx, userID, "Checking for empty playlists...")
+		deleted, errors := s.removeEmptyPlaylists(ctx, run)
+		result.PlaylistsDeleted += deleted
+		result.Errors = append(result.Errors, errors...)
+		if ctx.Err() != nil {
+			return s.interrupt(ctx, run, result), nil
+		}
+		if len(errors) == 0 {
+			s.completeStep(run, stepRemoveEmpty)
+		}
+	}
+
+	// Failed executions keep their checkpoint and can be resumed to retry what failed
+	status := domain.ExecutionCompleted
 	if len(result.Errors) > 0 {
 		result.Success = false
+		status = domain.ExecutionFailed
 	}
 
-	s.broadcaster.SendComplete(userID, fmt.Sprintf("Sort complete! Created %d playlists, deleted %d empty playlists, added %d tracks, removed %d tracks",
-		result.PlaylistsCreated, result.PlaylistsDeleted, result.TracksAdded, result.TracksRemoved))
+	s.finishExecution(run, status, result)
+
+	s.broadcaster.SendComplete(ctx, userID, fmt.Sprintf("Sort complete! Created %d playlists, consolidated %d genre groups, merged %d playlists, deleted %d empty playlists, added %d tracks, removed %d tracks",
+		result.PlaylistsCreated, result.GroupsMerged, result.PlaylistsMerged, result.PlaylistsDeleted, result.TracksAdded, result.TracksRemoved))
 
 	log.Info().
+		Str("executionID", run.exec.ID).
 		Int("playlistsCreated", result.PlaylistsCreated).
+		Int("playlistsMerged", result.PlaylistsMerged).
+		Int("groupsMerged", result.GroupsMerged).
 		Int("playlistsDeleted", result.PlaylistsDeleted).
 		Int("tracksAdded", result.TracksAdded).
 		Int("tracksRemoved", result.TracksRemoved).
@@ -118,26 +362,99 @@ func (s *ExecutorService) ExecuteSortPlan(ctx context.Context, client *spotify.C
 	return result, nil
 }
 
-// createPlaylists creates new playlists for genres
-func (s *ExecutorService) createPlaylists(ctx context.Context, client *spotify.Client, genres []string, userID string) (map[string]string, error) {
-	createdPlaylists := make(map[string]string) // genre -> playlistID
+// interrupt stops an execution whose context ended, leaving it resumable from its checkpoint.
+// Executions the user cancelled end up cancelled, all others interrupted.
+func (s *ExecutorService) interrupt(ctx context.Context, run *executionRun, result *domain.ExecutionResult) *domain.ExecutionResult {
+	result.Success = false
+
+	if errors.Is(context.Cause(ctx), job.ErrCancelled) {
+		result.Cancelled = true
+		s.finishExecution(run, domain.ExecutionCancelled, result)
+
+		s.broadcaster.SendInfo(ctx, run.exec.UserID, fmt.Sprintf("Sort cancelled after %d of %d steps. Created %d playlists, added %d tracks, removed %d tracks",
+			len(result.CompletedSteps), len(executionSteps), result.PlaylistsCreated, result.TracksAdded, result.TracksRemoved))
+
+		log.Info().Str("executionID", run.exec.ID).Strs("completedSteps", result.CompletedSteps).Msg("Sort plan execution cancelled")
+		return result
+	}
+
+	s.finishExecution(run, domain.ExecutionInterrupted, result)
+
+	s.broadcaster.SendError(ctx, run.exec.UserID, "Sort was interrupted and can be resumed")
+
+	log.Warn().Str("executionID", run.exec.ID).Strs("completedSteps", result.CompletedSteps).Msg("Sort plan execution interrupted")
+
+	return result
+}
+
+// createPlaylists creates new playlists for genres and returns how many were created.
+// Genres that already got a playlist in an earlier run are skipped.
+func (s *ExecutorService) createPlaylists(ctx context.Context, run *executionRun, genres []string) (int, error) {
+	userID := run.exec.UserID
+	created := 0
 
 	for i, genreName := range genres {
-		s.broadcaster.SendProgress(userID, sse.PhaseCreatingPlaylists, i+1, len(genres),
+		if _, done := run.exec.Checkpoint.CreatedPlaylists[genreName]; done {
+			continue
+		}
+		if _, renamed := run.exec.Checkpoint.RenamedPlaylists[genreName]; renamed {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return created, err
+		}
+
+		s.broadcaster.SendProgress(ctx, userID, sse.PhaseCreatingPlaylists, i+1, len(genres),
 			fmt.Sprintf("Creating playlist for %s...", genreName))
 
 		description := fmt.Sprintf("Automatically organized %s tracks", genreName)
-		playlist, err := s.spotifyClient.CreatePlaylist(ctx, client, userID, genreName, description, false)
+		genreKey := genre.NormalizeGenre(genreName)
+		playlist, err := s.spotifyClient.CreatePlaylist(ctx, run.client, userID, genreName, genreKey, description, false)
 		if err != nil {
 			log.Error().Err(err).Str("genre", genreName).Msg("Failed to create playlist")
-			return createdPlaylists, fmt.Errorf("failed to create playlist for %s: %w", genreName, err)
+			return created, fmt.Errorf("failed to create playlist for %s: %w", genreName, err)
 		}
+		s.libraryService.registerPlaylist(userID, genreKey, playlist.ID.String())
+		run.setContents(playlist.ID.String(), nil)
 
-		createdPlaylists[genreName] = playlist.ID.String()
+		created++
+		s.recordJournal(run.exec.ID, domain.JournalEntry{
+			Operation:    domain.JournalPlaylistCreated,
+			PlaylistID:   playlist.ID.String(),
+			PlaylistName: genreName,
+		})
+		run.exec.Checkpoint.CreatedPlaylists[genreName] = playlist.ID.String()
+		s.saveCheckpoint(run)
 		log.Info().Str("genre", genreName).Str("playlistID", playlist.ID.String()).Msg("Created playlist")
 	}
 
-	return createdPlaylists, nil
+	return created, nil
+}
+
+// renameGroupPlaylists renames the child playlists that become the playlist of their group, before new
+// playlists are created. Songs of the group are planned for a new playlist of the group; once the rename
+// succeeded they go to the renamed playlist instead. When it fails the group's playlist is created as planned,
+// and the merge moves the child into it.
+func (s *ExecutorService) renameGroupPlaylists(ctx context.Context, run *executionRun, merges []domain.GroupMerge) {
+	for _, merge := range merges {
+		if !merge.Rename {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := s.renameToGroup(ctx, run, merge); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn().Err(err).Str("playlistID", merge.TargetID).Str("group", merge.ParentGenre).Msg("Failed to rename playlist to its group, creating the group's playlist instead")
+			continue
+		}
+
+		run.exec.Checkpoint.RenamedPlaylists[merge.ParentGenre] = merge.TargetID
+		s.saveCheckpoint(run)
+	}
 }
 
 // updatePlanWithCreatedPlaylists updates the plan with newly created playlist IDs
@@ -168,12 +485,14 @@ func (s *ExecutorService) updatePlanWithCreatedPlaylists(plan *domain.SortPlan,
 }
 
 // addTracksToPlaylists adds tracks to their target playlists
-func (s *ExecutorService) addTracksToPlaylists(ctx context.Context, client *spotify.Client, moves []domain.TrackMove, userID string) (int, []domain.ExecutionError) {
+func (s *ExecutorService) addTracksToPlaylists(ctx context.Context, run *executionRun, moves []domain.TrackMove) (int, []domain.ExecutionError) {
 	// Group tracks by target playlist
 	playlistTracks := make(map[string][]spotify.ID)
+	playlistNames := make(map[string]string)
 	for _, move := range moves {
 		if move.ToPlaylist != "" {
 			playlistTracks[move.ToPlaylist] = append(playlistTracks[move.ToPlaylist], spotify.ID(move.TrackID))
+			playlistNames[move.ToPlaylist] = move.ToPlaylistName
 		}
 	}
 
@@ -182,20 +501,25 @@ func (s *ExecutorService) addTracksToPlaylists(ctx context.Context, client *spot
 	current := 0
 	total := len(moves)
 
-	for playlistID, trackIDs := range playlistTracks {
-		s.broadcaster.SendProgress(userID, sse.PhaseAddingTracks, current, total,
+	// Playlists are processed in a fixed order so batch keys match between runs
+	for _, playlistID := range sortedKeys(playlistTracks) {
+		trackIDs := playlistTracks[playlistID]
+
+		s.broadcaster.SendProgress(ctx, run.exec.UserID, sse.PhaseAddingTracks, current, total,
 			fmt.Sprintf("Adding %d tracks to playlist...", len(trackIDs)))
 
-		err := s.spotifyClient.AddTracksToPlaylist(ctx, client, playlistID, trackIDs)
+		added, err := s.addTracksInBatches(ctx, run, "add", playlistID, playlistNames[playlistID], trackIDs)
+		totalAdded += added
 		if err != nil {
+			if ctx.Err() != nil {
+				return totalAdded, errors
+			}
 			log.Error().Err(err).Str("playlistID", playlistID).Msg("Failed to add tracks to playlist")
 			errors = append(errors, domain.ExecutionError{
 				Operation: "add_tracks",
 				Playlist:  playlistID,
 				Error:     err.Error(),
 			})
-		} else {
-			totalAdded += len(trackIDs)
 		}
 
 		current += len(trackIDs)
@@ -204,13 +528,165 @@ func (s *ExecutorService) addTracksToPlaylists(ctx context.Context, client *spot
 	return totalAdded, errors
 }
 
+// addTracksInBatches adds tracks to a playlist in batches, checkpointing every batch.
+// Tracks already in the playlist are left out, so additions are never duplicated, also when a batch was
+// applied right before a previous run stopped. Batches are cut from the full list so their checkpoint keys
+// stay the same between runs.
+// A failed batch is skipped and the remaining batches are still attempted; the first error is returned.
+func (s *ExecutorService) addTracksInBatches(ctx context.Context, run *executionRun, keyPrefix, playlistID, playlistName string, trackIDs []spotify.ID) (int, error) {
+	batches := batchTrackIDs(trackIDs)
+
+	pending := false
+	for i := range batches {
+		if !run.exec.Checkpoint.CompletedBatches[batchKey(keyPrefix, playlistID, i)] {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return 0, nil
+	}
+
+	current, err := s.playlistContents(ctx, run, playlistID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch playlist tracks: %w", err)
+	}
+	existing := make(map[spotify.ID]bool, len(current))
+	for _, id := range current {
+		existing[spotify.ID(id)] = true
+	}
+
+	added := 0
+	var firstErr error
+	for i, batch := range batches {
+		key := batchKey(keyPrefix, playlistID, i)
+		if run.exec.Checkpoint.CompletedBatches[key] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return added, err
+		}
+
+		toAdd := make([]spotify.ID, 0, len(batch))
+		for _, id := range batch {
+			if !existing[id] {
+				toAdd = append(toAdd, id)
+				existing[id] = true
+			}
+		}
+
+		if len(toAdd) > 0 {
+			if err := s.spotifyClient.AddTracksToPlaylist(ctx, run.client, playlistID, toAdd); err != nil {
+				for _, id := range toAdd {
+					delete(existing, id)
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			added += len(toAdd)
+			run.contents[playlistID] = append(run.contents[playlistID], idStrings(toAdd)...)
+			s.recordJournal(run.exec.ID, domain.JournalEntry{
+				Operation:    domain.JournalTracksAdded,
+				PlaylistID:   playlistID,
+				PlaylistName: playlistName,
+				TrackIDs:     idStrings(toAdd),
+			})
+		}
+
+		s.completeBatch(run, key)
+	}
+
+	return added, firstErr
+}
+
+// playlistContents returns the tracks of a playlist in playlist order. They are loaded once per run,
+// from the playlist cache when the snapshot ID still matches, and updated as the run changes the playlist.
+func (s *ExecutorService) playlistContents(ctx context.Context, run *executionRun, playlistID string) ([]string, error) {
+	if trackIDs, ok := run.contents[playlistID]; ok {
+		return trackIDs, nil
+	}
+
+	if run.snapshots == nil {
+		playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, run.client, run.exec.UserID)
+		if err != nil {
+			return nil, err
+		}
+		run.snapshots = make(map[string]string, len(playlists))
+		for _, p := range playlists {
+			run.snapshots[p.ID] = p.SnapshotID
+		}
+	}
+
+	trackIDs, err := s.libraryService.playlistTracks(ctx, run.client, playlistID, run.snapshots[playlistID])
+	if err != nil {
+		return nil, err
+	}
+	run.setContents(playlistID, trackIDs)
+
+	return trackIDs, nil
+}
+
+// positions returns where the tracks are in the known contents of a playlist, -1 for tracks that are not,
+// or nil when the contents are unknown
+func (run *executionRun) positions(playlistID string, trackIDs []spotify.ID) []int {
+	current, ok := run.contents[playlistID]
+	if !ok {
+		return nil
+	}
+	index := make(map[string]int, len(current))
+	for i := len(current) - 1; i >= 0; i-- {
+		index[current[i]] = i
+	}
+	positions := make([]int, len(trackIDs))
+	for i, id := range trackIDs {
+		position, found := index[id.String()]
+		if !found {
+			position = -1
+		}
+		positions[i] = position
+	}
+	return positions
+}
+
+// removeContents drops removed tracks from the known contents of a playlist
+func (run *executionRun) removeContents(playlistID string, trackIDs []spotify.ID) {
+	current, ok := run.contents[playlistID]
+	if !ok {
+		return
+	}
+	removed := make(map[string]bool, len(trackIDs))
+	for _, id := range trackIDs {
+		removed[id.String()] = true
+	}
+	kept := current[:0]
+	for _, id := range current {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	run.contents[playlistID] = kept
+}
+
+// setContents records the tracks of a playlist, e.g. an empty list for a playlist the run created
+func (run *executionRun) setContents(playlistID string, trackIDs []string) {
+	if run.contents == nil {
+		run.contents = make(map[string][]string)
+	}
+	run.contents[playlistID] = append([]string{}, trackIDs...)
+}
+
 // removeTracksFromPlaylists removes tracks from playlists
-func (s *ExecutorService) removeTracksFromPlaylists(ctx context.Context, client *spotify.Client, moves []domain.TrackMove, userID string) (int, []domain.ExecutionError) {
+func (s *ExecutorService) removeTracksFromPlaylists(ctx context.Context, run *executionRun, moves []domain.TrackMove) (int, []domain.ExecutionError) {
 	// Group tracks by source playlist
 	playlistTracks := make(map[string][]spotify.ID)
+	playlistNames := make(map[string]string)
 	for _, move := range moves {
 		if move.FromPlaylist != "" {
 			playlistTracks[move.FromPlaylist] = append(playlistTracks[move.FromPlaylist], spotify.ID(move.TrackID))
+			playlistNames[move.FromPlaylist] = move.FromPlaylistName
 		}
 	}
 
@@ -219,30 +695,53 @@ func (s *ExecutorService) removeTracksFromPlaylists(ctx context.Context, client
 	current := 0
 	total := len(moves)
 
-	for playlistID, trackIDs := range playlistTracks {
-		s.broadcaster.SendProgress(userID, sse.PhaseRemovingTracks, current, total,
+	// Playlists are processed in a fixed order so batch keys match between runs
+	for _, playlistID := range sortedKeys(playlistTracks) {
+		trackIDs := playlistTracks[playlistID]
+
+		s.broadcaster.SendProgress(ctx, run.exec.UserID, sse.PhaseRemovingTracks, current, total,
 			fmt.Sprintf("Removing %d tracks from playlist...", len(trackIDs)))
 
+		// Positions are journaled so a rollback can put the tracks back where they were
+		if _, err := s.playlistContents(ctx, run, playlistID); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Str("playlistID", playlistID).Msg("Failed to fetch playlist tracks, a rollback appends removed tracks")
+		}
+
 		// Remove in batches to avoid API limits
-		batchSize := 100
-		for i := 0; i < len(trackIDs); i += batchSize {
-			end := i + batchSize
-			if end > len(trackIDs) {
-				end = len(trackIDs)
+		for i, batch := range batchTrackIDs(trackIDs) {
+			key := batchKey("remove", playlistID, i)
+			if run.exec.Checkpoint.CompletedBatches[key] {
+				continue
+			}
+			if ctx.Err() != nil {
+				return totalRemoved, errors
 			}
-			batch := trackIDs[i:end]
 
-			err := s.spotifyClient.RemoveTracksFromPlaylist(ctx, client, playlistID, batch)
+			err := s.spotifyClient.RemoveTracksFromPlaylist(ctx, run.client, playlistID, batch)
 			if err != nil {
+				if ctx.Err() != nil {
+					return totalRemoved, errors
+				}
 				log.Error().Err(err).Str("playlistID", playlistID).Msg("Failed to remove tracks from playlist")
 				errors = append(errors, domain.ExecutionError{
 					Operation: "remove_tracks",
 					Playlist:  playlistID,
 					Error:     err.Error(),
 				})
-			} else {
-				totalRemoved += len(batch)
+				continue
 			}
+
+			totalRemoved += len(batch)
+			positions := run.positions(playlistID, batch)
+			run.removeContents(playlistID, batch)
+			s.recordJournal(run.exec.ID, domain.JournalEntry{
+				Operation:    domain.JournalTracksRemoved,
+				PlaylistID:   playlistID,
+				PlaylistName: playlistNames[playlistID],
+				TrackIDs:     idStrings(batch),
+				Positions:    positions,
+			})
+			s.completeBatch(run, key)
 		}
 
 		current += len(trackIDs)
@@ -251,50 +750,293 @@ func (s *ExecutorService) removeTracksFromPlaylists(ctx context.Context, client
 	return totalRemoved, errors
 }
 
-// handleUncategorizedTracks creates/updates an "Uncategorized" playlist
-func (s *ExecutorService) handleUncategorizedTracks(ctx context.Context, client *spotify.Client, tracks []domain.Track, userID string) (int, []domain.ExecutionError) {
-	if len(tracks) == 0 {
-		return 0, nil
+// mergePlaylists adds the tracks of duplicate playlists to their survivor and unfollows the duplicates.
+// Tracks earlier steps add to the survivor are left out.
+func (s *ExecutorService) mergePlaylists(ctx context.Context, run *executionRun, merges []domain.PlaylistMerge) (int, int, []domain.ExecutionError) {
+	addedByPlan := plannedAdditions(run.exec.Plan)
+	uncategorized := make(map[string]bool)
+	for _, track := range run.exec.Plan.ApprovedUncategorizedTracks() {
+		uncategorized[track.ID] = true
+	}
+
+	totalAdded := 0
+	merged := 0
+	var errors []domain.ExecutionError
+
+	for _, merge := range merges {
+		if ctx.Err() != nil {
+			return totalAdded, merged, errors
+		}
+
+		var trackIDs []spotify.ID
+		for _, id := range merge.TrackIDs {
+			if addedByPlan[merge.SurvivorID][id] || (merge.Genre == uncategorizedGenreKey && uncategorized[id]) {
+				continue
+			}
+			trackIDs = append(trackIDs, spotify.ID(id))
+		}
+
+		s.broadcaster.SendInfo(ctx, run.exec.UserID, fmt.Sprintf("Merging %d duplicate playlists into %s...", len(merge.Duplicates), merge.SurvivorName))
+
+		added, unfollowed, errs := s.mergeInto(ctx, run, "merge", merge.SurvivorID, merge.SurvivorName, trackIDs, merge.Duplicates)
+		totalAdded += added
+		merged += unfollowed
+		errors = append(errors, errs...)
+	}
+
+	return totalAdded, merged, errors
+}
+
+// mergeGroups consolidates the playlists of child genres into the playlist of their parent group.
+// A child playlist that becomes the group's playlist was renamed before any track was added; when that
+// failed and the group's playlist was created instead, the child is merged into it like the others.
+// The tracks of the children are added to the group's playlist, then the children are unfollowed. Returns the tracks added, the children
+// unfollowed and the groups that were merged completely.
+func (s *ExecutorService) mergeGroups(ctx context.Context, run *executionRun, merges []domain.GroupMerge) (int, int, int, []domain.ExecutionError) {
+	userID := run.exec.UserID
+	addedByPlan := plannedAdditions(run.exec.Plan)
+
+	totalAdded := 0
+	merged := 0
+	groups := 0
+	var errors []domain.ExecutionError
+
+	for _, merge := range merges {
+		if ctx.Err() != nil {
+			return totalAdded, merged, groups, errors
+		}
+
+		targetID, targetName := merge.TargetID, merge.TargetName
+		children := merge.Children
+		mergedTrackIDs := merge.TrackIDs
+		if merge.Rename {
+			targetName = merge.ParentGenre
+
+			if createdID, created := run.exec.Checkpoint.CreatedPlaylists[merge.ParentGenre]; created {
+				// The rename failed and the group's playlist was created instead,
+				// the child that was to be renamed is merged into it like the others
+				contents, err := s.playlistContents(ctx, run, merge.TargetID)
+				if err != nil {
+					if ctx.Err() != nil {
+						return totalAdded, merged, groups, errors
+					}
+					log.Error().Err(err).Str("playlistID", merge.TargetID).Msg("Failed to fetch playlist tracks")
+					errors = append(errors, domain.ExecutionError{
+						Operation: "merge_tracks",
+						Playlist:  merge.TargetID,
+						Error:     err.Error(),
+					})
+					continue
+				}
+
+				targetID = createdID
+				children = append([]domain.MergedPlaylist{{
+					ID:         merge.TargetID,
+					Name:       merge.TargetName,
+					TrackCount: len(contents),
+				}}, merge.Children...)
+				mergedTrackIDs = append(append([]string{}, contents...), merge.TrackIDs...)
+			} else if err := s.renameToGroup(ctx, run, merge); err != nil {
+				if ctx.Err() != nil {
+					return totalAdded, merged, groups, errors
+				}
+				log.Error().Err(err).Str("playlistID", merge.TargetID).Str("group", merge.ParentGenre).Msg("Failed to rename playlist to its group")
+				errors = append(errors, domain.ExecutionError{
+					Operation: "rename_group_playlist",
+					Playlist:  merge.TargetID,
+					Error:     err.Error(),
+				})
+				continue
+			}
+		}
+
+		var trackIDs []spotify.ID
+		for _, id := range mergedTrackIDs {
+			if !addedByPlan[targetID][id] {
+				trackIDs = append(trackIDs, spotify.ID(id))
+			}
+		}
+
+		s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Merging %d playlists into %s...", len(children), targetName))
+
+		added, unfollowed, errs := s.mergeInto(ctx, run, "group", targetID, targetName, trackIDs, children)
+		totalAdded += added
+		merged += unfollowed
+		errors = append(errors, errs...)
+		if len(errs) == 0 && ctx.Err() == nil {
+			groups++
+			log.Info().Str("group", merge.ParentGenre).Str("playlistID", targetID).Int("children", len(children)).Msg("Merged group playlists")
+		}
+	}
+
+	return totalAdded, merged, groups, errors
+}
+
+// renameToGroup renames a child playlist to its parent group and registers it under the group's genre.
+// The rename is journaled with the previous name and description, so a rollback restores them.
+func (s *ExecutorService) renameToGroup(ctx context.Context, run *executionRun, merge domain.GroupMerge) error {
+	key := batchKey("rename", merge.TargetID, 0)
+	if run.exec.Checkpoint.CompletedBatches[key] {
+		return nil
+	}
+
+	userID := run.exec.UserID
+	genreKey := genre.NormalizeGenre(merge.ParentGenre)
+	description := domain.ManagedDescription(fmt.Sprintf("Automatically organized %s tracks", merge.ParentGenre), genreKey)
+	if err := s.spotifyClient.ChangePlaylistDetails(ctx, run.client, merge.TargetID, merge.ParentGenre, description); err != nil {
+		return err
 	}
 
+	// The playlist leaves the child genre it was registered for
+	s.libraryService.unregisterPlaylist(userID, merge.TargetID)
+	s.libraryService.registerPlaylist(userID, genreKey, merge.TargetID)
+
+	s.recordJournal(run.exec.ID, domain.JournalEntry{
+		Operation:           domain.JournalPlaylistRenamed,
+		PlaylistID:          merge.TargetID,
+		PlaylistName:        merge.ParentGenre,
+		PreviousName:        merge.TargetName,
+		PreviousDescription: merge.TargetDescription,
+	})
+	s.completeBatch(run, key)
+	log.Info().Str("playlistID", merge.TargetID).Str("from", merge.TargetName).Str("to", merge.ParentGenre).Msg("Renamed playlist to its group")
+
+	return nil
+}
+
+// mergeInto adds tracks to a playlist and then unfollows the playlists merged into it.
+// They are only unfollowed once all of the tracks were added, so a failed merge never loses tracks.
+func (s *ExecutorService) mergeInto(ctx context.Context, run *executionRun, keyPrefix, playlistID, playlistName string, trackIDs []spotify.ID, merged []domain.MergedPlaylist) (int, int, []domain.ExecutionError) {
 	var errors []domain.ExecutionError
 
-	// Check if "Uncategorized" playlist already exists
-	playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, client, userID)
+	added, err := s.addTracksInBatches(ctx, run, keyPrefix, playlistID, playlistName, trackIDs)
 	if err != nil {
-		errors = append(errors, domain.ExecutionError{
-			Operation: "fetch_playlists",
-			Error:     err.Error(),
-		})
-		return 0, errors
+		if ctx.Err() == nil {
+			log.Error().Err(err).Str("playlistID", playlistID).Msg("Failed to merge tracks into playlist")
+			errors = append(errors, domain.ExecutionError{
+				Operation: "merge_tracks",
+				Playlist:  playlistID,
+				Error:     err.Error(),
+			})
+		}
+		return added, 0, errors
 	}
 
-	var uncategorizedPlaylist *domain.Playlist
-	for i := range playlists {
-		if playlists[i].ManagedByApp && playlists[i].OwnerID == userID {
-			normalized := genre.NormalizeGenre(playlists[i].AssignedGenre)
-			if normalized == "uncategorized" || playlists[i].Name == "Uncategorized" {
-				uncategorizedPlaylist = &playlists[i]
-				break
+	unfollowed := 0
+	for _, playlist := range merged {
+		key := batchKey("unfollow", playlist.ID, 0)
+		if run.exec.Checkpoint.CompletedBatches[key] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return added, unfollowed, errors
+		}
+
+		if err := s.spotifyClient.DeletePlaylist(ctx, run.client, playlist.ID); err != nil {
+			if ctx.Err() != nil {
+				return added, unfollowed, errors
 			}
+			log.Error().Err(err).Str("playlistID", playlist.ID).Str("playlistName", playlist.Name).Msg("Failed to unfollow merged playlist")
+			errors = append(errors, domain.ExecutionError{
+				Operation: "unfollow_merged_playlist",
+				Playlist:  playlist.ID,
+				Error:     err.Error(),
+			})
+			continue
+		}
+
+		unfollowed++
+		s.libraryService.unregisterPlaylist(run.exec.UserID, playlist.ID)
+		s.recordJournal(run.exec.ID, domain.JournalEntry{
+			Operation:    domain.JournalPlaylistUnfollowed,
+			PlaylistID:   playlist.ID,
+			PlaylistName: playlist.Name,
+		})
+		s.completeBatch(run, key)
+		log.Info().Str("playlistID", playlist.ID).Str("into", playlistID).Msg("Merged playlist")
+	}
+
+	return added, unfollowed, errors
+}
+
+// plannedAdditions returns the tracks the add step puts into each playlist. They are decided by the plan
+// alone, so merges leave out the same tracks on every run and their batch keys match between runs.
+func plannedAdditions(plan *domain.SortPlan) map[string]map[string]bool {
+	added := make(map[string]map[string]bool) // playlist ID -> track IDs
+	for _, move := range plan.ApprovedTracksToAdd() {
+		if added[move.ToPlaylist] == nil {
+			added[move.ToPlaylist] = make(map[string]bool)
 		}
+		added[move.ToPlaylist][move.TrackID] = true
+	}
+	return added
+}
+
+// handleUncategorizedTracks creates/updates an "Uncategorized" playlist
+func (s *ExecutorService) handleUncategorizedTracks(ctx context.Context, run *executionRun, tracks []domain.Track) (int, []domain.ExecutionError) {
+	if len(tracks) == 0 {
+		return 0, nil
 	}
 
-	// Create if doesn't exist
-	if uncategorizedPlaylist == nil {
-		s.broadcaster.SendInfo(userID, "Creating Uncategorized playlist...")
-		playlist, err := s.spotifyClient.CreatePlaylist(ctx, client, userID, "Uncategorized", "Songs without a clear genre", false)
+	userID := run.exec.UserID
+	var errors []domain.ExecutionError
+
+	uncategorizedPlaylist := &domain.Playlist{
+		ID:   run.exec.Checkpoint.UncategorizedPlaylistID,
+		Name: "Uncategorized",
+	}
+
+	// The playlist is looked up once; resumed runs reuse it from the checkpoint
+	if uncategorizedPlaylist.ID == "" {
+		// Check if "Uncategorized" playlist already exists
+		playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, run.client, userID)
 		if err != nil {
 			errors = append(errors, domain.ExecutionError{
-				Operation: "create_uncategorized_playlist",
+				Operation: "fetch_playlists",
 				Error:     err.Error(),
 			})
 			return 0, errors
 		}
-		uncategorizedPlaylist = &domain.Playlist{
-			ID:   playlist.ID.String(),
-			Name: playlist.Name,
+		s.libraryService.resolveManagedPlaylists(userID, playlists)
+
+		// The survivor of duplicates, a merge later in the execution unfollows the others
+		if group := s.libraryService.managedPlaylistsByGenre(playlists, userID)[uncategorizedGenreKey]; len(group) > 0 {
+			uncategorizedPlaylist = group[0]
+		} else {
+			for i := range playlists {
+				if playlists[i].ManagedByApp && playlists[i].OwnerID == userID && playlists[i].Name == "Uncategorized" {
+					uncategorizedPlaylist = &playlists[i]
+					break
+				}
+			}
 		}
+
+		// Create if doesn't exist
+		if uncategorizedPlaylist.ID == "" {
+			s.broadcaster.SendInfo(ctx, userID, "Creating Uncategorized playlist...")
+			playlist, err := s.spotifyClient.CreatePlaylist(ctx, run.client, userID, "Uncategorized", uncategorizedGenreKey, "Songs without a clear genre", false)
+			if err != nil {
+				errors = append(errors, domain.ExecutionError{
+					Operation: "create_uncategorized_playlist",
+					Error:     err.Error(),
+				})
+				return 0, errors
+			}
+			s.libraryService.registerPlaylist(userID, uncategorizedGenreKey, playlist.ID.String())
+			uncategorizedPlaylist = &domain.Playlist{
+				ID:   playlist.ID.String(),
+				Name: playlist.Name,
+			}
+			run.setContents(uncategorizedPlaylist.ID, nil)
+			s.recordJournal(run.exec.ID, domain.JournalEntry{
+				Operation:    domain.JournalPlaylistCreated,
+				PlaylistID:   uncategorizedPlaylist.ID,
+				PlaylistName: uncategorizedPlaylist.Name,
+			})
+		}
+
+		run.exec.Checkpoint.UncategorizedPlaylistID = uncategorizedPlaylist.ID
+		s.saveCheckpoint(run)
 	}
 
 	// Add tracks
@@ -303,26 +1045,46 @@ func (s *ExecutorService) handleUncategorizedTracks(ctx context.Context, client
 		trackIDs[i] = spotify.ID(track.ID)
 	}
 
-	s.broadcaster.SendInfo(userID, fmt.Sprintf("Adding %d tracks to Uncategorized playlist...", len(trackIDs)))
-	err = s.spotifyClient.AddTracksToPlaylist(ctx, client, uncategorizedPlaylist.ID, trackIDs)
-	if err != nil {
+	s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Adding %d tracks to Uncategorized playlist...", len(trackIDs)))
+
+	added, err := s.addTracksInBatches(ctx, run, "uncategorized", uncategorizedPlaylist.ID, uncategorizedPlaylist.Name, trackIDs)
+	if err != nil && ctx.Err() == nil {
 		errors = append(errors, domain.ExecutionError{
 			Operation: "add_uncategorized_tracks",
 			Playlist:  uncategorizedPlaylist.ID,
 			Error:     err.Error(),
 		})
-		return 0, errors
 	}
 
-	return len(trackIDs), errors
+	return added, errors
+}
+
+// executionTargets returns the IDs of the playlists the execution created, renamed to their group
+// or merged other playlists into, including those of earlier runs
+func executionTargets(exec *domain.Execution) map[string]bool {
+	targets := make(map[string]bool)
+	for _, playlistID := range exec.Checkpoint.CreatedPlaylists {
+		targets[playlistID] = true
+	}
+	for _, playlistID := range exec.Checkpoint.RenamedPlaylists {
+		targets[playlistID] = true
+	}
+	for _, merge := range exec.Plan.ApprovedPlaylistMerges() {
+		targets[merge.SurvivorID] = true
+	}
+	for _, merge := range exec.Plan.ApprovedGroupMerges() {
+		targets[merge.TargetID] = true
+	}
+	return targets
 }
 
-// removeEmptyPlaylists finds and deletes empty managed playlists
-func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, client *spotify.Client, userID string) (int, []domain.ExecutionError) {
+// removeEmptyPlaylists finds and deletes empty managed playlists, except those the execution created or merged into
+func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, run *executionRun) (int, []domain.ExecutionError) {
+	userID := run.exec.UserID
 	var errors []domain.ExecutionError
 
 	// Fetch all playlists
-	playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, client, userID)
+	playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, run.client, userID)
 	if err != nil {
 		errors = append(errors, domain.ExecutionError{
 			Operation: "fetch_playlists_for_cleanup",
@@ -330,6 +1092,8 @@ func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, client *spot
 		})
 		return 0, errors
 	}
+	s.libraryService.resolveManagedPlaylists(userID, playlists)
+	targets := executionTargets(run.exec)
 
 	deletedCount := 0
 	for _, playlist := range playlists {
@@ -338,19 +1102,31 @@ func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, client *spot
 			continue
 		}
 
+		// Playlists the execution created or merged into are kept, even when every addition to them was rejected
+		if targets[playlist.ID] {
+			continue
+		}
+
 		// Skip "Uncategorized" playlist - we don't want to delete it even if empty
 		normalized := genre.NormalizeGenre(playlist.AssignedGenre)
-		if normalized == "uncategorized" || playlist.Name == "Uncategorized" {
+		if normalized == uncategorizedGenreKey || playlist.Name == "Uncategorized" {
 			continue
 		}
 
 		// Check if playlist is empty
 		// Use TrackCount from the playlist object (from Spotify API)
 		if playlist.TrackCount == 0 {
-			s.broadcaster.SendInfo(userID, fmt.Sprintf("Deleting empty playlist: %s", playlist.Name))
-			
-			err := s.spotifyClient.DeletePlaylist(ctx, client, playlist.ID)
+			if ctx.Err() != nil {
+				return deletedCount, errors
+			}
+
+			s.broadcaster.SendInfo(ctx, userID, fmt.Sprintf("Deleting empty playlist: %s", playlist.Name))
+
+			err := s.spotifyClient.DeletePlaylist(ctx, run.client, playlist.ID)
 			if err != nil {
+				if ctx.Err() != nil {
+					return deletedCount, errors
+				}
 				log.Error().Err(err).Str("playlistID", playlist.ID).Str("playlistName", playlist.Name).Msg("Failed to delete empty playlist")
 				errors = append(errors, domain.ExecutionError{
 					Operation: "delete_empty_playlist",
@@ -359,6 +1135,12 @@ func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, client *spot
 				})
 			} else {
 				deletedCount++
+				s.libraryService.unregisterPlaylist(userID, playlist.ID)
+				s.recordJournal(run.exec.ID, domain.JournalEntry{
+					Operation:    domain.JournalPlaylistUnfollowed,
+					PlaylistID:   playlist.ID,
+					PlaylistName: playlist.Name,
+				})
 				log.Info().Str("playlistID", playlist.ID).Str("playlistName", playlist.Name).Msg("Deleted empty playlist")
 			}
 		}
@@ -366,3 +1148,98 @@ func (s *ExecutorService) removeEmptyPlaylists(ctx context.Context, client *spot
 
 	return deletedCount, errors
 }
+
+// recordJournal appends a mutation to the execution journal
+func (s *ExecutorService) recordJournal(executionID string, entry domain.JournalEntry) {
+	entry.At = time.Now()
+	if err := s.executions.AppendJournal(executionID, entry); err != nil {
+		log.Error().Err(err).Str("executionID", executionID).Str("operation", string(entry.Operation)).Msg("Failed to record journal entry")
+	}
+}
+
+// completeStep records a finished step in the checkpoint
+func (s *ExecutorService) completeStep(run *executionRun, step string) {
+	run.exec.Checkpoint.CompletedSteps = append(run.exec.Checkpoint.CompletedSteps, step)
+	s.saveCheckpoint(run)
+}
+
+// completeBatch records an applied batch in the checkpoint
+func (s *ExecutorService) completeBatch(run *executionRun, key string) {
+	run.exec.Checkpoint.CompletedBatches[key] = true
+	s.saveCheckpoint(run)
+}
+
+// saveCheckpoint persists the checkpoint of a running execution
+func (s *ExecutorService) saveCheckpoint(run *executionRun) {
+	checkpoint := run.exec.Checkpoint.Clone()
+	if err := s.executions.Update(run.exec.ID, func(exec *domain.Execution) {
+		exec.Checkpoint = checkpoint
+	}); err != nil {
+		log.Error().Err(err).Str("executionID", run.exec.ID).Msg("Failed to save execution checkpoint")
+	}
+}
+
+// finishExecution stores the final status and result of an execution
+func (s *ExecutorService) finishExecution(run *executionRun, status domain.ExecutionStatus, result *domain.ExecutionResult) {
+	result.Status = status
+	result.CompletedSteps = append([]string{}, run.exec.Checkpoint.CompletedSteps...)
+	run.exec.Status = status
+
+	err := s.executions.Update(run.exec.ID, func(exec *domain.Execution) {
+		now := time.Now()
+		exec.Status = status
+		exec.FinishedAt = &now
+		resultCopy := *result
+		exec.Result = &resultCopy
+	})
+	if err != nil {
+		log.Error().Err(err).Str("executionID", run.exec.ID).Msg("Failed to finish execution")
+	}
+}
+
+// batchKey identifies a batch of a playlist mutation in the checkpoint.
+// Batches are cut from the full track list, so keys are stable between runs.
+func batchKey(prefix, playlistID string, index int) string {
+	return fmt.Sprintf("%s:%s:%d", prefix, playlistID, index)
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// batchTrackIDs splits track IDs into batches Spotify accepts in a single request
+func batchTrackIDs(trackIDs []spotify.ID) [][]spotify.ID {
+	var batches [][]spotify.ID
+	for i := 0; i < len(trackIDs); i += playlistBatchSize {
+		end := i + playlistBatchSize
+		if end > len(trackIDs) {
+			end = len(trackIDs)
+		}
+		batches = append(batches, trackIDs[i:end])
+	}
+	return batches
+}
+
+// idStrings converts Spotify IDs to plain strings
+func idStrings(ids []spotify.ID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
+
+// spotifyIDs converts plain strings to Spotify IDs
+func spotifyIDs(ids []string) []spotify.ID {
+	result := make([]spotify.ID, len(ids))
+	for i, id := range ids {
+		result[i] = spotify.ID(id)
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/job"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+)
+
+func TestExecuteSortsLibrary(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 300, Artists: 30})
+
+	plan := env.plan(t, nil)
+	if len(plan.PlaylistsToCreate) == 0 || len(plan.TracksToAdd) == 0 {
+		t.Fatalf("plan has nothing to do: %d playlists, %d additions", len(plan.PlaylistsToCreate), len(plan.TracksToAdd))
+	}
+
+	result := env.execute(t, plan)
+	if result.PlaylistsCreated != len(plan.PlaylistsToCreate) {
+		t.Errorf("created %d playlists, want %d", result.PlaylistsCreated, len(plan.PlaylistsToCreate))
+	}
+	if result.TracksAdded != len(plan.TracksToAdd)+len(plan.UncategorizedTracks) {
+		t.Errorf("added %d tracks, want %d", result.TracksAdded, len(plan.TracksToAdd)+len(plan.UncategorizedTracks))
+	}
+	env.assertNoDuplicates(t)
+
+	// A sorted library leaves nothing to do
+	again := env.plan(t, nil)
+	if n := len(again.PlaylistsToCreate) + len(again.TracksToAdd) + len(again.TracksToRemove); n > 0 {
+		t.Errorf("sorted library still has %d changes planned", n)
+	}
+}
+
+func TestExecuteSkipsTracksAlreadyInPlaylist(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	ctx := context.Background()
+
+	env.execute(t, env.plan(t, nil))
+
+	// Take songs out of their playlist, so the plan adds them back
+	var playlistID string
+	var trackIDs []spotify.ID
+	for _, p := range env.followedPlaylists() {
+		// Songs without a genre are planned for Uncategorized every time, not as additions
+		if len(p.TrackIDs) >= 2 && p.Name != "Uncategorized" {
+			playlistID = p.ID
+			trackIDs = []spotify.ID{spotify.ID(p.TrackIDs[0]), spotify.ID(p.TrackIDs[1])}
+			break
+		}
+	}
+	if err := env.spotify.RemoveTracksFromPlaylist(ctx, env.client, playlistID, trackIDs); err != nil {
+		t.Fatal(err)
+	}
+	plan := env.plan(t, nil)
+	if len(plan.TracksToAdd) != 2 {
+		t.Fatalf("plan has %d additions, want 2", len(plan.TracksToAdd))
+	}
+
+	// One of them is back in the playlist before the plan is executed
+	if err := env.spotify.AddTracksToPlaylist(ctx, env.client, playlistID, trackIDs[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	result := env.execute(t, plan)
+	if result.TracksAdded != 1 {
+		t.Errorf("added %d tracks, want 1", result.TracksAdded)
+	}
+	env.assertNoDuplicates(t)
+}
+
+func TestResumeDoesNotDuplicateUncheckpointedBatches(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 300, Artists: 30})
+
+	plan := env.plan(t, nil)
+	result := env.execute(t, plan)
+
+	// Pretend the execution stopped right after applying its additions, before checkpointing them
+	err := env.executor.executions.Update(result.ExecutionID, func(e *domain.Execution) {
+		e.Status = domain.ExecutionInterrupted
+		e.Checkpoint.CompletedSteps = []string{stepCreatePlaylists}
+		e.Checkpoint.CompletedBatches = map[string]bool{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := env.executor.ResumeExecution(context.Background(), env.client, result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.TracksAdded != result.TracksAdded {
+		t.Errorf("resumed run added %d more tracks", resumed.TracksAdded-result.TracksAdded)
+	}
+	env.assertNoDuplicates(t)
+
+	exec, err := env.executor.GetExecution(result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.Status != domain.ExecutionCompleted {
+		t.Errorf("status = %s, want completed", exec.Status)
+	}
+	for _, step := range executionSteps {
+		if !exec.Checkpoint.StepCompleted(step) {
+			t.Errorf("step %s is not checkpointed", step)
+		}
+	}
+}
+
+func TestCancelledExecutionResumes(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 300, Artists: 30, Latency: time.Millisecond})
+	plan := env.plan(t, nil)
+
+	// Cancel like the job manager does, once the execution made a few requests
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	start := env.server.Requests()
+	go func() {
+		for env.server.Requests() < start+5 {
+			time.Sleep(100 * time.Microsecond)
+		}
+		cancel(job.ErrCancelled)
+	}()
+
+	result, err := env.executor.ExecuteSortPlan(ctx, env.client, plan, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Cancelled || result.Success {
+		t.Fatalf("result = %+v, want cancelled", result)
+	}
+
+	exec, err := env.executor.GetExecution(result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.Status != domain.ExecutionCancelled || !exec.Resumable() {
+		t.Fatalf("status = %s, want a resumable cancelled execution", exec.Status)
+	}
+
+	resumed, err := env.executor.ResumeExecution(context.Background(), env.client, result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Success || len(resumed.Errors) > 0 {
+		t.Fatalf("resumed execution failed: %+v", resumed.Errors)
+	}
+	if resumed.PlaylistsCreated != len(plan.PlaylistsToCreate) {
+		t.Errorf("created %d playlists over both runs, want %d", resumed.PlaylistsCreated, len(plan.PlaylistsToCreate))
+	}
+	env.assertNoDuplicates(t)
+
+	again := env.plan(t, nil)
+	if n := len(again.PlaylistsToCreate) + len(again.TracksToAdd) + len(again.TracksToRemove); n > 0 {
+		t.Errorf("library still has %d changes planned after resuming", n)
+	}
+}
+
+func TestExecuteWaitsOutRateLimits(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+
+	plan := env.plan(t, nil)
+	env.server.RateLimit(2, time.Second)
+	result := env.execute(t, plan)
+	if result.PlaylistsCreated != len(plan.PlaylistsToCreate) {
+		t.Errorf("created %d playlists, want %d", result.PlaylistsCreated, len(plan.PlaylistsToCreate))
+	}
+	if result.TracksAdded != len(plan.TracksToAdd)+len(plan.UncategorizedTracks) {
+		t.Errorf("added %d tracks, want %d", result.TracksAdded, len(plan.TracksToAdd)+len(plan.UncategorizedTracks))
+	}
+	env.assertNoDuplicates(t)
+
+	again := env.plan(t, nil)
+	if n := len(again.PlaylistsToCreate) + len(again.TracksToAdd) + len(again.TracksToRemove); n > 0 {
+		t.Errorf("sorted library still has %d changes planned", n)
+	}
+}
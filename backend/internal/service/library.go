@@ -2,107 +2,96 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/zmb3/spotify/v2"
 
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/artistcache"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/genre"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/genreregistry"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/library"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/playlistcache"
 	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
 )
 
 // LibraryService handles fetching and analyzing user's Spotify library
 type LibraryService struct {
-	spotifyClient *spotifyClient.Client
+	spotifyClient spotifyClient.API
 	broadcaster   *sse.Broadcaster
+	artists       artistcache.Store
+	snapshots     library.Store
+	playlists     playlistcache.Store
+	registry      genreregistry.Store
 }
 
-// NewLibraryService creates a new library service
-func NewLibraryService(client *spotifyClient.Client, broadcaster *sse.Broadcaster) *LibraryService {
+// fullSyncInterval is how long incremental syncs are trusted before the whole library is fetched again
+const fullSyncInterval = 24 * time.Hour
+
+// NewLibraryService creates a new library service. Artist genres are cached in artists,
+// liked songs are kept in snapshots so later analyses only fetch newly liked songs,
+// managed playlist contents are cached in playlists until the playlist changes,
+// and registry keeps the genre of every managed playlist across renames.
+func NewLibraryService(client spotifyClient.API, broadcaster *sse.Broadcaster, artists artistcache.Store, snapshots library.Store, playlists playlistcache.Store, registry genreregistry.Store) *LibraryService {
 	return &LibraryService{
 		spotifyClient: client,
 		broadcaster:   broadcaster,
+		artists:       artists,
+		snapshots:     snapshots,
+		playlists:     playlists,
+		registry:      registry,
 	}
 }
 
+// AnalyzeOptions tune a library analysis
+type AnalyzeOptions struct {
+	RefreshArtists bool // Fetch every artist from Spotify, even those cached and fresh
+	FullSync       bool // Fetch all liked songs and managed playlists instead of only what changed since the last sync
+}
+
 // LibraryAnalysis contains the complete analysis of user's library
 type LibraryAnalysis struct {
-	Tracks             []domain.Track            `json:"tracks"`
-	Playlists          []domain.Playlist         `json:"playlists"`
-	GenreDistribution  map[string]int            `json:"genreDistribution"`
-	TotalLikedSongs    int                       `json:"totalLikedSongs"`
-	TracksWithGenre    int                       `json:"tracksWithGenre"`
-	TracksWithoutGenre int                       `json:"tracksWithoutGenre"`
-	GroupingSuggestions []genre.GroupSuggestion  `json:"groupingSuggestions"`
-	GenreGroups        map[string]*genre.GenreGroup `json:"genreGroups"`
+	Tracks              []domain.Track               `json:"tracks"`
+	Playlists           []domain.Playlist            `json:"playlists"`
+	GenreDistribution   map[string]int               `json:"genreDistribution"`
+	TotalLikedSongs     int                          `json:"totalLikedSongs"`
+	TracksWithGenre     int                          `json:"tracksWithGenre"`
+	TracksWithoutGenre  int                          `json:"tracksWithoutGenre"`
+	GroupingSuggestions []genre.GroupSuggestion      `json:"groupingSuggestions"`
+	GenreGroups         map[string]*genre.GenreGroup `json:"genreGroups"`
+	UnlikedTracks       []domain.Track               `json:"unlikedTracks"` // Unliked songs that managed playlists still hold, with those playlists
+	Fingerprint         string                       `json:"fingerprint"`   // Identifies the library contents the analysis was built from
 }
 
 // AnalyzeLibrary fetches all liked songs, playlists, and analyzes genres
-func (s *LibraryService) AnalyzeLibrary(ctx context.Context, client *spotify.Client, userID string) (*LibraryAnalysis, error) {
+func (s *LibraryService) AnalyzeLibrary(ctx context.Context, client spotifyClient.Conn, userID string, opts AnalyzeOptions) (*LibraryAnalysis, error) {
 	log.Info().Str("userID", userID).Msg("Starting library analysis")
 
-	// Fetch liked songs
-	s.broadcaster.SendProgress(userID, sse.PhaseFetchingLikedSongs, 0, 0, "Fetching your liked songs...")
-	tracks, err := s.spotifyClient.FetchAllLikedSongs(ctx, client, func(current, total int) {
-		s.broadcaster.SendProgress(userID, sse.PhaseFetchingLikedSongs, current, total,
-			fmt.Sprintf("Fetching liked songs: %d/%d", current, total))
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch liked songs: %w", err)
-	}
-
-	log.Info().Int("count", len(tracks)).Msg("Fetched liked songs")
-
-	// Fetch playlists
-	s.broadcaster.SendProgress(userID, sse.PhaseFetchingPlaylists, 0, 0, "Fetching your playlists...")
-	playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, client, userID)
+	tracks, unliked, playlists, err := s.fetchLibrary(ctx, client, userID, opts.FullSync)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch playlists: %w", err)
-	}
-
-	log.Info().Int("count", len(playlists)).Msg("Fetched playlists")
-
-	// Fetch playlist tracks for managed playlists
-	s.broadcaster.SendInfo(userID, "Loading managed playlists...")
-	for i := range playlists {
-		if playlists[i].ManagedByApp && playlists[i].OwnerID == userID {
-			trackIDs, err := s.spotifyClient.FetchPlaylistTracks(ctx, client, playlists[i].ID)
-			if err != nil {
-				log.Warn().Err(err).Str("playlistID", playlists[i].ID).Msg("Failed to fetch playlist tracks")
-				continue
-			}
-			playlists[i].TrackIDs = trackIDs
-		}
-	}
-
-	// Build track to playlist mapping
-	trackToPlaylists := make(map[string][]string)
-	for _, playlist := range playlists {
-		if playlist.ManagedByApp && playlist.OwnerID == userID {
-			for _, trackID := range playlist.TrackIDs {
-				trackToPlaylists[trackID] = append(trackToPlaylists[trackID], playlist.ID)
-			}
-		}
+		return nil, err
 	}
 
-	// Update tracks with playlist membership
-	for i := range tracks {
-		if playlists, ok := trackToPlaylists[tracks[i].ID]; ok {
-			tracks[i].InPlaylists = playlists
-		}
-	}
+	// Fingerprint before enrichment, genres are not part of the library contents
+	fingerprint := LibraryFingerprint(tracks, playlists, userID)
 
 	// Fetch artist genres
-	s.broadcaster.SendProgress(userID, sse.PhaseFetchingArtists, 0, len(tracks), "Fetching artist information...")
-	tracks, err = s.enrichTracksWithGenres(ctx, client, tracks, userID)
+	s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingArtists, 0, len(tracks), "Fetching artist information...")
+	tracks, err = s.enrichTracksWithGenres(ctx, client, tracks, userID, opts.RefreshArtists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to enrich tracks with genres: %w", err)
 	}
 
 	// Analyze genre distribution
-	s.broadcaster.SendProgress(userID, sse.PhaseAnalyzing, 0, 0, "Analyzing your music library...")
+	s.broadcaster.SendProgress(ctx, userID, sse.PhaseAnalyzing, 0, 0, "Analyzing your music library...")
 	genreDistribution := make(map[string]int)
 	tracksWithGenre := 0
 	tracksWithoutGenre := 0
@@ -137,11 +126,431 @@ func (s *LibraryService) AnalyzeLibrary(ctx context.Context, client *spotify.Cli
 		TracksWithoutGenre:  tracksWithoutGenre,
 		GroupingSuggestions: groupingSuggestions,
 		GenreGroups:         genreGroups,
+		UnlikedTracks:       unliked,
+		Fingerprint:         fingerprint,
 	}, nil
 }
 
-// enrichTracksWithGenres fetches artist information and assigns genres to tracks
-func (s *LibraryService) enrichTracksWithGenres(ctx context.Context, client *spotify.Client, tracks []domain.Track, userID string) ([]domain.Track, error) {
+// CurrentFingerprint fetches the library contents and returns their fingerprint
+// without running the genre analysis
+func (s *LibraryService) CurrentFingerprint(ctx context.Context, client spotifyClient.Conn, userID string) (string, error) {
+	tracks, _, playlists, err := s.fetchLibrary(ctx, client, userID, false)
+	if err != nil {
+		return "", err
+	}
+
+	return LibraryFingerprint(tracks, playlists, userID), nil
+}
+
+// fetchLibrary fetches liked songs and playlists, and resolves which managed playlists each track is in.
+// It also returns the unliked songs that managed playlists still hold.
+func (s *LibraryService) fetchLibrary(ctx context.Context, client spotifyClient.Conn, userID string, fullSync bool) ([]domain.Track, []domain.Track, []domain.Playlist, error) {
+	// Fetch liked songs
+	s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingLikedSongs, 0, 0, "Fetching your liked songs...")
+	tracks, unliked, err := s.likedSongs(ctx, client, userID, fullSync)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch liked songs: %w", err)
+	}
+
+	log.Info().Int("count", len(tracks)).Msg("Fetched liked songs")
+
+	// Fetch playlists
+	s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingPlaylists, 0, 0, "Fetching your playlists...")
+	playlists, err := s.spotifyClient.FetchAllPlaylists(ctx, client, userID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch playlists: %w", err)
+	}
+
+	log.Info().Int("count", len(playlists)).Msg("Fetched playlists")
+	s.resolveManagedPlaylists(userID, playlists)
+
+	// Fetch playlist tracks for managed playlists
+	s.broadcaster.SendInfo(ctx, userID, "Loading managed playlists...")
+	if err := s.loadManagedPlaylistTracks(ctx, client, playlists, userID, fullSync); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Build track to playlist mapping
+	trackToPlaylists := make(map[string][]string)
+	for _, playlist := range playlists {
+		if playlist.ManagedByApp && playlist.OwnerID == userID {
+			for _, trackID := range playlist.TrackIDs {
+				trackToPlaylists[trackID] = append(trackToPlaylists[trackID], playlist.ID)
+			}
+		}
+	}
+
+	// Update tracks with playlist membership
+	for i := range tracks {
+		if playlists, ok := trackToPlaylists[tracks[i].ID]; ok {
+			tracks[i].InPlaylists = playlists
+		}
+	}
+
+	// Unliked songs are remembered until no managed playlist holds them anymore
+	held := []domain.Track{}
+	for _, track := range unliked {
+		if inPlaylists, ok := trackToPlaylists[track.ID]; ok {
+			track.InPlaylists = inPlaylists
+			held = append(held, track)
+		}
+	}
+	if len(held) != len(unliked) {
+		s.forgetUnliked(userID, held)
+	}
+
+	return tracks, held, playlists, nil
+}
+
+// resolveManagedPlaylists assigns the user's managed playlists their registered genre, so a renamed playlist
+// keeps its genre. Playlists the registry does not know yet are registered under the genre from their marker,
+// or from their name when they were created before markers existed. Registrations of playlists that are gone
+// or no longer tagged as managed are dropped.
+func (s *LibraryService) resolveManagedPlaylists(userID string, playlists []domain.Playlist) {
+	registered, err := s.registry.Get(userID)
+	if err != nil {
+		// Markers and names still identify most playlists
+		log.Warn().Err(err).Str("userID", userID).Msg("Failed to load genre registry")
+		return
+	}
+
+	genreOf := make(map[string]string, len(registered)) // playlist ID -> genre key
+	for genreKey, playlistID := range registered {
+		genreOf[playlistID] = genreKey
+	}
+
+	managed := make(map[string]bool)
+	for i := range playlists {
+		p := &playlists[i]
+		if !p.ManagedByApp || p.OwnerID != userID {
+			continue
+		}
+		managed[p.ID] = true
+
+		if genreKey, ok := genreOf[p.ID]; ok {
+			p.AssignedGenre = genreKey
+			continue
+		}
+
+		genreKey := genre.NormalizeGenre(p.AssignedGenre)
+		if genreKey == "" {
+			continue
+		}
+		if _, taken := registered[genreKey]; taken {
+			// Another playlist holds the genre already
+			continue
+		}
+		s.registerPlaylist(userID, genreKey, p.ID)
+		registered[genreKey] = p.ID
+		genreOf[p.ID] = genreKey
+	}
+
+	for playlistID := range genreOf {
+		if !managed[playlistID] {
+			s.unregisterPlaylist(userID, playlistID)
+		}
+	}
+}
+
+// registerPlaylist records the playlist of a genre. Failures are logged, the marker still identifies the playlist.
+func (s *LibraryService) registerPlaylist(userID, genreKey, playlistID string) {
+	if err := s.registry.Put(userID, genreKey, playlistID); err != nil {
+		log.Warn().Err(err).Str("genre", genreKey).Str("playlistID", playlistID).Msg("Failed to register genre playlist")
+	}
+}
+
+// unregisterPlaylist forgets the genre of a playlist that was deleted
+func (s *LibraryService) unregisterPlaylist(userID, playlistID string) {
+	if err := s.registry.RemovePlaylist(userID, playlistID); err != nil {
+		log.Warn().Err(err).Str("playlistID", playlistID).Msg("Failed to unregister genre playlist")
+	}
+}
+
+// loadManagedPlaylistTracks fills in the tracks of the user's managed playlists. Playlists whose snapshot ID
+// matches the cached one are taken from the cache, the others are fetched, or all of them with fullSync.
+// A playlist that cannot be fetched fails the load, planning without its tracks would add them all again.
+func (s *LibraryService) loadManagedPlaylistTracks(ctx context.Context, client spotifyClient.Conn, playlists []domain.Playlist, userID string, fullSync bool) error {
+	var managedIDs []string
+	for _, p := range playlists {
+		if p.ManagedByApp && p.OwnerID == userID {
+			managedIDs = append(managedIDs, p.ID)
+		}
+	}
+
+	cached := map[string]*playlistcache.Entry{}
+	if !fullSync && len(managedIDs) > 0 {
+		var err error
+		cached, err = s.playlists.GetMany(managedIDs)
+		if err != nil {
+			// The cache only saves requests, fetch everything instead
+			log.Warn().Err(err).Msg("Failed to load cached playlist contents")
+			cached = map[string]*playlistcache.Entry{}
+		}
+	}
+
+	fetched := 0
+	for i := range playlists {
+		if !playlists[i].ManagedByApp || playlists[i].OwnerID != userID {
+			continue
+		}
+
+		if entry, ok := cached[playlists[i].ID]; ok && entry.SnapshotID != "" && entry.SnapshotID == playlists[i].SnapshotID {
+			playlists[i].TrackIDs = entry.TrackIDs
+			continue
+		}
+
+		trackIDs, err := s.spotifyClient.FetchPlaylistTracks(ctx, client, playlists[i].ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to fetch tracks of playlist %s: %w", playlists[i].ID, err)
+		}
+		playlists[i].TrackIDs = trackIDs
+		fetched++
+
+		if err := s.playlists.Put(&playlistcache.Entry{
+			PlaylistID: playlists[i].ID,
+			SnapshotID: playlists[i].SnapshotID,
+			TrackIDs:   trackIDs,
+			FetchedAt:  time.Now(),
+		}); err != nil {
+			log.Warn().Err(err).Str("playlistID", playlists[i].ID).Msg("Failed to cache playlist contents")
+		}
+	}
+
+	log.Info().
+		Int("managed", len(managedIDs)).
+		Int("fetched", fetched).
+		Bool("fullSync", fullSync).
+		Msg("Loaded managed playlist tracks")
+
+	return nil
+}
+
+// playlistTracks returns the tracks of a playlist in playlist order, from the cache when its snapshot ID
+// still matches, otherwise fetched and cached
+func (s *LibraryService) playlistTracks(ctx context.Context, client spotifyClient.Conn, playlistID, snapshotID string) ([]string, error) {
+	if snapshotID != "" {
+		cached, err := s.playlists.GetMany([]string{playlistID})
+		if err != nil {
+			log.Warn().Err(err).Str("playlistID", playlistID).Msg("Failed to load cached playlist contents")
+		} else if entry, ok := cached[playlistID]; ok && entry.SnapshotID == snapshotID {
+			return entry.TrackIDs, nil
+		}
+	}
+
+	trackIDs, err := s.spotifyClient.FetchPlaylistTracks(ctx, client, playlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshotID != "" {
+		if err := s.playlists.Put(&playlistcache.Entry{
+			PlaylistID: playlistID,
+			SnapshotID: snapshotID,
+			TrackIDs:   trackIDs,
+			FetchedAt:  time.Now(),
+		}); err != nil {
+			log.Warn().Err(err).Str("playlistID", playlistID).Msg("Failed to cache playlist contents")
+		}
+	}
+
+	return trackIDs, nil
+}
+
+// likedSongs returns the user's liked songs, newest first, and the unliked songs the snapshot remembers.
+// With a recent snapshot only songs liked since the last sync are fetched. Otherwise, with fullSync or when
+// songs were unliked, the whole library is fetched and compared with the snapshot to tell which were unliked.
+func (s *LibraryService) likedSongs(ctx context.Context, client spotifyClient.Conn, userID string, fullSync bool) ([]domain.Track, []domain.Track, error) {
+	snapshot, err := s.snapshots.Get(userID)
+	if err != nil && !errors.Is(err, library.ErrSnapshotNotFound) {
+		// The snapshot only saves requests, fetch everything instead
+		log.Warn().Err(err).Str("userID", userID).Msg("Failed to load library snapshot")
+	}
+
+	if snapshot != nil && len(snapshot.Tracks) > 0 && !fullSync && time.Since(snapshot.FullSyncAt) < fullSyncInterval {
+		tracks, complete, err := s.syncLikedSongs(ctx, client, userID, snapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+		if complete {
+			return tracks, pendingUnliked(snapshot.Unliked, tracks), nil
+		}
+	}
+
+	tracks, err := s.spotifyClient.FetchAllLikedSongs(ctx, client, func(current, total int) {
+		s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingLikedSongs, current, total,
+			fmt.Sprintf("Fetching liked songs: %d/%d", current, total))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The new snapshot drops the unliked songs and remembers them until the sort took them out of its playlists
+	unliked := []domain.Track{}
+	if snapshot != nil {
+		newlyUnliked := unlikedTracks(snapshot.Tracks, tracks)
+		unliked = pendingUnliked(append(snapshot.Unliked, newlyUnliked...), tracks)
+		log.Info().
+			Str("userID", userID).
+			Int("unliked", len(newlyUnliked)).
+			Msg("Full sync of liked songs")
+	}
+
+	now := time.Now()
+	s.saveSnapshot(&library.Snapshot{
+		UserID:     userID,
+		Tracks:     tracks,
+		Unliked:    unliked,
+		SyncedAt:   now,
+		FullSyncAt: now,
+	})
+
+	return tracks, unliked, nil
+}
+
+// syncLikedSongs fetches the songs liked since the snapshot and merges them into it. Unliked songs
+// cannot be seen this way, they show up as a merged library larger than Spotify's count.
+// complete is false in that case, and the whole library has to be fetched to tell which songs they are.
+func (s *LibraryService) syncLikedSongs(ctx context.Context, client spotifyClient.Conn, userID string, snapshot *library.Snapshot) (tracks []domain.Track, complete bool, err error) {
+	known := make(map[string]time.Time, len(snapshot.Tracks))
+	for _, track := range snapshot.Tracks {
+		known[track.ID] = track.AddedAt
+	}
+
+	// A song liked again moves to the top with a new added_at, it is not known in its new position
+	newTracks, total, err := s.spotifyClient.FetchNewLikedSongs(ctx, client, func(track domain.Track) bool {
+		addedAt, exists := known[track.ID]
+		return exists && addedAt.Equal(track.AddedAt)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged := make([]domain.Track, 0, len(newTracks)+len(snapshot.Tracks))
+	seen := make(map[string]bool, cap(merged))
+	for _, track := range append(newTracks, snapshot.Tracks...) {
+		if !seen[track.ID] {
+			seen[track.ID] = true
+			merged = append(merged, track)
+		}
+	}
+
+	if len(merged) != total {
+		log.Info().
+			Str("userID", userID).
+			Int("merged", len(merged)).
+			Int("total", total).
+			Msg("Liked songs were removed since the last sync, fetching all of them")
+		return nil, false, nil
+	}
+
+	log.Info().
+		Str("userID", userID).
+		Int("new", len(newTracks)).
+		Int("total", total).
+		Msg("Incremental sync of liked songs")
+	s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingLikedSongs, total, total,
+		fmt.Sprintf("Fetching liked songs: %d/%d", total, total))
+
+	s.saveSnapshot(&library.Snapshot{
+		UserID:     userID,
+		Tracks:     merged,
+		Unliked:    pendingUnliked(snapshot.Unliked, merged),
+		SyncedAt:   time.Now(),
+		FullSyncAt: snapshot.FullSyncAt,
+	})
+
+	return merged, true, nil
+}
+
+// forgetUnliked replaces the unliked songs of the user's snapshot with those managed playlists still hold
+func (s *LibraryService) forgetUnliked(userID string, held []domain.Track) {
+	snapshot, err := s.snapshots.Get(userID)
+	if err != nil {
+		return
+	}
+	snapshot.Unliked = held
+	s.saveSnapshot(snapshot)
+}
+
+// saveSnapshot stores the snapshot, a failure only costs a full sync next time
+func (s *LibraryService) saveSnapshot(snapshot *library.Snapshot) {
+	if err := s.snapshots.Save(snapshot); err != nil {
+		log.Warn().Err(err).Str("userID", snapshot.UserID).Msg("Failed to save library snapshot")
+	}
+}
+
+// unlikedTracks returns the tracks of before that are missing from after
+func unlikedTracks(before, after []domain.Track) []domain.Track {
+	current := make(map[string]bool, len(after))
+	for _, track := range after {
+		current[track.ID] = true
+	}
+
+	unliked := []domain.Track{}
+	for _, track := range before {
+		if !current[track.ID] {
+			unliked = append(unliked, track)
+		}
+	}
+	return unliked
+}
+
+// pendingUnliked returns the unliked tracks that were not liked again, each once
+func pendingUnliked(unliked, liked []domain.Track) []domain.Track {
+	skip := make(map[string]bool, len(liked)+len(unliked))
+	for _, track := range liked {
+		skip[track.ID] = true
+	}
+
+	pending := []domain.Track{}
+	for _, track := range unliked {
+		if !skip[track.ID] {
+			skip[track.ID] = true
+			pending = append(pending, track)
+		}
+	}
+	return pending
+}
+
+// LibraryFingerprint summarizes the liked songs and managed playlist contents.
+// Two fingerprints differ when a track was liked or unliked, or a managed playlist changed.
+func LibraryFingerprint(tracks []domain.Track, playlists []domain.Playlist, userID string) string {
+	h := sha256.New()
+
+	trackIDs := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		trackIDs = append(trackIDs, track.ID)
+	}
+	sort.Strings(trackIDs)
+	for _, id := range trackIDs {
+		h.Write([]byte("track:" + id + "\n"))
+	}
+
+	var managed []domain.Playlist
+	for _, p := range playlists {
+		if p.ManagedByApp && p.OwnerID == userID {
+			managed = append(managed, p)
+		}
+	}
+	sort.Slice(managed, func(i, j int) bool {
+		return managed[i].ID < managed[j].ID
+	})
+
+	for _, p := range managed {
+		ids := append([]string(nil), p.TrackIDs...)
+		sort.Strings(ids)
+		h.Write([]byte("playlist:" + p.ID + ":" + strings.Join(ids, ",") + "\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// enrichTracksWithGenres assigns genres to tracks. Artists come from the cache, only missing and stale ones
+// are fetched from Spotify, or all of them with refresh.
+func (s *LibraryService) enrichTracksWithGenres(ctx context.Context, client spotifyClient.Conn, tracks []domain.Track, userID string, refresh bool) ([]domain.Track, error) {
 	// Collect unique artist IDs
 	artistIDMap := make(map[string]bool)
 	for _, track := range tracks {
@@ -151,25 +560,26 @@ func (s *LibraryService) enrichTracksWithGenres(ctx context.Context, client *spo
 	}
 
 	// Convert to slice
-	artistIDs := make([]spotify.ID, 0, len(artistIDMap))
+	artistIDs := make([]string, 0, len(artistIDMap))
 	for id := range artistIDMap {
-		artistIDs = append(artistIDs, spotify.ID(id))
+		artistIDs = append(artistIDs, id)
 	}
 
-	log.Info().Int("count", len(artistIDs)).Msg("Fetching artist genres")
-
-	// Batch fetch artists
-	artistsMap, err := s.spotifyClient.BatchFetchArtists(ctx, client, artistIDs)
+	genresByArtist, err := s.artistGenres(ctx, client, artistIDs, refresh)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update tracks with artist genres
 	for i := range tracks {
+		if i%100 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		for j := range tracks[i].Artists {
 			artistID := tracks[i].Artists[j].ID
-			if artist, ok := artistsMap[artistID]; ok && artist != nil {
-				tracks[i].Artists[j].Genres = artist.Genres
+			if genres, ok := genresByArtist[artistID]; ok {
+				tracks[i].Artists[j].Genres = genres
 			}
 		}
 
@@ -178,7 +588,7 @@ func (s *LibraryService) enrichTracksWithGenres(ctx context.Context, client *spo
 
 		// Update progress periodically
 		if (i+1)%100 == 0 || i == len(tracks)-1 {
-			s.broadcaster.SendProgress(userID, sse.PhaseFetchingArtists, i+1, len(tracks),
+			s.broadcaster.SendProgress(ctx, userID, sse.PhaseFetchingArtists, i+1, len(tracks),
 				fmt.Sprintf("Processing artist genres: %d/%d", i+1, len(tracks)))
 		}
 	}
@@ -186,6 +596,70 @@ func (s *LibraryService) enrichTracksWithGenres(ctx context.Context, client *spo
 	return tracks, nil
 }
 
+// artistGenres returns the genres of the artists by ID, fetching the ones missing from the cache
+func (s *LibraryService) artistGenres(ctx context.Context, client spotifyClient.Conn, artistIDs []string, refresh bool) (map[string][]string, error) {
+	genresByArtist := make(map[string][]string, len(artistIDs))
+
+	missing := artistIDs
+	if !refresh {
+		cached, err := s.artists.GetMany(artistIDs)
+		if err != nil {
+			// The cache only saves requests, fetch everything instead
+			log.Warn().Err(err).Msg("Failed to load cached artists")
+			cached = nil
+		}
+
+		missing = make([]string, 0, len(artistIDs)-len(cached))
+		for _, id := range artistIDs {
+			if artist, ok := cached[id]; ok {
+				genresByArtist[id] = artist.Genres
+			} else {
+				missing = append(missing, id)
+			}
+		}
+	}
+
+	log.Info().
+		Int("count", len(artistIDs)).
+		Int("cached", len(genresByArtist)).
+		Int("fetching", len(missing)).
+		Bool("refresh", refresh).
+		Msg("Fetching artist genres")
+
+	if len(missing) == 0 {
+		return genresByArtist, nil
+	}
+
+	ids := make([]spotify.ID, 0, len(missing))
+	for _, id := range missing {
+		ids = append(ids, spotify.ID(id))
+	}
+
+	// Batch fetch artists
+	fetched, err := s.spotifyClient.BatchFetchArtists(ctx, client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	toCache := make([]*artistcache.Artist, 0, len(fetched))
+	for id, artist := range fetched {
+		genresByArtist[id] = artist.Genres
+		toCache = append(toCache, &artistcache.Artist{
+			ID:        id,
+			Name:      artist.Name,
+			Genres:    artist.Genres,
+			FetchedAt: now,
+		})
+	}
+
+	if err := s.artists.PutMany(toCache); err != nil {
+		log.Warn().Err(err).Msg("Failed to cache artists")
+	}
+
+	return genresByArtist, nil
+}
+
 // determinePrimaryGenre determines the primary genre for a track based on its artists
 func (s *LibraryService) determinePrimaryGenre(track domain.Track) string {
 	// Collect all genres from all artists
@@ -244,19 +718,51 @@ func (s *LibraryService) GetManagedPlaylists(playlists []domain.Playlist, userID
 
 // BuildGenreToPlaylistMap creates a mapping from normalized genre to playlist
 // When enabledGroups is provided, the map will be used with effective genres (after grouping)
+// When several managed playlists share a genre, the one that survives their merge is mapped.
 func (s *LibraryService) BuildGenreToPlaylistMap(playlists []domain.Playlist, userID string, enabledGroups map[string]bool) map[string]*domain.Playlist {
 	result := make(map[string]*domain.Playlist)
 
+	for normalized, group := range s.managedPlaylistsByGenre(playlists, userID) {
+		// Map the playlist's genre directly
+		result[normalized] = group[0]
+	}
+
+	return result
+}
+
+// managedPlaylistsByGenre groups the user's managed playlists by normalized genre. The first playlist of a
+// group survives when duplicates are merged: the registered playlist of the genre, otherwise the one with
+// the most tracks, and the lowest ID on a tie, so every plan picks the same one.
+func (s *LibraryService) managedPlaylistsByGenre(playlists []domain.Playlist, userID string) map[string][]*domain.Playlist {
+	registered, err := s.registry.Get(userID)
+	if err != nil {
+		log.Warn().Err(err).Str("userID", userID).Msg("Failed to load genre registry")
+		registered = map[string]string{}
+	}
+
+	groups := make(map[string][]*domain.Playlist)
 	for i := range playlists {
 		if playlists[i].ManagedByApp && playlists[i].OwnerID == userID {
-			playlistGenre := playlists[i].AssignedGenre
-			normalized := genre.NormalizeGenre(playlistGenre)
+			normalized := genre.NormalizeGenre(playlists[i].AssignedGenre)
 			if normalized != "" {
-				// Map the playlist's genre directly
-				result[normalized] = &playlists[i]
+				groups[normalized] = append(groups[normalized], &playlists[i])
 			}
 		}
 	}
 
-	return result
+	for normalized, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			iRegistered := group[i].ID == registered[normalized]
+			jRegistered := group[j].ID == registered[normalized]
+			if iRegistered != jRegistered {
+				return iRegistered
+			}
+			if group[i].TrackCount != group[j].TrackCount {
+				return group[i].TrackCount > group[j].TrackCount
+			}
+			return group[i].ID < group[j].ID
+		})
+	}
+
+	return groups
 }
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+)
+
+func TestAnalyzeReportsUnlikedTracks(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	ctx := context.Background()
+
+	env.execute(t, env.plan(t, nil))
+
+	// Unlike a sorted song
+	var playlistID, trackID string
+	for _, p := range env.followedPlaylists() {
+		if len(p.TrackIDs) > 0 && p.Name != "Uncategorized" {
+			playlistID, trackID = p.ID, p.TrackIDs[0]
+			break
+		}
+	}
+	env.server.Unlike(trackID)
+
+	analyze := func() *LibraryAnalysis {
+		t.Helper()
+		analysis, err := env.library.AnalyzeLibrary(ctx, env.client, env.userID, AnalyzeOptions{})
+		if err != nil {
+			t.Fatalf("analyze library: %v", err)
+		}
+		return analysis
+	}
+
+	analysis := analyze()
+	for _, track := range analysis.Tracks {
+		if track.ID == trackID {
+			t.Fatalf("unliked track %s is still among the liked songs", trackID)
+		}
+	}
+	assertUnliked(t, analysis.UnlikedTracks, trackID, playlistID)
+
+	// The next analysis syncs incrementally and still knows about it
+	analysis = analyze()
+	assertUnliked(t, analysis.UnlikedTracks, trackID, playlistID)
+
+	plan, err := env.sorter.GenerateSortPlan(ctx, analysis, env.userID, false, nil)
+	if err != nil {
+		t.Fatalf("generate sort plan: %v", err)
+	}
+	if len(plan.TracksToRemove) != 1 {
+		t.Fatalf("plan has %d removals, want 1", len(plan.TracksToRemove))
+	}
+	removal := plan.TracksToRemove[0]
+	if removal.TrackID != trackID || removal.FromPlaylist != playlistID || !removal.Approved {
+		t.Errorf("plan removes %s from %s (approved %t), want %s from %s",
+			removal.TrackID, removal.FromPlaylist, removal.Approved, trackID, playlistID)
+	}
+
+	env.execute(t, plan)
+	for _, id := range env.followedPlaylists()[playlistID].TrackIDs {
+		if id == trackID {
+			t.Errorf("playlist still holds unliked track %s", trackID)
+		}
+	}
+
+	// Once out of its playlists it is forgotten
+	if unliked := analyze().UnlikedTracks; len(unliked) != 0 {
+		t.Errorf("analysis reports %d unliked tracks after the sort, want 0", len(unliked))
+	}
+}
+
+// assertUnliked fails the test unless the unliked tracks are exactly the given one, held by the given playlist
+func assertUnliked(t *testing.T, unliked []domain.Track, trackID, playlistID string) {
+	t.Helper()
+
+	if len(unliked) != 1 || unliked[0].ID != trackID {
+		t.Fatalf("unliked tracks = %v, want only %s", unliked, trackID)
+	}
+	if got := unliked[0].InPlaylists; len(got) != 1 || got[0] != playlistID {
+		t.Errorf("unliked track is in playlists %v, want [%s]", got, playlistID)
+	}
+}
+
+func TestAnalyzeFetchesOnlyChangedPlaylists(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	ctx := context.Background()
+
+	env.execute(t, env.plan(t, nil))
+
+	// requests returns how many requests an analysis made
+	requests := func(opts AnalyzeOptions) int64 {
+		t.Helper()
+		start := env.server.Requests()
+		if _, err := env.library.AnalyzeLibrary(ctx, env.client, env.userID, opts); err != nil {
+			t.Fatalf("analyze library: %v", err)
+		}
+		return env.server.Requests() - start
+	}
+
+	requests(AnalyzeOptions{})
+	unchanged := requests(AnalyzeOptions{})
+
+	// Changing a playlist gives it a new snapshot ID, only that playlist is fetched again
+	var playlistID, trackID string
+	for _, p := range env.followedPlaylists() {
+		if len(p.TrackIDs) > 1 && p.Name != "Uncategorized" {
+			playlistID, trackID = p.ID, p.TrackIDs[0]
+			break
+		}
+	}
+	if err := env.spotify.RemoveTracksFromPlaylist(ctx, env.client, playlistID, []spotify.ID{spotify.ID(trackID)}); err != nil {
+		t.Fatal(err)
+	}
+	if changed := requests(AnalyzeOptions{}); changed != unchanged+1 {
+		t.Errorf("analysis after one playlist changed made %d requests, want %d", changed, unchanged+1)
+	}
+
+	// A full sync fetches every managed playlist
+	if full := requests(AnalyzeOptions{FullSync: true}); full <= unchanged+1 {
+		t.Errorf("full sync made %d requests, want more than %d", full, unchanged+1)
+	}
+}
+
+func TestAnalyzeFailsWhenManagedPlaylistCannotBeFetched(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	ctx := context.Background()
+
+	env.execute(t, env.plan(t, nil))
+
+	var playlistID string
+	for _, p := range env.followedPlaylists() {
+		if len(p.TrackIDs) > 0 {
+			playlistID = p.ID
+			break
+		}
+	}
+	env.server.Fail(http.MethodGet, "/playlists/"+playlistID+"/tracks", http.StatusForbidden, 1)
+	if _, err := env.library.AnalyzeLibrary(ctx, env.client, env.userID, AnalyzeOptions{FullSync: true}); err == nil {
+		t.Fatal("analysis succeeded without the contents of a managed playlist")
+	}
+
+	// Once the playlist can be fetched again, its tracks are not planned a second time
+	again := env.plan(t, nil)
+	if n := len(again.PlaylistsToCreate) + len(again.TracksToAdd) + len(again.TracksToRemove); n > 0 {
+		t.Errorf("sorted library has %d changes planned after a failed fetch", n)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zmb3/spotify/v2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
+)
+
+var (
+	ErrExecutionInProgress = errors.New("execution is still running")
+	ErrAlreadyRolledBack   = errors.New("execution already rolled back")
+	ErrNotResumable        = errors.New("execution cannot be resumed")
+)
+
+// GetExecution returns an execution record with its journal
+func (s *ExecutorService) GetExecution(executionID, userID string) (*domain.Execution, error) {
+	return s.executions.Get(executionID, userID)
+}
+
+// ListExecutions returns the user's most recent executions
+func (s *ExecutorService) ListExecutions(userID string, limit int) ([]*domain.Execution, error) {
+	return s.executions.ListByUser(userID, limit)
+}
+
+// RollbackExecution replays an execution journal backwards and reverts every mutation it recorded.
+// Entries that were reverted are marked, so a failed rollback can be retried.
+func (s *ExecutorService) RollbackExecution(ctx context.Context, client spotifyClient.Conn, executionID, userID string) (*domain.RollbackResult, error) {
+	exec, err := s.executions.Get(executionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, running := s.active.LoadOrStore(executionID, struct{}{}); running || exec.Status == domain.ExecutionRunning {
+		if !running {
+			s.active.Delete(executionID)
+		}
+		return nil, ErrExecutionInProgress
+	}
+	defer s.active.Delete(executionID)
+
+	switch exec.Status {
+	case domain.ExecutionRolledBack:
+		return nil, ErrAlreadyRolledBack
+	}
+
+	log.Info().Str("executionID", executionID).Str("userID", userID).Int("entries", len(exec.Journal)).Msg("Rolling back execution")
+
+	result := &domain.RollbackResult{
+		ExecutionID: executionID,
+		Success:     true,
+		Errors:      []domain.ExecutionError{},
+	}
+
+	// Playlists created by this execution are unfollowed as a whole,
+	// so the tracks added to them don't need to be removed first
+	createdPlaylists := make(map[string]bool)
+	for _, entry := range exec.Journal {
+		if entry.Operation == domain.JournalPlaylistCreated && !entry.RolledBack {
+			createdPlaylists[entry.PlaylistID] = true
+		}
+	}
+
+	total := len(exec.Journal)
+	for i := total - 1; i >= 0; i-- {
+		entry := exec.Journal[i]
+		if entry.RolledBack {
+			continue
+		}
+
+		// Stop between entries once cancelled, the remaining entries can be reverted by retrying
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, domain.ExecutionError{
+				Operation: "rollback",
+				Error:     err.Error(),
+			})
+			break
+		}
+
+		s.broadcaster.SendProgress(ctx, userID, sse.PhaseRollingBack, total-i, total,
+			fmt.Sprintf("Reverting %s on %s...", entry.Operation, entry.PlaylistName))
+
+		if err := s.revertJournalEntry(ctx, client, userID, entry, createdPlaylists, result); err != nil {
+			log.Error().Err(err).Str("executionID", executionID).Int("seq", entry.Seq).Msg("Failed to revert journal entry")
+			result.Errors = append(result.Errors, domain.ExecutionError{
+				Operation: "revert_" + string(entry.Operation),
+				Playlist:  entry.PlaylistID,
+				Error:     err.Error(),
+			})
+			continue
+		}
+
+		result.EntriesReverted++
+		if err := s.executions.MarkReverted(executionID, entry.Seq); err != nil {
+			log.Error().Err(err).Str("executionID", executionID).Msg("Failed to mark journal entry as reverted")
+		}
+	}
+
+	status := domain.ExecutionRolledBack
+	if len(result.Errors) > 0 {
+		result.Success = false
+		status = domain.ExecutionRollbackFailed
+	}
+
+	if err := s.executions.Update(executionID, func(e *domain.Execution) {
+		now := time.Now()
+		e.Status = status
+		e.RolledBackAt = &now
+	}); err != nil {
+		log.Error().Err(err).Str("executionID", executionID).Msg("Failed to update execution status")
+	}
+
+	s.broadcaster.SendComplete(ctx, userID, fmt.Sprintf("Rollback complete! Removed %d playlists, restored %d playlists, renamed %d playlists back, restored %d tracks, removed %d tracks",
+		result.PlaylistsRemoved, result.PlaylistsRestored, result.PlaylistsRenamed, result.TracksRestored, result.TracksRemoved))
+
+	log.Info().
+		Str("executionID", executionID).
+		Int("entriesReverted", result.EntriesReverted).
+		Int("errors", len(result.Errors)).
+		Msg("Execution rollback complete")
+
+	return result, nil
+}
+
+// revertJournalEntry applies the inverse of a single journaled mutation
+func (s *ExecutorService) revertJournalEntry(ctx context.Context, client spotifyClient.Conn, userID string, entry domain.JournalEntry, createdPlaylists map[string]bool, result *domain.RollbackResult) error {
+	switch entry.Operation {
+	case domain.JournalPlaylistCreated:
+		if err := s.spotifyClient.DeletePlaylist(ctx, client, entry.PlaylistID); err != nil {
+			return err
+		}
+		result.PlaylistsRemoved++
+
+	case domain.JournalTracksAdded:
+		if createdPlaylists[entry.PlaylistID] {
+			return nil
+		}
+		if err := s.spotifyClient.RemoveTracksFromPlaylist(ctx, client, entry.PlaylistID, spotifyIDs(entry.TrackIDs)); err != nil {
+			return err
+		}
+		result.TracksRemoved += len(entry.TrackIDs)
+
+	case domain.JournalTracksRemoved:
+		restored, err := s.restoreTracks(ctx, client, entry)
+		result.TracksRestored += restored
+		if err != nil {
+			return err
+		}
+
+	case domain.JournalPlaylistUnfollowed:
+		if err := s.spotifyClient.FollowPlaylist(ctx, client, entry.PlaylistID); err != nil {
+			return err
+		}
+		result.PlaylistsRestored++
+
+	case domain.JournalPlaylistRenamed:
+		if err := s.spotifyClient.ChangePlaylistDetails(ctx, client, entry.PlaylistID, entry.PreviousName, entry.PreviousDescription); err != nil {
+			return err
+		}
+		// The next analysis registers the playlist under the genre of its restored marker or name again
+		s.libraryService.unregisterPlaylist(userID, entry.PlaylistID)
+		result.PlaylistsRenamed++
+
+	default:
+		return fmt.Errorf("unknown journal operation %q", entry.Operation)
+	}
+
+	return nil
+}
+
+// restoreTracks puts removed tracks back into their playlist. Tracks with a journaled position are inserted
+// there in ascending order, so each insert lands where the track was before the removal. Tracks that were not
+// in the playlist are left out, and so are tracks already back in it, so a failed rollback can be retried.
+// Entries journaled without positions are appended.
+func (s *ExecutorService) restoreTracks(ctx context.Context, client spotifyClient.Conn, entry domain.JournalEntry) (int, error) {
+	current, err := s.spotifyClient.FetchPlaylistTracks(ctx, client, entry.PlaylistID)
+	if err != nil {
+		return 0, err
+	}
+	present := make(map[string]bool, len(current))
+	for _, id := range current {
+		present[id] = true
+	}
+
+	type placedTrack struct {
+		id       spotify.ID
+		position int
+	}
+	var placed []placedTrack
+	var appended []spotify.ID
+	positioned := len(entry.Positions) == len(entry.TrackIDs)
+	for i, id := range entry.TrackIDs {
+		switch {
+		case present[id]:
+		case !positioned:
+			appended = append(appended, spotify.ID(id))
+		case entry.Positions[i] >= 0:
+			placed = append(placed, placedTrack{spotify.ID(id), entry.Positions[i]})
+		}
+	}
+	sort.Slice(placed, func(i, j int) bool { return placed[i].position < placed[j].position })
+
+	restored := 0
+	length := len(current)
+	for start := 0; start < len(placed); {
+		// Tracks that were next to each other go back in one insert
+		end := start + 1
+		for end < len(placed) && placed[end].position == placed[end-1].position+1 {
+			end++
+		}
+		ids := make([]spotify.ID, 0, end-start)
+		for _, track := range placed[start:end] {
+			ids = append(ids, track.id)
+		}
+
+		if err := s.spotifyClient.InsertTracksIntoPlaylist(ctx, client, entry.PlaylistID, ids, placed[start].position, length); err != nil {
+			return restored, err
+		}
+		restored += len(ids)
+		length += len(ids)
+		start = end
+	}
+
+	if len(appended) > 0 {
+		if err := s.spotifyClient.AddTracksToPlaylist(ctx, client, entry.PlaylistID, appended); err != nil {
+			return restored, err
+		}
+		restored += len(appended)
+	}
+
+	return restored, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+)
+
+func TestRollbackRestoresLibrary(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+
+	// A managed playlist holding songs of several genres, the sort removes those that are not jazz
+	original := env.server.LikedTrackIDs()[:10]
+	jazzID := env.server.AddPlaylist("jazz", domain.ManagedDescription("Automatically organized jazz tracks", "jazz"), original)
+
+	plan := env.plan(t, nil)
+	removed := 0
+	for _, move := range plan.TracksToRemove {
+		if move.FromPlaylist == jazzID {
+			removed++
+		}
+	}
+	if removed == 0 || removed == len(original) {
+		t.Fatalf("plan removes %d of %d songs from the jazz playlist, want some", removed, len(original))
+	}
+
+	result := env.execute(t, plan)
+
+	rollback, err := env.executor.RollbackExecution(context.Background(), env.client, result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rollback.Success {
+		t.Fatalf("rollback failed: %+v", rollback.Errors)
+	}
+	if rollback.TracksRestored != removed {
+		t.Errorf("restored %d tracks, want %d", rollback.TracksRestored, removed)
+	}
+
+	// Only the jazz playlist is left, with its songs in their original order
+	followed := env.followedPlaylists()
+	if len(followed) != 1 {
+		t.Errorf("%d playlists followed after rollback, want 1", len(followed))
+	}
+	if got := followed[jazzID].TrackIDs; !slices.Equal(got, original) {
+		t.Errorf("jazz playlist after rollback = %v, want %v", got, original)
+	}
+
+	exec, err := env.executor.GetExecution(result.ExecutionID, env.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.Status != domain.ExecutionRolledBack {
+		t.Errorf("status = %s, want rolled_back", exec.Status)
+	}
+	if _, err := env.executor.RollbackExecution(context.Background(), env.client, result.ExecutionID, env.userID); err != ErrAlreadyRolledBack {
+		t.Errorf("second rollback: err = %v, want ErrAlreadyRolledBack", err)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/artistcache"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/execution"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/genreregistry"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/library"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/playlistcache"
+	spotifyClient "github.com/adelvecchio/spotify-playlist-sorter/internal/spotify"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/sse"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// testEnv wires the services to a fake Spotify Web API
+type testEnv struct {
+	server   *fake.Server
+	spotify  *spotifyClient.Client
+	client   spotifyClient.Conn
+	userID   string
+	library  *LibraryService
+	sorter   *SorterService
+	executor *ExecutorService
+}
+
+func newTestEnv(t *testing.T, opts fake.Options) *testEnv {
+	t.Helper()
+
+	server := fake.NewServer(opts)
+	t.Cleanup(server.Close)
+
+	sc := spotifyClient.NewClient("test", "test", "http://localhost/callback", false, spotifyClient.RateLimits{
+		Rate:        1000,
+		Burst:       100,
+		UserRate:    1000,
+		UserBurst:   100,
+		Concurrency: 4,
+	})
+	sc.SetBaseURL(server.BaseURL())
+
+	broadcaster := sse.NewBroadcaster()
+	libraryService := NewLibraryService(sc, broadcaster,
+		artistcache.NewMemoryStore(artistcache.DefaultTTL),
+		library.NewMemoryStore(),
+		playlistcache.NewMemoryStore(),
+		genreregistry.NewMemoryStore(),
+	)
+
+	return &testEnv{
+		server:   server,
+		spotify:  sc,
+		client:   sc.NewSpotifyClient(context.Background(), &oauth2.Token{AccessToken: "test"}),
+		userID:   server.UserID(),
+		library:  libraryService,
+		sorter:   NewSorterService(libraryService),
+		executor: NewExecutorService(sc, libraryService, broadcaster, execution.NewMemoryStore()),
+	}
+}
+
+// plan analyzes the library and generates a sort plan for it
+func (e *testEnv) plan(t *testing.T, enabledGroups map[string]bool) *domain.SortPlan {
+	t.Helper()
+
+	ctx := context.Background()
+	analysis, err := e.library.AnalyzeLibrary(ctx, e.client, e.userID, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("analyze library: %v", err)
+	}
+	plan, err := e.sorter.GenerateSortPlan(ctx, analysis, e.userID, false, enabledGroups)
+	if err != nil {
+		t.Fatalf("generate sort plan: %v", err)
+	}
+	return plan
+}
+
+// execute executes a plan and fails the test when the execution reports errors
+func (e *testEnv) execute(t *testing.T, plan *domain.SortPlan) *domain.ExecutionResult {
+	t.Helper()
+
+	result, err := e.executor.ExecuteSortPlan(context.Background(), e.client, plan, e.userID)
+	if err != nil {
+		t.Fatalf("execute sort plan: %v", err)
+	}
+	if !result.Success || len(result.Errors) > 0 {
+		t.Fatalf("execution failed: %+v", result.Errors)
+	}
+	return result
+}
+
+// followedPlaylists returns the playlists the user still follows, by ID
+func (e *testEnv) followedPlaylists() map[string]fake.Playlist {
+	followed := make(map[string]fake.Playlist)
+	for _, p := range e.server.Playlists() {
+		if p.Followed {
+			followed[p.ID] = p
+		}
+	}
+	return followed
+}
+
+// assertNoDuplicates fails the test when a followed playlist holds a track more than once
+func (e *testEnv) assertNoDuplicates(t *testing.T) {
+	t.Helper()
+
+	for _, p := range e.followedPlaylists() {
+		seen := make(map[string]bool, len(p.TrackIDs))
+		for _, id := range p.TrackIDs {
+			if seen[id] {
+				t.Errorf("playlist %q holds track %s more than once", p.Name, id)
+			}
+			seen[id] = true
+		}
+	}
+}
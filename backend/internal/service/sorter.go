@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,21 +30,37 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 	log.Info().Str("userID", userID).Bool("dryRun", dryRun).Int("enabledGroups", len(enabledGroups)).Msg("Generating sort plan")
 
 	plan := &domain.SortPlan{
-		ID:                  uuid.New().String(),
-		CreatedAt:           time.Now(),
-		DryRun:              dryRun,
-		TotalLikedTracks:    len(analysis.Tracks),
-		TracksToAdd:         []domain.TrackMove{},
-		TracksToRemove:      []domain.TrackMove{},
-		PlaylistsToCreate:   []string{},
-		UncategorizedTracks: []domain.Track{},
-		GenreStats:          []domain.GenreStat{},
-		EnabledGroups:       enabledGroups,
+		ID:                    uuid.New().String(),
+		CreatedAt:             time.Now(),
+		DryRun:                dryRun,
+		TotalLikedTracks:      len(analysis.Tracks),
+		TracksToAdd:           []domain.TrackMove{},
+		TracksToRemove:        []domain.TrackMove{},
+		PlaylistsToCreate:     []string{},
+		PlaylistMerges:        []domain.PlaylistMerge{},
+		GroupMerges:           []domain.GroupMerge{},
+		UncategorizedTracks:   []domain.Track{},
+		GenreStats:            []domain.GenreStat{},
+		EnabledGroups:         enabledGroups,
+		LibraryFingerprint:    analysis.Fingerprint,
+		RejectedPlaylists:     map[string]bool{},
+		RejectedUncategorized: map[string]bool{},
 	}
 
 	// Build genre to playlist mapping (with grouping awareness)
 	genreToPlaylist := s.libraryService.BuildGenreToPlaylistMap(analysis.Playlists, userID, enabledGroups)
 
+	// Playlists of child genres are consolidated into one playlist per enabled group. Songs of the group go
+	// to an existing playlist of the parent genre. A child playlist only becomes the group's playlist once the
+	// rename is approved and succeeds, so for those the group's playlist is planned as a new one: the executor
+	// resolves it to the renamed child, or creates it when the rename is rejected or fails.
+	plan.GroupMerges = s.planGroupMerges(analysis, userID, enabledGroups)
+	for _, merge := range plan.GroupMerges {
+		if !merge.Rename {
+			genreToPlaylist[genre.NormalizeGenre(merge.ParentGenre)] = &domain.Playlist{ID: merge.TargetID, Name: merge.TargetName}
+		}
+	}
+
 	// Track which genres need new playlists
 	neededGenres := make(map[string]bool)
 
@@ -102,6 +119,7 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 			}
 
 			plan.TracksToAdd = append(plan.TracksToAdd, domain.TrackMove{
+				ID:               "add:" + track.ID,
 				TrackID:          track.ID,
 				TrackName:        track.Name,
 				ArtistName:       artistName,
@@ -112,6 +130,7 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 				ToPlaylist:       toPlaylistID,
 				ToPlaylistName:   toPlaylistName,
 				Reason:           reason,
+				Approved:         true,
 			})
 		}
 
@@ -149,6 +168,7 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 				}
 
 				plan.TracksToRemove = append(plan.TracksToRemove, domain.TrackMove{
+					ID:               "remove:" + track.ID + ":" + playlist.ID,
 					TrackID:          track.ID,
 					TrackName:        track.Name,
 					ArtistName:       artistName,
@@ -159,16 +179,42 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 					ToPlaylist:       "",
 					ToPlaylistName:   "",
 					Reason:           fmt.Sprintf("Song genre (%s) doesn't match playlist (%s)", trackEffectiveGenre, playlistEffectiveGenre),
+					Approved:         true,
 				})
 			}
 		}
 	}
 
+	// Unliked songs leave the managed playlists that still hold them
+	for _, track := range analysis.UnlikedTracks {
+		artistName := ""
+		if len(track.Artists) > 0 {
+			artistName = track.Artists[0].Name
+		}
+
+		for _, playlistID := range track.InPlaylists {
+			plan.TracksToRemove = append(plan.TracksToRemove, domain.TrackMove{
+				ID:               "remove:" + track.ID + ":" + playlistID,
+				TrackID:          track.ID,
+				TrackName:        track.Name,
+				ArtistName:       artistName,
+				AlbumImage:       track.AlbumImage,
+				FromPlaylist:     playlistID,
+				FromPlaylistName: playlistNames[playlistID],
+				Reason:           "Song is no longer liked",
+				Approved:         true,
+			})
+		}
+	}
+
 	// Add needed genres to playlists to create
 	for genreName := range neededGenres {
 		plan.PlaylistsToCreate = append(plan.PlaylistsToCreate, genreName)
 	}
 
+	// Consolidate managed playlists that share a genre
+	plan.PlaylistMerges = s.planPlaylistMerges(analysis, userID, enabledGroups, plan.GroupMerges)
+
 	// Generate genre statistics
 	genreCounts := make(map[string]int)
 	for _, track := range analysis.Tracks {
@@ -199,11 +245,187 @@ func (s *SorterService) GenerateSortPlan(ctx context.Context, analysis *LibraryA
 		Int("tracksToRemove", len(plan.TracksToRemove)).
 		Int("playlistsToCreate", len(plan.PlaylistsToCreate)).
 		Int("uncategorized", len(plan.UncategorizedTracks)).
+		Int("playlistMerges", len(plan.PlaylistMerges)).
+		Int("groupMerges", len(plan.GroupMerges)).
 		Msg("Sort plan generated")
 
 	return plan, nil
 }
 
+// planPlaylistMerges plans a merge for every genre with more than one managed playlist. The tracks of the
+// duplicates that are missing from the survivor move to it, except liked songs of another genre, those
+// are added to their own playlist by the plan and would only be removed from the survivor again.
+// Duplicates of genres in a group merge are consolidated by the group merge.
+func (s *SorterService) planPlaylistMerges(analysis *LibraryAnalysis, userID string, enabledGroups map[string]bool, groupMerges []domain.GroupMerge) []domain.PlaylistMerge {
+	trackGenres := effectiveTrackGenres(analysis, enabledGroups)
+
+	merging := make(map[string]bool)
+	for _, merge := range groupMerges {
+		merging[merge.TargetID] = true
+		for _, child := range merge.Children {
+			merging[child.ID] = true
+		}
+	}
+
+	merges := []domain.PlaylistMerge{}
+	groups := s.libraryService.managedPlaylistsByGenre(analysis.Playlists, userID)
+	for _, genreKey := range sortedKeys(groups) {
+		group := groups[genreKey]
+		if len(group) < 2 || merging[group[0].ID] {
+			continue
+		}
+
+		survivor := group[0]
+		seen := make(map[string]bool, len(survivor.TrackIDs))
+		for _, id := range survivor.TrackIDs {
+			seen[id] = true
+		}
+
+		merge := domain.PlaylistMerge{
+			ID:           "merge:" + genreKey,
+			Genre:        genreKey,
+			SurvivorID:   survivor.ID,
+			SurvivorName: survivor.Name,
+			Duplicates:   []domain.MergedPlaylist{},
+			TrackIDs:     []string{},
+			Approved:     true,
+		}
+		for _, duplicate := range group[1:] {
+			merge.Duplicates = append(merge.Duplicates, domain.MergedPlaylist{
+				ID:         duplicate.ID,
+				Name:       duplicate.Name,
+				TrackCount: duplicate.TrackCount,
+			})
+			for _, id := range duplicate.TrackIDs {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if trackGenre, liked := trackGenres[id]; liked && trackGenre != genreKey {
+					continue
+				}
+				merge.TrackIDs = append(merge.TrackIDs, id)
+			}
+		}
+
+		log.Info().
+			Str("genre", genreKey).
+			Str("survivorID", survivor.ID).
+			Int("duplicates", len(merge.Duplicates)).
+			Int("tracks", len(merge.TrackIDs)).
+			Msg("Found duplicate managed playlists")
+		merges = append(merges, merge)
+	}
+
+	return merges
+}
+
+// planGroupMerges plans a merge for every enabled group that has managed playlists of its child genres.
+// The target is the playlist of the parent genre when there is one, otherwise the child playlist with
+// the most tracks, renamed to the parent genre. Every other playlist of the group is a child whose tracks
+// move to the target, leaving out liked songs that belong to another genre.
+func (s *SorterService) planGroupMerges(analysis *LibraryAnalysis, userID string, enabledGroups map[string]bool) []domain.GroupMerge {
+	if len(enabledGroups) == 0 {
+		return []domain.GroupMerge{}
+	}
+
+	// Parent genre key -> playlists of the parent genre and of its children
+	parents := make(map[string][]*domain.Playlist)
+	children := make(map[string][]*domain.Playlist)
+	parentNames := make(map[string]string)
+	byGenre := s.libraryService.managedPlaylistsByGenre(analysis.Playlists, userID)
+	for _, genreKey := range sortedKeys(byGenre) {
+		group := byGenre[genreKey]
+		parentName := genre.ApplyGrouping(genreKey, enabledGroups)
+		parentKey := genre.NormalizeGenre(parentName)
+		if parentKey == genreKey {
+			parents[parentKey] = group
+			continue
+		}
+		parentNames[parentKey] = parentName
+		children[parentKey] = append(children[parentKey], group...)
+	}
+
+	trackGenres := effectiveTrackGenres(analysis, enabledGroups)
+
+	merges := []domain.GroupMerge{}
+	for _, parentKey := range sortedKeys(children) {
+		// Existing playlists of the parent genre come first, then the largest children
+		candidates := append(append([]*domain.Playlist{}, parents[parentKey]...), children[parentKey]...)
+		existing := len(parents[parentKey]) > 0
+		if !existing {
+			sort.SliceStable(candidates, func(i, j int) bool {
+				if candidates[i].TrackCount != candidates[j].TrackCount {
+					return candidates[i].TrackCount > candidates[j].TrackCount
+				}
+				return candidates[i].ID < candidates[j].ID
+			})
+		}
+
+		target := candidates[0]
+		seen := make(map[string]bool, len(target.TrackIDs))
+		for _, id := range target.TrackIDs {
+			seen[id] = true
+		}
+
+		merge := domain.GroupMerge{
+			ID:                "group:" + parentKey,
+			ParentGenre:       parentNames[parentKey],
+			TargetID:          target.ID,
+			TargetName:        target.Name,
+			TargetDescription: target.Description,
+			Rename:            !existing,
+			Children:          []domain.MergedPlaylist{},
+			TrackIDs:          []string{},
+			Approved:          true,
+		}
+		for _, child := range candidates[1:] {
+			merge.Children = append(merge.Children, domain.MergedPlaylist{
+				ID:         child.ID,
+				Name:       child.Name,
+				Genre:      genre.NormalizeGenre(child.AssignedGenre),
+				TrackCount: child.TrackCount,
+			})
+			for _, id := range child.TrackIDs {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if trackGenre, liked := trackGenres[id]; liked && trackGenre != parentKey {
+					continue
+				}
+				merge.TrackIDs = append(merge.TrackIDs, id)
+			}
+		}
+
+		log.Info().
+			Str("group", merge.ParentGenre).
+			Str("targetID", target.ID).
+			Bool("rename", merge.Rename).
+			Int("children", len(merge.Children)).
+			Int("tracks", len(merge.TrackIDs)).
+			Msg("Found playlists to merge into group")
+		merges = append(merges, merge)
+	}
+
+	return merges
+}
+
+// effectiveTrackGenres returns the normalized genre of every liked song with a genre, after grouping.
+// Unliked songs map to no genre, so merges leave them out; the plan removes them from their playlists.
+func effectiveTrackGenres(analysis *LibraryAnalysis, enabledGroups map[string]bool) map[string]string {
+	trackGenres := make(map[string]string)
+	for _, track := range analysis.Tracks {
+		if track.PrimaryGenre != "" {
+			trackGenres[track.ID] = genre.NormalizeGenre(genre.ApplyGrouping(track.PrimaryGenre, enabledGroups))
+		}
+	}
+	for _, track := range analysis.UnlikedTracks {
+		trackGenres[track.ID] = ""
+	}
+	return trackGenres
+}
+
 // ValidateSortPlan checks if a sort plan is valid
 func (s *SorterService) ValidateSortPlan(plan *domain.SortPlan) error {
 	if plan == nil {
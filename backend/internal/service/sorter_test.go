@@ -0,0 +1,237 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+)
+
+var rockGroup = map[string]bool{"Rock": true}
+
+// sortedWithRockChildren returns a sorted library with playlists for child genres of Rock,
+// and the plan that consolidates them into a Rock playlist by renaming one of them
+func sortedWithRockChildren(t *testing.T) (*testEnv, *domain.SortPlan) {
+	t.Helper()
+
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	env.server.AddPlaylist("modern rock", domain.ManagedDescription("Automatically organized modern rock tracks", "modern rock"), []string{"offlinerock"})
+	env.execute(t, env.plan(t, nil))
+
+	plan := env.plan(t, rockGroup)
+	if len(plan.GroupMerges) != 1 {
+		t.Fatalf("plan has %d group merges, want 1", len(plan.GroupMerges))
+	}
+	merge := plan.GroupMerges[0]
+	if !merge.Rename || len(merge.Children) == 0 {
+		t.Fatalf("group merge = %+v, want a rename with children", merge)
+	}
+	if !slices.Contains(plan.PlaylistsToCreate, "Rock") {
+		t.Fatal("Rock is not planned as the fallback of the rename")
+	}
+	return env, plan
+}
+
+// assertSorted fails the test when sorting the library again would change anything
+func (e *testEnv) assertSorted(t *testing.T, enabledGroups map[string]bool) {
+	t.Helper()
+
+	plan := e.plan(t, enabledGroups)
+	if n := len(plan.PlaylistsToCreate) + len(plan.TracksToAdd) + len(plan.TracksToRemove) + len(plan.PlaylistMerges) + len(plan.GroupMerges); n > 0 {
+		t.Errorf("sorted library still has %d changes planned: create %v, %d additions, %d removals, %d merges, %d group merges",
+			n, plan.PlaylistsToCreate, len(plan.TracksToAdd), len(plan.TracksToRemove), len(plan.PlaylistMerges), len(plan.GroupMerges))
+	}
+}
+
+// rockPlaylists returns the followed playlists named Rock
+func (e *testEnv) rockPlaylists() []fake.Playlist {
+	var rock []fake.Playlist
+	for _, p := range e.followedPlaylists() {
+		if p.Name == "Rock" {
+			rock = append(rock, p)
+		}
+	}
+	return rock
+}
+
+func TestGroupMergeRenamesChild(t *testing.T) {
+	env, plan := sortedWithRockChildren(t)
+	merge := plan.GroupMerges[0]
+
+	result := env.execute(t, plan)
+	if result.GroupsMerged != 1 || result.PlaylistsCreated != 0 {
+		t.Errorf("merged %d groups and created %d playlists, want 1 and 0", result.GroupsMerged, result.PlaylistsCreated)
+	}
+
+	rock := env.rockPlaylists()
+	if len(rock) != 1 || rock[0].ID != merge.TargetID {
+		t.Fatalf("Rock playlists = %v, want the renamed %s", rock, merge.TargetID)
+	}
+	if !slices.Contains(rock[0].TrackIDs, "offlinerock") {
+		t.Error("Rock playlist does not hold the songs of the merged children")
+	}
+	for _, child := range merge.Children {
+		if _, followed := env.followedPlaylists()[child.ID]; followed {
+			t.Errorf("child %q is still followed", child.Name)
+		}
+	}
+	env.assertNoDuplicates(t)
+	env.assertSorted(t, rockGroup)
+}
+
+func TestGroupMergeRejectedCreatesParent(t *testing.T) {
+	env, plan := sortedWithRockChildren(t)
+	merge := plan.GroupMerges[0]
+	plan.GroupMerges[0].Approved = false
+
+	result := env.execute(t, plan)
+	if result.GroupsMerged != 0 {
+		t.Errorf("merged %d groups, want 0", result.GroupsMerged)
+	}
+
+	// The songs of the group go to a new Rock playlist, the children are left alone
+	rock := env.rockPlaylists()
+	if len(rock) != 1 || rock[0].ID == merge.TargetID {
+		t.Fatalf("Rock playlists = %v, want a new one", rock)
+	}
+	target, followed := env.followedPlaylists()[merge.TargetID]
+	if !followed || target.Name != merge.TargetName {
+		t.Errorf("child %q was renamed or unfollowed", merge.TargetName)
+	}
+	env.assertNoDuplicates(t)
+}
+
+func TestCreatedParentKeptWhenAdditionsRejected(t *testing.T) {
+	env, plan := sortedWithRockChildren(t)
+	plan.GroupMerges[0].Approved = false
+	for i := range plan.TracksToAdd {
+		if plan.TracksToAdd[i].ToPlaylistName == "Rock" {
+			plan.TracksToAdd[i].Approved = false
+		}
+	}
+
+	// The Rock playlist stays empty, it was just created and is not cleaned up as a leftover
+	result := env.execute(t, plan)
+	if result.PlaylistsCreated != 1 || result.PlaylistsDeleted != 0 {
+		t.Errorf("created %d playlists and deleted %d, want 1 and 0", result.PlaylistsCreated, result.PlaylistsDeleted)
+	}
+	if rock := env.rockPlaylists(); len(rock) != 1 || len(rock[0].TrackIDs) != 0 {
+		t.Errorf("Rock playlists = %v, want the new empty one", rock)
+	}
+}
+
+func TestGroupMergeFailedRenameFallsBackToParent(t *testing.T) {
+	env, plan := sortedWithRockChildren(t)
+	merge := plan.GroupMerges[0]
+	env.server.Fail(http.MethodPut, "/playlists/"+merge.TargetID, http.StatusForbidden, 1)
+
+	result := env.execute(t, plan)
+	if result.GroupsMerged != 1 || result.PlaylistsCreated != 1 {
+		t.Errorf("merged %d groups and created %d playlists, want 1 and 1", result.GroupsMerged, result.PlaylistsCreated)
+	}
+
+	// The group's playlist was created and the child that was to be renamed merged into it
+	rock := env.rockPlaylists()
+	if len(rock) != 1 || rock[0].ID == merge.TargetID {
+		t.Fatalf("Rock playlists = %v, want a new one", rock)
+	}
+	if _, followed := env.followedPlaylists()[merge.TargetID]; followed {
+		t.Errorf("child %q is still followed", merge.TargetName)
+	}
+	if !slices.Contains(rock[0].TrackIDs, "offlinerock") {
+		t.Error("Rock playlist does not hold the songs of the merged children")
+	}
+	env.assertNoDuplicates(t)
+	env.assertSorted(t, rockGroup)
+}
+
+func TestPlaylistMergeConsolidatesDuplicates(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	liked := env.server.LikedTrackIDs()
+	survivorID := env.server.AddPlaylist("jazz", domain.ManagedDescription("Automatically organized jazz tracks", "jazz"), liked[:2])
+	duplicateID := env.server.AddPlaylist("Jazz", domain.ManagedDescription("Automatically organized jazz tracks", "jazz"), []string{"offlineunliked"})
+
+	plan := env.plan(t, nil)
+	if len(plan.PlaylistMerges) != 1 {
+		t.Fatalf("plan has %d playlist merges, want 1", len(plan.PlaylistMerges))
+	}
+	merge := plan.PlaylistMerges[0]
+	if merge.SurvivorID != survivorID || len(merge.Duplicates) != 1 || merge.Duplicates[0].ID != duplicateID {
+		t.Fatalf("merge = %+v, want %s to survive %s", merge, survivorID, duplicateID)
+	}
+	if !slices.Equal(merge.TrackIDs, []string{"offlineunliked"}) {
+		t.Errorf("merge moves %v, want the song only the duplicate holds", merge.TrackIDs)
+	}
+
+	result := env.execute(t, plan)
+	if result.PlaylistsMerged != 1 {
+		t.Errorf("merged %d playlists, want 1", result.PlaylistsMerged)
+	}
+	followed := env.followedPlaylists()
+	if _, ok := followed[duplicateID]; ok {
+		t.Error("duplicate is still followed")
+	}
+	if !slices.Contains(followed[survivorID].TrackIDs, "offlineunliked") {
+		t.Error("survivor does not hold the songs of the duplicate")
+	}
+	env.assertNoDuplicates(t)
+	env.assertSorted(t, nil)
+}
+
+func TestLegacyManagedPlaylistKeepsItsGenre(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+
+	// A song of another genre than the legacy playlist's
+	var wrongTrackID string
+	for _, move := range env.plan(t, nil).TracksToAdd {
+		if move.Genre != "" && move.Genre != "jazz" {
+			wrongTrackID = move.TrackID
+			break
+		}
+	}
+	if wrongTrackID == "" {
+		t.Fatal("no song of a genre other than jazz")
+	}
+
+	// Managed playlists from before genre markers only carry the tag, their name is the genre
+	legacyID := env.server.AddPlaylist("jazz", "Automatically organized jazz tracks "+domain.ManagedTag, []string{wrongTrackID})
+
+	plan := env.plan(t, nil)
+	if slices.Contains(plan.PlaylistsToCreate, "jazz") || slices.Contains(plan.PlaylistsToCreate, "Jazz") {
+		t.Error("plan creates a jazz playlist next to the legacy one")
+	}
+	removed := slices.ContainsFunc(plan.TracksToRemove, func(move domain.TrackMove) bool {
+		return move.TrackID == wrongTrackID && move.FromPlaylist == legacyID
+	})
+	if !removed {
+		t.Fatal("plan does not move the song of another genre out of the legacy playlist")
+	}
+
+	env.execute(t, plan)
+	if slices.Contains(env.followedPlaylists()[legacyID].TrackIDs, wrongTrackID) {
+		t.Error("legacy playlist still holds the song of another genre")
+	}
+	env.assertNoDuplicates(t)
+	env.assertSorted(t, nil)
+}
+
+func TestRenamedPlaylistsKeepTheirGenre(t *testing.T) {
+	env := newTestEnv(t, fake.Options{LikedSongs: 120, Artists: 12})
+	env.execute(t, env.plan(t, nil))
+
+	renamed := 0
+	for _, p := range env.followedPlaylists() {
+		if strings.Contains(p.Description, domain.ManagedTag) {
+			env.server.Rename(p.ID, "Best of "+p.Name+" ✨")
+			renamed++
+		}
+	}
+	if renamed == 0 {
+		t.Fatal("sorted library has no managed playlists")
+	}
+
+	env.assertSorted(t, nil)
+}
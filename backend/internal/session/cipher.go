@@ -0,0 +1,150 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// cipherVersion prefixes encrypted tokens so the format can change later
+const cipherVersion = "v1"
+
+var (
+	ErrNoSecret        = errors.New("session secret is empty")
+	ErrUnknownKey      = errors.New("token was encrypted with an unknown key")
+	ErrInvalidCipher   = errors.New("invalid encrypted token")
+	ErrDecryptionError = errors.New("failed to decrypt token")
+)
+
+// tokenKey is one AES-256-GCM key derived from a secret
+type tokenKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// TokenCipher encrypts OAuth tokens at rest with AES-256-GCM.
+// New values are encrypted with the key derived from the current secret.
+// Keys derived from previous secrets can still decrypt, so secrets can be rotated without logging everyone out.
+type TokenCipher struct {
+	current *tokenKey
+	keys    map[string]*tokenKey // key ID -> key, including the current one
+}
+
+// NewTokenCipher derives encryption keys from the current secret and any previous secrets
+func NewTokenCipher(secret string, previous ...string) (*TokenCipher, error) {
+	current, err := deriveKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &TokenCipher{
+		current: current,
+		keys:    map[string]*tokenKey{current.id: current},
+	}
+
+	for _, s := range previous {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		key, err := deriveKey(s)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[key.id] = key
+	}
+
+	return c, nil
+}
+
+// Encrypt seals a token. The session ID is bound to the ciphertext, so it cannot be moved to another session.
+func (c *TokenCipher) Encrypt(sessionID string, token *oauth2.Token) (string, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	nonce := make([]byte, c.current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.current.aead.Seal(nonce, nonce, plaintext, []byte(sessionID))
+
+	return cipherVersion + ":" + c.current.id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a token sealed by Encrypt. stale is true when the token was encrypted
+// with a previous key and should be encrypted again with the current one.
+func (c *TokenCipher) Decrypt(sessionID, value string) (token *oauth2.Token, stale bool, err error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != cipherVersion {
+		return nil, false, ErrInvalidCipher
+	}
+
+	key, exists := c.keys[parts[1]]
+	if !exists {
+		return nil, false, ErrUnknownKey
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return nil, false, ErrInvalidCipher
+	}
+
+	nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, []byte(sessionID))
+	if err != nil {
+		return nil, false, ErrDecryptionError
+	}
+
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, false, fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	return token, key != c.current, nil
+}
+
+// deriveKey derives an AES-256 key and its public ID from a secret
+func deriveKey(secret string) (*tokenKey, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+
+	keyBytes, err := hkdf.Key(sha256.New, []byte(secret), nil, "session token encryption", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ID is derived separately so it reveals nothing about the key
+	idBytes, err := hkdf.Key(sha256.New, []byte(secret), nil, "session token key id", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenKey{
+		id:   hex.EncodeToString(idBytes),
+		aead: aead,
+	}, nil
+}
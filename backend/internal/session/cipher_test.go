@@ -0,0 +1,153 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func testToken() *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  "access",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour).Round(0),
+	}
+}
+
+func newTestCipher(t *testing.T, secret string, previous ...string) *TokenCipher {
+	t.Helper()
+
+	c, err := NewTokenCipher(secret, previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c := newTestCipher(t, "secret")
+	token := testToken()
+
+	sealed, err := c.Encrypt("session", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, token.AccessToken) || strings.Contains(sealed, token.RefreshToken) {
+		t.Fatalf("sealed token %q contains the plaintext", sealed)
+	}
+
+	again, err := c.Encrypt("session", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("encrypting twice gave the same ciphertext, nonces are reused")
+	}
+
+	opened, stale, err := c.Decrypt("session", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale {
+		t.Error("token sealed with the current key is stale")
+	}
+	if opened.AccessToken != token.AccessToken || opened.RefreshToken != token.RefreshToken || !opened.Expiry.Equal(token.Expiry) {
+		t.Errorf("decrypted %+v, want %+v", opened, token)
+	}
+}
+
+func TestCipherRejectsWrongKey(t *testing.T) {
+	sealed, err := newTestCipher(t, "secret").Encrypt("session", testToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := newTestCipher(t, "other").Decrypt("session", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("decrypting with another secret: error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestCipherRotation(t *testing.T) {
+	old := newTestCipher(t, "old")
+	sealed, err := old.Encrypt("session", testToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestCipher(t, "new", "old")
+	token, stale, err := rotated.Decrypt("session", sealed)
+	if err != nil {
+		t.Fatalf("decrypting with a previous secret: %v", err)
+	}
+	if !stale {
+		t.Error("token sealed with a previous secret is not stale")
+	}
+
+	// Encrypted again it no longer needs the previous secret
+	resealed, err := rotated.Encrypt("session", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, stale, err := newTestCipher(t, "new").Decrypt("session", resealed); err != nil || stale {
+		t.Errorf("re-encrypted token: stale = %t, error = %v", stale, err)
+	}
+
+	// Dropping the previous secret makes old tokens unreadable
+	if _, _, err := newTestCipher(t, "new").Decrypt("session", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("after removing the previous secret: error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestCipherRejectsTampering(t *testing.T) {
+	c := newTestCipher(t, "secret")
+	sealed, err := c.Encrypt("session", testToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.SplitN(sealed, ":", 3)
+	raw, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 1
+	flipped := parts[0] + ":" + parts[1] + ":" + base64.RawStdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		value     string
+		want      error
+	}{
+		{"flipped bit", "session", flipped, ErrDecryptionError},
+		{"other session", "other", sealed, ErrDecryptionError},
+		{"truncated", "session", parts[0] + ":" + parts[1] + ":AAAA", ErrInvalidCipher},
+		{"bad encoding", "session", parts[0] + ":" + parts[1] + ":!!!", ErrInvalidCipher},
+		{"unknown version", "session", "v0:" + parts[1] + ":" + parts[2], ErrInvalidCipher},
+		{"not sealed", "session", "plain", ErrInvalidCipher},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := c.Decrypt(tt.sessionID, tt.value); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTokenCipherRequiresSecret(t *testing.T) {
+	if _, err := NewTokenCipher(""); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("error = %v, want ErrNoSecret", err)
+	}
+
+	// Blank previous secrets are ignored
+	if _, err := NewTokenCipher("secret", "", "  "); err != nil {
+		t.Errorf("blank previous secrets: %v", err)
+	}
+}
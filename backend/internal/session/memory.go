@@ -0,0 +1,215 @@
+package session
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// MemoryStore manages user sessions in memory. Sessions are lost on restart.
+type MemoryStore struct {
+	sessions     map[string]*Session            // sessionID -> Session
+	userSessions map[string]map[string]struct{} // userID -> sessionIDs
+	mu           sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory session store
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		sessions:     make(map[string]*Session),
+		userSessions: make(map[string]map[string]struct{}),
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredSessions()
+
+	return store
+}
+
+// Create creates a new session
+func (s *MemoryStore) Create(sessionID, userID string, token *oauth2.Token, userAgent string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	session := &Session{
+		ID:         sessionID,
+		UserID:     userID,
+		Token:      token,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+
+	s.sessions[sessionID] = session
+	if s.userSessions[userID] == nil {
+		s.userSessions[userID] = make(map[string]struct{})
+	}
+	s.userSessions[userID][sessionID] = struct{}{}
+
+	clone := *session
+	return &clone, nil
+}
+
+// Get retrieves a session by ID. It returns a copy, token refreshes update the stored session while callers use it.
+func (s *MemoryStore) Get(sessionID string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	clone := *session
+	return &clone, nil
+}
+
+// GetByUserID retrieves the user's most recent session
+func (s *MemoryStore) GetByUserID(userID string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *Session
+	for sessionID := range s.userSessions[userID] {
+		session := s.sessions[sessionID]
+		if latest == nil || session.CreatedAt.After(latest.CreatedAt) {
+			latest = session
+		}
+	}
+
+	if latest == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	if time.Now().After(latest.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	clone := *latest
+	return &clone, nil
+}
+
+// ListByUserID returns the user's active sessions, most recently seen first
+func (s *MemoryStore) ListByUserID(userID string) ([]*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	result := []*Session{}
+	for sessionID := range s.userSessions[userID] {
+		session := s.sessions[sessionID]
+		if now.After(session.ExpiresAt) {
+			continue
+		}
+		clone := *session
+		result = append(result, &clone)
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].LastSeenAt.After(result[k].LastSeenAt)
+	})
+
+	return result, nil
+}
+
+// Update updates a session's token
+func (s *MemoryStore) Update(sessionID string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+
+	session.Token = token
+	return nil
+}
+
+// Delete deletes a session
+func (s *MemoryStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(sessionID)
+
+	return nil
+}
+
+// DeleteByUserID deletes all sessions of the user and returns how many there were
+func (s *MemoryStore) DeleteByUserID(userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionIDs := s.userSessions[userID]
+	for sessionID := range sessionIDs {
+		delete(s.sessions, sessionID)
+	}
+	delete(s.userSessions, userID)
+
+	return len(sessionIDs), nil
+}
+
+// Refresh extends the session expiration and records the session as seen
+func (s *MemoryStore) Refresh(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+
+	session.LastSeenAt = time.Now()
+	session.ExpiresAt = session.LastSeenAt.Add(sessionTTL)
+	return nil
+}
+
+// remove deletes a session and its entry in the user index. The caller must hold the lock.
+func (s *MemoryStore) remove(sessionID string) {
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return
+	}
+
+	delete(s.sessions, sessionID)
+	delete(s.userSessions[session.UserID], sessionID)
+	if len(s.userSessions[session.UserID]) == 0 {
+		delete(s.userSessions, session.UserID)
+	}
+}
+
+// cleanupExpiredSessions periodically removes expired sessions
+func (s *MemoryStore) cleanupExpiredSessions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+
+		for sessionID, session := range s.sessions {
+			if now.After(session.ExpiresAt) {
+				s.remove(sessionID)
+			}
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// Count returns the number of active sessions
+func (s *MemoryStore) Count() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.sessions), nil
+}
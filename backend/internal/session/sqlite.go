@@ -0,0 +1,268 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+// sessionColumns are the columns scanSession reads, in order
+const sessionColumns = `id_hash, user_id, token, user_agent, created_at, last_seen_at, expires_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// SQLiteStore keeps user sessions in the embedded database, so logins survive a restart
+// and background work can find a user's tokens. Sessions are stored by the hash of their cookie
+// and tokens are encrypted at rest, a copy of the database does not let anyone log in.
+type SQLiteStore struct {
+	db     *storage.DB
+	cipher *TokenCipher
+}
+
+// NewSQLiteStore creates a new SQLite-backed session store
+func NewSQLiteStore(db *storage.DB, cipher *TokenCipher) *SQLiteStore {
+	store := &SQLiteStore{
+		db:     db,
+		cipher: cipher,
+	}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredSessions()
+
+	return store
+}
+
+// Create creates a new session
+func (s *SQLiteStore) Create(sessionID, userID string, token *oauth2.Token, userAgent string) (*Session, error) {
+	now := time.Now()
+	session := &Session{
+		ID:         sessionID,
+		UserID:     userID,
+		Token:      token,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+
+	tokenData, err := s.cipher.Encrypt(sessionID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO sessions (id_hash, user_id, token, user_agent, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sessionID, userID, tokenData, userAgent, now.UnixNano(), now.UnixNano(), session.ExpiresAt.UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return session, nil
+}
+
+// Get retrieves a session by ID
+func (s *SQLiteStore) Get(sessionID string) (*Session, error) {
+	row := s.db.QueryRow(
+		`SELECT `+sessionColumns+` FROM sessions WHERE id_hash = ?`,
+		sessionID,
+	)
+	return s.loadSession(row)
+}
+
+// GetByUserID retrieves the user's most recent session
+func (s *SQLiteStore) GetByUserID(userID string) (*Session, error) {
+	row := s.db.QueryRow(
+		`SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? ORDER BY created_at DESC LIMIT 1`,
+		userID,
+	)
+	return s.loadSession(row)
+}
+
+// ListByUserID returns the user's active sessions, most recently seen first
+func (s *SQLiteStore) ListByUserID(userID string) ([]*Session, error) {
+	rows, err := s.db.Query(
+		`SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_seen_at DESC`,
+		userID, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	stale := []*Session{}
+	for rows.Next() {
+		session, isStale, err := s.scanSession(rows)
+		if errors.Is(err, ErrUnknownKey) || errors.Is(err, ErrInvalidCipher) || errors.Is(err, ErrDecryptionError) {
+			// A session nobody can use anymore should not hide the others
+			log.Warn().Err(err).Str("userID", userID).Msg("Skipping unreadable session")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+		if isStale {
+			stale = append(stale, session)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Re-encrypt once the rows are released, the database allows a single connection
+	for _, session := range stale {
+		s.reencrypt(session)
+	}
+
+	return sessions, nil
+}
+
+// Update updates a session's token
+func (s *SQLiteStore) Update(sessionID string, token *oauth2.Token) error {
+	tokenData, err := s.cipher.Encrypt(sessionID, token)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`UPDATE sessions SET token = ? WHERE id_hash = ?`, tokenData, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// Delete deletes a session
+func (s *SQLiteStore) Delete(sessionID string) error {
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE id_hash = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUserID deletes all sessions of the user and returns how many there were
+func (s *SQLiteStore) DeleteByUserID(userID string) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	return int(deleted), err
+}
+
+// Refresh extends the session expiration and records the session as seen
+func (s *SQLiteStore) Refresh(sessionID string) error {
+	now := time.Now()
+	res, err := s.db.Exec(
+		`UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id_hash = ?`,
+		now.UnixNano(), now.Add(sessionTTL).UnixNano(), sessionID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// Count returns the number of active sessions
+func (s *SQLiteStore) Count() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE expires_at > ?`, time.Now().UnixNano()).Scan(&count)
+	return count, err
+}
+
+// cleanupExpiredSessions periodically removes expired sessions
+func (s *SQLiteStore) cleanupExpiredSessions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, time.Now().UnixNano()); err != nil {
+			log.Error().Err(err).Msg("Failed to clean up expired sessions")
+		}
+	}
+}
+
+// loadSession scans a single session and re-encrypts its token if needed
+func (s *SQLiteStore) loadSession(row *sql.Row) (*Session, error) {
+	session, stale, err := s.scanSession(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if stale {
+		s.reencrypt(session)
+	}
+
+	return session, nil
+}
+
+// reencrypt stores the session token again with the current key
+func (s *SQLiteStore) reencrypt(session *Session) {
+	if err := s.Update(session.ID, session.Token); err != nil {
+		log.Warn().Err(err).Msg("Failed to re-encrypt session token")
+	}
+}
+
+// scanSession reads a session row, checks its expiration and decrypts its token.
+// stale is true for tokens sealed with a previous key, which should be encrypted again with the current key.
+func (s *SQLiteStore) scanSession(row rowScanner) (*Session, bool, error) {
+	var (
+		session                          Session
+		tokenData                        string
+		createdAt, lastSeenAt, expiresAt int64
+	)
+
+	err := row.Scan(&session.ID, &session.UserID, &tokenData, &session.UserAgent, &createdAt, &lastSeenAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	session.CreatedAt = time.Unix(0, createdAt)
+	session.LastSeenAt = time.Unix(0, lastSeenAt)
+	if lastSeenAt == 0 {
+		// Sessions created before last_seen_at was recorded
+		session.LastSeenAt = session.CreatedAt
+	}
+	session.ExpiresAt = time.Unix(0, expiresAt)
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, false, ErrSessionExpired
+	}
+
+	var stale bool
+	session.Token, stale, err = s.cipher.Decrypt(session.ID, tokenData)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt session token: %w", err)
+	}
+
+	return &session, stale, nil
+}
+
+// requireAffected reports ErrSessionNotFound when a statement matched no session
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/storage"
+)
+
+func openTestDB(t *testing.T) *storage.DB {
+	t.Helper()
+
+	db, err := storage.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteStoreReencryptsWithRotatedSecret(t *testing.T) {
+	db := openTestDB(t)
+
+	old := newTestCipher(t, "old")
+	if _, err := NewSQLiteStore(db, old).Create("session-id", "user", testToken(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestCipher(t, "new", "old")
+	if _, err := NewSQLiteStore(db, rotated).Get("session-id"); err != nil {
+		t.Fatalf("reading a session sealed with the previous secret: %v", err)
+	}
+
+	// Reading it sealed the token again, the previous secret is no longer needed
+	current := newTestCipher(t, "new")
+	sess, err := NewSQLiteStore(db, current).Get("session-id")
+	if err != nil {
+		t.Fatalf("reading the re-encrypted session: %v", err)
+	}
+	if sess.Token.AccessToken != testToken().AccessToken {
+		t.Errorf("token = %+v", sess.Token)
+	}
+}
+
+func TestSQLiteStoreRejectsPlaintextToken(t *testing.T) {
+	db := openTestDB(t)
+	store := NewSQLiteStore(db, newTestCipher(t, "secret"))
+
+	plaintext, err := json.Marshal(testToken())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(
+		`INSERT INTO sessions (id_hash, user_id, token, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		"session-id", "user", string(plaintext), time.Now().UnixNano(), time.Now().Add(time.Hour).UnixNano(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("session-id"); !errors.Is(err, ErrInvalidCipher) {
+		t.Errorf("Get error = %v, want ErrInvalidCipher", err)
+	}
+}
+
+func TestSQLiteStoreKeepsOnlyCookieHash(t *testing.T) {
+	db := openTestDB(t)
+	store := NewSQLiteStore(db, newTestCipher(t, "secret"))
+
+	cookie := "cookie-value"
+	token := testToken()
+	created, err := store.Create(HashID(cookie), "user", token, "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing in the row can be sent as the cookie
+	var id, tokenData string
+	if err := db.QueryRow(`SELECT id_hash, token FROM sessions`).Scan(&id, &tokenData); err != nil {
+		t.Fatal(err)
+	}
+	if id != HashID(cookie) || strings.Contains(id, cookie) {
+		t.Errorf("stored session ID %q, want the hash of the cookie", id)
+	}
+	if strings.Contains(tokenData, token.AccessToken) {
+		t.Error("stored token is not encrypted")
+	}
+
+	if _, err := store.Get(cookie); err != ErrSessionNotFound {
+		t.Errorf("Get(cookie) error = %v, want ErrSessionNotFound", err)
+	}
+	sess, err := store.Get(HashID(cookie))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.Token.AccessToken != token.AccessToken || sess.UserID != "user" {
+		t.Errorf("Get returned %+v", sess)
+	}
+
+	// Listed sessions can be told apart and deleted without knowing the cookie
+	listed, err := store.ListByUserID("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].PublicID() != created.PublicID() {
+		t.Fatalf("ListByUserID returned %+v, want the created session", listed)
+	}
+	if err := store.Delete(listed[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(HashID(cookie)); err != ErrSessionNotFound {
+		t.Errorf("Get after Delete error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestPublicIDDoesNotRevealSessionID(t *testing.T) {
+	id := HashID("cookie-value")
+	public := PublicID(id)
+
+	if len(public) != 16 || !strings.HasPrefix(id, public) {
+		t.Errorf("PublicID(%q) = %q, want its first 8 bytes", id, public)
+	}
+	if public == id {
+		t.Error("PublicID returned the whole session ID")
+	}
+}
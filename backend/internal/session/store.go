@@ -1,177 +1,73 @@
 package session
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
 )
 
+// sessionTTL is how long a session lives without activity
+const sessionTTL = 24 * time.Hour
+
 var (
 	ErrSessionNotFound = errors.New("session not found")
 	ErrSessionExpired  = errors.New("session expired")
 )
 
-// Session represents a user session
+// Session represents a user session. A user has one session per device they logged in on.
 type Session struct {
-	UserID    string
-	Token     *oauth2.Token
-	CreatedAt time.Time
-	ExpiresAt time.Time
-}
-
-// Store manages user sessions in memory
-type Store struct {
-	sessions map[string]*Session // sessionID -> Session
-	userSessions map[string]string // userID -> sessionID
-	mu       sync.RWMutex
-}
-
-// NewStore creates a new session store
-func NewStore() *Store {
-	store := &Store{
-		sessions: make(map[string]*Session),
-		userSessions: make(map[string]string),
-	}
-
-	// Start cleanup goroutine
-	go store.cleanupExpiredSessions()
-
-	return store
-}
-
-// Create creates a new session
-func (s *Store) Create(sessionID, userID string, token *oauth2.Token) *Session {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	session := &Session{
-		UserID:    userID,
-		Token:     token,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(24 * time.Hour), // Session expires in 24 hours
-	}
-
-	s.sessions[sessionID] = session
-	s.userSessions[userID] = sessionID
-
-	return session
-}
-
-// Get retrieves a session by ID
-func (s *Store) Get(sessionID string) (*Session, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return nil, ErrSessionNotFound
-	}
-
-	if time.Now().After(session.ExpiresAt) {
-		return nil, ErrSessionExpired
-	}
-
-	return session, nil
-}
-
-// GetByUserID retrieves a session by user ID
-func (s *Store) GetByUserID(userID string) (*Session, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	sessionID, exists := s.userSessions[userID]
-	if !exists {
-		return nil, ErrSessionNotFound
-	}
-
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return nil, ErrSessionNotFound
-	}
-
-	if time.Now().After(session.ExpiresAt) {
-		return nil, ErrSessionExpired
-	}
-
-	return session, nil
-}
-
-// Update updates a session's token
-func (s *Store) Update(sessionID string, token *oauth2.Token) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return ErrSessionNotFound
-	}
-
-	session.Token = token
-	return nil
-}
-
-// Delete deletes a session
-func (s *Store) Delete(sessionID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if session, exists := s.sessions[sessionID]; exists {
-		delete(s.userSessions, session.UserID)
-	}
-	delete(s.sessions, sessionID)
+	ID         string // SHA-256 of the session cookie, see HashID
+	UserID     string
+	Token      *oauth2.Token
+	UserAgent  string // User agent of the browser that logged in
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
 }
 
-// DeleteByUserID deletes a session by user ID
-func (s *Store) DeleteByUserID(userID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if sessionID, exists := s.userSessions[userID]; exists {
-		delete(s.sessions, sessionID)
-		delete(s.userSessions, userID)
-	}
+// PublicID identifies the session towards the user
+func (s *Session) PublicID() string {
+	return PublicID(s.ID)
 }
 
-// Refresh extends the session expiration
-func (s *Store) Refresh(sessionID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return ErrSessionNotFound
+// PublicID derives the public identifier of a session from its ID.
+// It is the first 8 bytes of the ID, so a response never carries enough to look the session up.
+func PublicID(sessionID string) string {
+	if len(sessionID) < 16 {
+		return sessionID
 	}
-
-	session.ExpiresAt = time.Now().Add(24 * time.Hour)
-	return nil
+	return sessionID[:16]
 }
 
-// cleanupExpiredSessions periodically removes expired sessions
-func (s *Store) cleanupExpiredSessions() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-
-		for sessionID, session := range s.sessions {
-			if now.After(session.ExpiresAt) {
-				delete(s.userSessions, session.UserID)
-				delete(s.sessions, sessionID)
-			}
-		}
-
-		s.mu.Unlock()
-	}
+// HashID returns the ID of the session a cookie value belongs to. Sessions are stored and looked up
+// by this hash only, so the database never holds a value that could be sent as a cookie.
+func HashID(cookie string) string {
+	sum := sha256.Sum256([]byte(cookie))
+	return hex.EncodeToString(sum[:])
 }
 
-// Count returns the number of active sessions
-func (s *Store) Count() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	return len(s.sessions)
+// SessionStore keeps user sessions and their Spotify tokens.
+// Session IDs are the HashID of the session cookie, never the cookie value itself.
+type SessionStore interface {
+	// Create creates a new session
+	Create(sessionID, userID string, token *oauth2.Token, userAgent string) (*Session, error)
+	// Get retrieves a session by ID
+	Get(sessionID string) (*Session, error)
+	// GetByUserID retrieves the user's most recent session
+	GetByUserID(userID string) (*Session, error)
+	// ListByUserID returns the user's active sessions, most recently seen first
+	ListByUserID(userID string) ([]*Session, error)
+	// Update updates a session's token
+	Update(sessionID string, token *oauth2.Token) error
+	// Delete deletes a session
+	Delete(sessionID string) error
+	// DeleteByUserID deletes all sessions of the user and returns how many there were
+	DeleteByUserID(userID string) (int, error)
+	// Refresh extends the session expiration and records the session as seen
+	Refresh(sessionID string) error
+	// Count returns the number of active sessions
+	Count() (int, error)
 }
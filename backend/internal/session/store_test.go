@@ -0,0 +1,87 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// stores runs a test against every SessionStore implementation
+func stores(t *testing.T, test func(t *testing.T, store SessionStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, NewSQLiteStore(openTestDB(t), newTestCipher(t, "secret")))
+	})
+}
+
+func TestStoreKeepsSessionPerDevice(t *testing.T) {
+	stores(t, func(t *testing.T, store SessionStore) {
+		if _, err := store.Create("laptop", "user", testToken(), "Firefox"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Create("phone", "user", testToken(), "Safari"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Create("other", "other-user", testToken(), "Chrome"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Using the laptop again makes it the most recently seen
+		if err := store.Refresh("laptop"); err != nil {
+			t.Fatal(err)
+		}
+
+		listed, err := store.ListByUserID("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed) != 2 || listed[0].ID != "laptop" || listed[1].ID != "phone" {
+			t.Fatalf("ListByUserID returned %d sessions, want laptop then phone", len(listed))
+		}
+		if listed[0].UserAgent != "Firefox" || listed[1].UserAgent != "Safari" {
+			t.Errorf("user agents = %q, %q, want Firefox, Safari", listed[0].UserAgent, listed[1].UserAgent)
+		}
+		if !listed[0].LastSeenAt.After(listed[1].LastSeenAt) {
+			t.Errorf("laptop last seen %v, not after phone %v", listed[0].LastSeenAt, listed[1].LastSeenAt)
+		}
+	})
+}
+
+func TestStoreRevokesSessions(t *testing.T) {
+	stores(t, func(t *testing.T, store SessionStore) {
+		for _, id := range []string{"laptop", "phone", "tablet"} {
+			if _, err := store.Create(id, "user", testToken(), ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := store.Create("other", "other-user", testToken(), ""); err != nil {
+			t.Fatal(err)
+		}
+
+		// Revoking one device leaves the others logged in
+		if err := store.Delete("phone"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get("phone"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Get of the revoked session: error = %v, want ErrSessionNotFound", err)
+		}
+		if _, err := store.Get("laptop"); err != nil {
+			t.Errorf("Get of another device: %v", err)
+		}
+
+		revoked, err := store.DeleteByUserID("user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != 2 {
+			t.Errorf("DeleteByUserID revoked %d sessions, want 2", revoked)
+		}
+		if listed, _ := store.ListByUserID("user"); len(listed) != 0 {
+			t.Errorf("user still has %d sessions", len(listed))
+		}
+		if _, err := store.Get("other"); err != nil {
+			t.Errorf("session of another user: %v", err)
+		}
+	})
+}
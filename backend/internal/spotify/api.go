@@ -0,0 +1,55 @@
+package spotify
+
+import (
+	"context"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+// Conn is a user's authenticated connection to the Spotify Web API, the part of *spotify.Client the API
+// methods use. Connections come from API.NewSpotifyClient or a TokenManager; tests may pass their own.
+type Conn interface {
+	CurrentUser(ctx context.Context) (*spotify.PrivateUser, error)
+	CurrentUsersTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedTrackPage, error)
+	CurrentUsersPlaylists(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error)
+	GetPlaylistItems(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.PlaylistItemPage, error)
+	GetArtists(ctx context.Context, ids ...spotify.ID) ([]*spotify.FullArtist, error)
+	CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error)
+	ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error
+	ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error
+	AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error)
+	ReorderPlaylistTracks(ctx context.Context, playlistID spotify.ID, opt spotify.PlaylistReorderOptions) (string, error)
+	RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error)
+	UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error
+	FollowPlaylist(ctx context.Context, playlistID spotify.ID, public bool) error
+}
+
+var _ Conn = (*spotify.Client)(nil)
+
+// API is the Spotify access the services and handlers depend on. Client implements it against the Spotify Web API;
+// pointed at a fake server with SetBaseURL and SetAuthEndpoint it lets the app run without a Spotify account.
+type API interface {
+	UsesPKCE() bool
+	GetAuthURL(state, verifier string) string
+	Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error)
+	NewSpotifyClient(ctx context.Context, token *oauth2.Token) Conn
+
+	FetchAllLikedSongs(ctx context.Context, client Conn, progressFn func(current, total int)) ([]domain.Track, error)
+	FetchNewLikedSongs(ctx context.Context, client Conn, known func(domain.Track) bool) ([]domain.Track, int, error)
+	FetchAllPlaylists(ctx context.Context, client Conn, userID string) ([]domain.Playlist, error)
+	FetchPlaylistTracks(ctx context.Context, client Conn, playlistID string) ([]string, error)
+	BatchFetchArtists(ctx context.Context, client Conn, artistIDs []spotify.ID) (map[string]*spotify.FullArtist, error)
+	CreatePlaylist(ctx context.Context, client Conn, userID, name, genreKey, description string, public bool) (*spotify.FullPlaylist, error)
+	ChangePlaylistDetails(ctx context.Context, client Conn, playlistID, name, description string) error
+	AddTracksToPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID) error
+	InsertTracksIntoPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID, position, length int) error
+	RemoveTracksFromPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID) error
+	DeletePlaylist(ctx context.Context, client Conn, playlistID string) error
+	FollowPlaylist(ctx context.Context, client Conn, playlistID string) error
+	GetCurrentUser(ctx context.Context, client Conn) (*spotify.PrivateUser, error)
+}
+
+var _ API = (*Client)(nil)
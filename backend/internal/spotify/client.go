@@ -3,33 +3,39 @@ package spotify
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
 	"golang.org/x/oauth2"
-	"golang.org/x/time/rate"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
 )
 
 type Client struct {
-	auth        *spotifyauth.Authenticator
-	rateLimiter *rate.Limiter
-	mu          sync.Mutex
-	clientID    string
-	clientSecret string
+	oauth            *oauth2.Config
+	usePKCE          bool
+	rateLimiter      *rateLimiter
+	fetchConcurrency int
+	baseURL          string // Spotify Web API URL, empty for the real one
 }
 
-func NewClient(clientID, clientSecret, redirectURL string) *Client {
-	auth := spotifyauth.New(
-		spotifyauth.WithClientID(clientID),
-		spotifyauth.WithClientSecret(clientSecret),
-		spotifyauth.WithRedirectURL(redirectURL),
-		spotifyauth.WithScopes(
+// NewClient creates a Spotify client. With usePKCE the authorization code flow is protected with PKCE,
+// and clientSecret may be empty so the app can run as a public client. API requests are paced by limits.
+func NewClient(clientID, clientSecret, redirectURL string, usePKCE bool, limits RateLimits) *Client {
+	cfg := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  spotifyauth.AuthURL,
+			TokenURL: spotifyauth.TokenURL,
+		},
+		Scopes: []string{
 			spotifyauth.ScopeUserLibraryRead,
 			spotifyauth.ScopePlaylistReadPrivate,
 			spotifyauth.ScopePlaylistReadCollaborative,
@@ -37,111 +43,175 @@ func NewClient(clientID, clientSecret, redirectURL string) *Client {
 			spotifyauth.ScopePlaylistModifyPrivate,
 			spotifyauth.ScopeUserReadPrivate,
 			spotifyauth.ScopeUserReadEmail,
-		),
-	)
+		},
+	}
+
+	if clientSecret == "" {
+		// Public clients identify themselves with the client ID in the request body
+		cfg.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
 
 	return &Client{
-		auth:         auth,
-		rateLimiter:  rate.NewLimiter(rate.Limit(2), 5), // 2 requests/sec with burst of 5
-		clientID:     clientID,
-		clientSecret: clientSecret,
+		oauth:            cfg,
+		usePKCE:          usePKCE,
+		rateLimiter:      newRateLimiter(limits),
+		fetchConcurrency: max(limits.Concurrency, 1),
 	}
 }
 
-func (c *Client) GetAuthURL(state string) string {
-	return c.auth.AuthURL(state)
+// SetBaseURL points API clients created afterwards at another server implementing the Spotify Web API,
+// such as a fake one for benchmarks. The URL must end with a slash.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
 }
 
-func (c *Client) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	return c.auth.Exchange(ctx, code)
+// SetAuthEndpoint points logins and token refreshes at another authorization server, such as a fake Spotify
+func (c *Client) SetAuthEndpoint(authURL, tokenURL string) {
+	c.oauth.Endpoint.AuthURL = authURL
+	c.oauth.Endpoint.TokenURL = tokenURL
 }
 
-func (c *Client) NewSpotifyClient(ctx context.Context, token *oauth2.Token) *spotify.Client {
-	httpClient := c.auth.Client(ctx, token)
-	return spotify.New(httpClient)
+// UsesPKCE reports whether logins use the PKCE authorization code flow
+func (c *Client) UsesPKCE() bool {
+	return c.usePKCE
 }
 
-func (c *Client) TokenSource(ctx context.Context, token *oauth2.Token) oauth2.TokenSource {
-	// Create OAuth2 config manually
-	cfg := &oauth2.Config{
-		ClientID:     c.clientID,
-		ClientSecret: c.clientSecret,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  spotifyauth.AuthURL,
-			TokenURL: spotifyauth.TokenURL,
-		},
+// GetAuthURL returns the Spotify authorization URL. A non-empty verifier adds its PKCE challenge.
+func (c *Client) GetAuthURL(state, verifier string) string {
+	if verifier != "" {
+		return c.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
 	}
-	return cfg.TokenSource(ctx, token)
+	return c.oauth.AuthCodeURL(state)
 }
 
-// Rate limited wrapper for API calls
-func (c *Client) withRateLimit(ctx context.Context) error {
-	return c.rateLimiter.Wait(ctx)
+// Exchange trades an authorization code for a token. verifier must be the one the auth URL was built with.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	if verifier != "" {
+		return c.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	}
+	return c.oauth.Exchange(ctx, code)
 }
 
-// FetchAllLikedSongs fetches all liked songs with pagination
-func (c *Client) FetchAllLikedSongs(ctx context.Context, client *spotify.Client, progressFn func(current, total int)) ([]domain.Track, error) {
-	var allTracks []domain.Track
-	limit := 50
-	offset := 0
+// NewSpotifyClient creates an API client for a token that does not belong to a session yet, such as during login.
+// Its requests only count against the global rate limit.
+func (c *Client) NewSpotifyClient(ctx context.Context, token *oauth2.Token) Conn {
+	return c.newAPIClient(c.oauth.TokenSource(ctx, token), "")
+}
 
-	// First request to get total
-	if err := c.withRateLimit(ctx); err != nil {
-		return nil, err
+// newAPIClient creates a Spotify API client that authenticates with the token source,
+// paces requests with the user's rate limit and retries rate limited and failed requests
+func (c *Client) newAPIClient(source oauth2.TokenSource, userID string) *spotify.Client {
+	var opts []spotify.ClientOption
+	if c.baseURL != "" {
+		opts = append(opts, spotify.WithBaseURL(c.baseURL))
 	}
 
-	page, err := client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(offset))
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch liked songs: %w", err)
-	}
+	return spotify.New(&http.Client{
+		Transport: &oauth2.Transport{
+			Source: source,
+			Base: &retryTransport{
+				base: &limitTransport{
+					limiter: c.rateLimiter,
+					userID:  userID,
+					base:    http.DefaultTransport,
+				},
+			},
+		},
+	}, opts...)
+}
 
-	total := int(page.Total)
+func (c *Client) TokenSource(ctx context.Context, token *oauth2.Token) oauth2.TokenSource {
+	return c.oauth.TokenSource(ctx, token)
+}
 
-	for _, item := range page.Tracks {
-		allTracks = append(allTracks, convertSavedTrack(item))
+// FetchAllLikedSongs fetches all liked songs with pagination.
+// The first page tells how many there are, the remaining pages are fetched concurrently.
+func (c *Client) FetchAllLikedSongs(ctx context.Context, client Conn, progressFn func(current, total int)) ([]domain.Track, error) {
+	limit := 50
+
+	// First request to get total
+	first, err := client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch liked songs: %w", apiError(err))
 	}
 
+	total := int(first.Total)
+	pages := make([][]domain.Track, pageCount(total, limit))
+	pages[0] = convertSavedTracks(first.Tracks)
+
+	// Progress is reported from the fetching goroutines, one at a time
+	var progressMu sync.Mutex
+	fetched := len(pages[0])
 	if progressFn != nil {
-		progressFn(len(allTracks), total)
+		progressFn(fetched, total)
 	}
 
 	// Fetch remaining pages
-	for offset = limit; offset < total; offset += limit {
-		if err := c.withRateLimit(ctx); err != nil {
-			return nil, err
+	err = c.fetchConcurrently(ctx, len(pages)-1, func(ctx context.Context, i int) error {
+		offset := (i + 1) * limit
+		page, err := client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return fmt.Errorf("failed to fetch liked songs at offset %d: %w", offset, apiError(err))
+		}
+		pages[i+1] = convertSavedTracks(page.Tracks)
+
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		fetched += len(page.Tracks)
+		if progressFn != nil {
+			progressFn(fetched, total)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allTracks := make([]domain.Track, 0, total)
+	for _, page := range pages {
+		allTracks = append(allTracks, page...)
+	}
+
+	return allTracks, nil
+}
+
+// FetchNewLikedSongs fetches liked songs newest first, page by page, until known reports a track
+// that was already fetched before. It returns the tracks liked since then and the current number of liked songs.
+func (c *Client) FetchNewLikedSongs(ctx context.Context, client Conn, known func(domain.Track) bool) ([]domain.Track, int, error) {
+	var newTracks []domain.Track
+	limit := 50
 
+	for offset := 0; ; offset += limit {
 		page, err := client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(offset))
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch liked songs at offset %d: %w", offset, err)
+			return nil, 0, fmt.Errorf("failed to fetch liked songs at offset %d: %w", offset, apiError(err))
 		}
 
 		for _, item := range page.Tracks {
-			allTracks = append(allTracks, convertSavedTrack(item))
+			track := convertSavedTrack(item)
+			if known(track) {
+				return newTracks, int(page.Total), nil
+			}
+			newTracks = append(newTracks, track)
 		}
 
-		if progressFn != nil {
-			progressFn(len(allTracks), total)
+		if page.Next == "" {
+			return newTracks, int(page.Total), nil
 		}
 	}
-
-	return allTracks, nil
 }
 
 // FetchAllPlaylists fetches all user playlists with pagination
-func (c *Client) FetchAllPlaylists(ctx context.Context, client *spotify.Client, userID string) ([]domain.Playlist, error) {
+func (c *Client) FetchAllPlaylists(ctx context.Context, client Conn, userID string) ([]domain.Playlist, error) {
 	var allPlaylists []domain.Playlist
 	limit := 50
 	offset := 0
 
 	for {
-		if err := c.withRateLimit(ctx); err != nil {
-			return nil, err
-		}
 
 		page, err := client.CurrentUsersPlaylists(ctx, spotify.Limit(limit), spotify.Offset(offset))
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch playlists: %w", err)
+			return nil, fmt.Errorf("failed to fetch playlists: %w", apiError(err))
 		}
 
 		for _, p := range page.Playlists {
@@ -151,6 +221,7 @@ func (c *Client) FetchAllPlaylists(ctx context.Context, client *spotify.Client,
 				Description: p.Description,
 				OwnerID:     p.Owner.ID,
 				TrackCount:  int(p.Tracks.Total),
+				SnapshotID:  p.SnapshotID,
 			}
 
 			if len(p.Images) > 0 {
@@ -160,8 +231,12 @@ func (c *Client) FetchAllPlaylists(ctx context.Context, client *spotify.Client,
 			// Check if managed by our app
 			if strings.Contains(p.Description, domain.ManagedTag) {
 				playlist.ManagedByApp = true
-				// Extract genre from name (assumes format "Genre Name")
-				playlist.AssignedGenre = extractGenreFromName(p.Name)
+				if genreKey, version, ok := domain.ParseManagedMarker(p.Description); ok && version <= domain.ManagedMarkerVersion {
+					playlist.AssignedGenre = genreKey
+				} else {
+					// Playlists created before the marker only have their name to go by
+					playlist.AssignedGenre = extractGenreFromName(p.Name)
+				}
 			}
 
 			allPlaylists = append(allPlaylists, playlist)
@@ -177,19 +252,16 @@ func (c *Client) FetchAllPlaylists(ctx context.Context, client *spotify.Client,
 }
 
 // FetchPlaylistTracks fetches all tracks from a playlist
-func (c *Client) FetchPlaylistTracks(ctx context.Context, client *spotify.Client, playlistID string) ([]string, error) {
+func (c *Client) FetchPlaylistTracks(ctx context.Context, client Conn, playlistID string) ([]string, error) {
 	var trackIDs []string
 	limit := 50
 	offset := 0
 
 	for {
-		if err := c.withRateLimit(ctx); err != nil {
-			return nil, err
-		}
 
 		page, err := client.GetPlaylistItems(ctx, spotify.ID(playlistID), spotify.Limit(limit), spotify.Offset(offset))
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch playlist tracks: %w", err)
+			return nil, fmt.Errorf("failed to fetch playlist tracks: %w", apiError(err))
 		}
 
 		for _, item := range page.Items {
@@ -207,26 +279,25 @@ func (c *Client) FetchPlaylistTracks(ctx context.Context, client *spotify.Client
 	return trackIDs, nil
 }
 
-// BatchFetchArtists fetches artists in batches of 50
-func (c *Client) BatchFetchArtists(ctx context.Context, client *spotify.Client, artistIDs []spotify.ID) (map[string]*spotify.FullArtist, error) {
-	result := make(map[string]*spotify.FullArtist)
+// BatchFetchArtists fetches artists in batches of 50, several batches at a time
+func (c *Client) BatchFetchArtists(ctx context.Context, client Conn, artistIDs []spotify.ID) (map[string]*spotify.FullArtist, error) {
+	batches := make([][]*spotify.FullArtist, pageCount(len(artistIDs), 50))
 
-	for i := 0; i < len(artistIDs); i += 50 {
-		end := i + 50
-		if end > len(artistIDs) {
-			end = len(artistIDs)
-		}
-		batch := artistIDs[i:end]
-
-		if err := c.withRateLimit(ctx); err != nil {
-			return nil, err
-		}
-
-		artists, err := client.GetArtists(ctx, batch...)
+	err := c.fetchConcurrently(ctx, len(batches), func(ctx context.Context, i int) error {
+		end := min((i+1)*50, len(artistIDs))
+		artists, err := client.GetArtists(ctx, artistIDs[i*50:end]...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch artists: %w", err)
+			return fmt.Errorf("failed to fetch artists: %w", apiError(err))
 		}
+		batches[i] = artists
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	result := make(map[string]*spotify.FullArtist, len(artistIDs))
+	for _, artists := range batches {
 		for _, artist := range artists {
 			if artist != nil {
 				result[artist.ID.String()] = artist
@@ -237,18 +308,31 @@ func (c *Client) BatchFetchArtists(ctx context.Context, client *spotify.Client,
 	return result, nil
 }
 
-// CreatePlaylist creates a new playlist
-func (c *Client) CreatePlaylist(ctx context.Context, client *spotify.Client, userID, name, description string, public bool) (*spotify.FullPlaylist, error) {
-	if err := c.withRateLimit(ctx); err != nil {
-		return nil, err
+// CreatePlaylist creates a new managed playlist for the genre, its description is tagged and marked with genreKey
+func (c *Client) CreatePlaylist(ctx context.Context, client Conn, userID, name, genreKey, description string, public bool) (*spotify.FullPlaylist, error) {
+	fullDescription := domain.ManagedDescription(description, genreKey)
+	playlist, err := client.CreatePlaylistForUser(ctx, userID, name, fullDescription, public, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playlist: %w", apiError(err))
+	}
+
+	return playlist, nil
+}
+
+// ChangePlaylistDetails changes the name and description of a playlist
+func (c *Client) ChangePlaylistDetails(ctx context.Context, client Conn, playlistID, name, description string) error {
+	if err := client.ChangePlaylistName(ctx, spotify.ID(playlistID), name); err != nil {
+		return fmt.Errorf("failed to rename playlist: %w", apiError(err))
+	}
+	if err := client.ChangePlaylistDescription(ctx, spotify.ID(playlistID), description); err != nil {
+		return fmt.Errorf("failed to change playlist description: %w", apiError(err))
 	}
 
-	fullDescription := description + " " + domain.ManagedTag
-	return client.CreatePlaylistForUser(ctx, userID, name, fullDescription, public, false)
+	return nil
 }
 
 // AddTracksToPlaylist adds tracks in batches of 100
-func (c *Client) AddTracksToPlaylist(ctx context.Context, client *spotify.Client, playlistID string, trackIDs []spotify.ID) error {
+func (c *Client) AddTracksToPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID) error {
 	for i := 0; i < len(trackIDs); i += 100 {
 		end := i + 100
 		if end > len(trackIDs) {
@@ -256,51 +340,78 @@ func (c *Client) AddTracksToPlaylist(ctx context.Context, client *spotify.Client
 		}
 		batch := trackIDs[i:end]
 
-		if err := c.withRateLimit(ctx); err != nil {
-			return err
-		}
-
 		_, err := client.AddTracksToPlaylist(ctx, spotify.ID(playlistID), batch...)
 		if err != nil {
-			return fmt.Errorf("failed to add tracks to playlist: %w", err)
+			return fmt.Errorf("failed to add tracks to playlist: %w", apiError(err))
 		}
 	}
 
 	return nil
 }
 
-// RemoveTracksFromPlaylist removes tracks from a playlist
-func (c *Client) RemoveTracksFromPlaylist(ctx context.Context, client *spotify.Client, playlistID string, trackIDs []spotify.ID) error {
-	if err := c.withRateLimit(ctx); err != nil {
-		return err
+// InsertTracksIntoPlaylist adds up to 100 tracks at a position of a playlist that holds length tracks.
+// The tracks are appended, then moved into place against the snapshot of the append.
+func (c *Client) InsertTracksIntoPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID, position, length int) error {
+	snapshotID, err := client.AddTracksToPlaylist(ctx, spotify.ID(playlistID), trackIDs...)
+	if err != nil {
+		return fmt.Errorf("failed to add tracks to playlist: %w", apiError(err))
+	}
+	if position >= length {
+		return nil
 	}
 
+	_, err = client.ReorderPlaylistTracks(ctx, spotify.ID(playlistID), spotify.PlaylistReorderOptions{
+		RangeStart:   spotify.Numeric(length),
+		RangeLength:  spotify.Numeric(len(trackIDs)),
+		InsertBefore: spotify.Numeric(position),
+		SnapshotID:   snapshotID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move tracks in playlist: %w", apiError(err))
+	}
+
+	return nil
+}
+
+// RemoveTracksFromPlaylist removes tracks from a playlist
+func (c *Client) RemoveTracksFromPlaylist(ctx context.Context, client Conn, playlistID string, trackIDs []spotify.ID) error {
+
 	_, err := client.RemoveTracksFromPlaylist(ctx, spotify.ID(playlistID), trackIDs...)
 	if err != nil {
-		return fmt.Errorf("failed to remove tracks from playlist: %w", err)
+		return fmt.Errorf("failed to remove tracks from playlist: %w", apiError(err))
 	}
 
 	return nil
 }
 
 // GetCurrentUser returns the current user's profile
-func (c *Client) GetCurrentUser(ctx context.Context, client *spotify.Client) (*spotify.PrivateUser, error) {
-	if err := c.withRateLimit(ctx); err != nil {
-		return nil, err
+func (c *Client) GetCurrentUser(ctx context.Context, client Conn) (*spotify.PrivateUser, error) {
+
+	user, err := client.CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current user: %w", apiError(err))
 	}
 
-	return client.CurrentUser(ctx)
+	return user, nil
 }
 
 // DeletePlaylist deletes a playlist
-func (c *Client) DeletePlaylist(ctx context.Context, client *spotify.Client, playlistID string) error {
-	if err := c.withRateLimit(ctx); err != nil {
-		return err
-	}
+func (c *Client) DeletePlaylist(ctx context.Context, client Conn, playlistID string) error {
 
 	err := client.UnfollowPlaylist(ctx, spotify.ID(playlistID))
 	if err != nil {
-		return fmt.Errorf("failed to delete playlist: %w", err)
+		return fmt.Errorf("failed to delete playlist: %w", apiError(err))
+	}
+
+	return nil
+}
+
+// FollowPlaylist follows a playlist again, restoring a playlist that was deleted
+func (c *Client) FollowPlaylist(ctx context.Context, client Conn, playlistID string) error {
+
+	err := client.FollowPlaylist(ctx, spotify.ID(playlistID), false)
+	if err != nil {
+		return fmt.Errorf("failed to follow playlist: %w", apiError(err))
 	}
 
 	return nil
@@ -308,6 +419,38 @@ func (c *Client) DeletePlaylist(ctx context.Context, client *spotify.Client, pla
 
 // Helper functions
 
+// fetchConcurrently calls fetch for every index below n, with at most fetchConcurrency calls in flight.
+// Requests still pass the rate limiter one by one. The first error cancels the remaining calls.
+func (c *Client) fetchConcurrently(ctx context.Context, n int, fetch func(ctx context.Context, i int) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.fetchConcurrency)
+
+	for i := range n {
+		g.Go(func() error {
+			// Stop once the caller cancelled or another call failed
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fetch(ctx, i)
+		})
+	}
+
+	return g.Wait()
+}
+
+// pageCount returns how many pages of size limit hold total items, at least one
+func pageCount(total, limit int) int {
+	return max((total+limit-1)/limit, 1)
+}
+
+func convertSavedTracks(items []spotify.SavedTrack) []domain.Track {
+	tracks := make([]domain.Track, 0, len(items))
+	for _, item := range items {
+		tracks = append(tracks, convertSavedTrack(item))
+	}
+	return tracks
+}
+
 func convertSavedTrack(st spotify.SavedTrack) domain.Track {
 	track := domain.Track{
 		ID:       st.ID.String(),
@@ -315,6 +458,10 @@ func convertSavedTrack(st spotify.SavedTrack) domain.Track {
 		Duration: int(st.Duration),
 	}
 
+	if addedAt, err := time.Parse(spotify.TimestampLayout, st.AddedAt); err == nil {
+		track.AddedAt = addedAt
+	}
+
 	if st.Album.Name != "" {
 		track.AlbumName = st.Album.Name
 	}
@@ -338,47 +485,3 @@ func extractGenreFromName(name string) string {
 	// Could add more sophisticated matching later
 	return strings.ToLower(strings.TrimSpace(name))
 }
-
-// HandleRateLimitError checks if an error is a rate limit error and waits
-func HandleRateLimitError(err error) (bool, time.Duration) {
-	if err == nil {
-		return false, 0
-	}
-
-	// Check for 429 status
-	if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "rate limit") {
-		// Default retry after 30 seconds
-		log.Warn().Msg("Rate limited by Spotify API, waiting 30 seconds")
-		return true, 30 * time.Second
-	}
-
-	return false, 0
-}
-
-// WithRetry wraps an operation with retry logic for rate limiting
-func WithRetry(ctx context.Context, maxRetries int, fn func() error) error {
-	var lastErr error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		err := fn()
-		if err == nil {
-			return nil
-		}
-
-		lastErr = err
-
-		isRateLimited, waitTime := HandleRateLimitError(err)
-		if !isRateLimited {
-			return err
-		}
-
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(waitTime):
-			continue
-		}
-	}
-
-	return lastErr
-}
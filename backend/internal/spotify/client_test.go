@@ -0,0 +1,227 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/spotify/fake"
+)
+
+// testLimits keeps the rate limiter out of the way of tests
+var testLimits = RateLimits{Rate: 100, Burst: 100, UserRate: 100, UserBurst: 100}
+
+// tokenRequest is what a login sent to the token endpoint
+type tokenRequest struct {
+	form      url.Values
+	basicAuth bool
+}
+
+// newTokenServer answers token requests with a fixed token and records them
+func newTokenServer(t *testing.T) (*httptest.Server, *[]tokenRequest) {
+	t.Helper()
+
+	var requests []tokenRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse token request: %v", err)
+		}
+		_, _, basicAuth := r.BasicAuth()
+		requests = append(requests, tokenRequest{form: r.PostForm, basicAuth: basicAuth})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestPKCELogin(t *testing.T) {
+	server, requests := newTokenServer(t)
+	client := NewClient("client-id", "", "http://localhost/callback", true, testLimits)
+	client.oauth.Endpoint.TokenURL = server.URL
+
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := url.Parse(client.GetAuthURL("state", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if query.Get("code_challenge") != oauth2.S256ChallengeFromVerifier(verifier) || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("auth URL %s does not carry the S256 challenge of the verifier", authURL)
+	}
+	if query.Get("state") != "state" {
+		t.Errorf("auth URL state = %q, want %q", query.Get("state"), "state")
+	}
+
+	if _, err := client.Exchange(context.Background(), "code", verifier); err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("%d token requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.form.Get("code_verifier") != verifier {
+		t.Errorf("token request sent verifier %q, want the one the challenge was made from", req.form.Get("code_verifier"))
+	}
+	// A public client has no secret, it identifies itself with the client ID alone
+	if req.basicAuth || req.form.Get("client_id") != "client-id" || req.form.Has("client_secret") {
+		t.Errorf("public client authenticated with basic auth %t, form %v", req.basicAuth, req.form)
+	}
+}
+
+func TestConfidentialLogin(t *testing.T) {
+	server, requests := newTokenServer(t)
+	client := NewClient("client-id", "client-secret", "http://localhost/callback", false, testLimits)
+	client.oauth.Endpoint.TokenURL = server.URL
+
+	authURL, err := url.Parse(client.GetAuthURL("state", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authURL.Query().Has("code_challenge") {
+		t.Errorf("auth URL %s carries a challenge without PKCE", authURL)
+	}
+
+	if _, err := client.Exchange(context.Background(), "code", ""); err != nil {
+		t.Fatal(err)
+	}
+	if req := (*requests)[0]; req.form.Has("code_verifier") || !req.basicAuth {
+		t.Errorf("confidential token request: basic auth %t, form %v", req.basicAuth, req.form)
+	}
+}
+
+func TestDemoLogin(t *testing.T) {
+	server := fake.NewServer(fake.Options{UserID: "demo-user", LikedSongs: 10, Artists: 5})
+	t.Cleanup(server.Close)
+
+	// Wired like cmd/server does in demo mode, without client credentials
+	redirectURL := "http://localhost/callback"
+	client := NewClient("", "", redirectURL, false, testLimits)
+	client.SetBaseURL(server.BaseURL())
+	client.SetAuthEndpoint(server.AuthURL(redirectURL), server.TokenURL())
+
+	// The login leads straight back to the callback with a code and the state
+	authURL, err := url.Parse(client.GetAuthURL("state", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != redirectURL {
+		t.Errorf("login leads to %s, want the callback %s", got, redirectURL)
+	}
+	query := authURL.Query()
+	if query.Get("state") != "state" {
+		t.Errorf("callback state = %q, want %q", query.Get("state"), "state")
+	}
+
+	ctx := context.Background()
+	token, err := client.Exchange(ctx, query.Get("code"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := client.GetCurrentUser(ctx, client.NewSpotifyClient(ctx, token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "demo-user" {
+		t.Errorf("logged in as %q, want the demo user", user.ID)
+	}
+}
+
+// benchLibrary is large enough to span many pages, with enough latency that waiting on Spotify dominates
+var benchLibrary = fake.Options{LikedSongs: 2000, Artists: 800, Latency: 5 * time.Millisecond}
+
+// benchConcurrency compares fetching one page at a time with concurrent pagination
+var benchConcurrency = []struct {
+	name        string
+	concurrency int
+}{
+	{"sequential", 1},
+	{"concurrent", 4},
+}
+
+// newBenchClient returns a client of the fake server whose rate limiter does not hold back the fetches
+func newBenchClient(server *fake.Server, concurrency int) (*Client, Conn) {
+	client := NewClient("bench", "bench", "http://localhost/callback", false, RateLimits{
+		Rate:        10000,
+		Burst:       concurrency,
+		UserRate:    10000,
+		UserBurst:   concurrency,
+		Concurrency: concurrency,
+	})
+	client.SetBaseURL(server.BaseURL())
+	return client, client.NewSpotifyClient(context.Background(), &oauth2.Token{AccessToken: "bench"})
+}
+
+func BenchmarkFetchAllLikedSongs(b *testing.B) {
+	server := fake.NewServer(benchLibrary)
+	b.Cleanup(server.Close)
+	ctx := context.Background()
+
+	for _, bc := range benchConcurrency {
+		b.Run(bc.name, func(b *testing.B) {
+			client, conn := newBenchClient(server, bc.concurrency)
+			requests := server.Requests()
+
+			b.ReportAllocs()
+			for b.Loop() {
+				tracks, err := client.FetchAllLikedSongs(ctx, conn, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(tracks) != benchLibrary.LikedSongs {
+					b.Fatalf("fetched %d liked songs, want %d", len(tracks), benchLibrary.LikedSongs)
+				}
+			}
+			b.ReportMetric(float64(server.Requests()-requests)/float64(b.N), "requests/op")
+		})
+	}
+}
+
+func BenchmarkBatchFetchArtists(b *testing.B) {
+	server := fake.NewServer(benchLibrary)
+	b.Cleanup(server.Close)
+	ctx := context.Background()
+
+	// The artists of the liked songs, as an analysis looks them up
+	client, conn := newBenchClient(server, 1)
+	tracks, err := client.FetchAllLikedSongs(ctx, conn, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	var artistIDs []spotify.ID
+	for _, track := range tracks {
+		for _, artist := range track.Artists {
+			if !seen[artist.ID] {
+				seen[artist.ID] = true
+				artistIDs = append(artistIDs, spotify.ID(artist.ID))
+			}
+		}
+	}
+
+	for _, bc := range benchConcurrency {
+		b.Run(bc.name, func(b *testing.B) {
+			client, conn := newBenchClient(server, bc.concurrency)
+			requests := server.Requests()
+
+			b.ReportAllocs()
+			for b.Loop() {
+				artists, err := client.BatchFetchArtists(ctx, conn, artistIDs)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(artists) != len(artistIDs) {
+					b.Fatalf("fetched %d artists, want %d", len(artists), len(artistIDs))
+				}
+			}
+			b.ReportMetric(float64(server.Requests()-requests)/float64(b.N), "requests/op")
+		})
+	}
+}
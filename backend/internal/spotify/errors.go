@@ -0,0 +1,79 @@
+package spotify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+var (
+	ErrRateLimited  = errors.New("rate limited by Spotify")
+	ErrUnauthorized = errors.New("spotify rejected the access token")
+	ErrForbidden    = errors.New("not allowed by Spotify")
+	ErrNotFound     = errors.New("not found on Spotify")
+	ErrUnavailable  = errors.New("spotify is unavailable")
+)
+
+// APIError is an error response from the Spotify Web API.
+// errors.Is matches it against ErrRateLimited, ErrUnauthorized, ErrForbidden, ErrNotFound and ErrUnavailable.
+type APIError struct {
+	Status     int
+	Message    string
+	RetryAfter time.Duration // How long Spotify asked to wait, set when rate limited
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("spotify: HTTP %d: %s (retry after %s)", e.Status, msg, e.RetryAfter)
+	}
+	return fmt.Sprintf("spotify: HTTP %d: %s", e.Status, msg)
+}
+
+// Unwrap returns the sentinel error matching the status
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.Status == http.StatusForbidden:
+		return ErrForbidden
+	case e.Status == http.StatusNotFound:
+		return ErrNotFound
+	case e.Status >= http.StatusInternalServerError:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// apiError turns errors from the Spotify library into an *APIError so callers can branch on them.
+// Other errors are returned unchanged.
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Raised by the retry transport when it gave up, possibly wrapped by the HTTP client
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var spotifyErr spotify.Error
+	if errors.As(err, &spotifyErr) {
+		return &APIError{
+			Status:  spotifyErr.Status,
+			Message: spotifyErr.Message,
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,201 @@
+package fake
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+// demoSeed makes the realistic library the same on every start, so a demo can be repeated
+const demoSeed = 20240601
+
+// commonGenres are most of a real library, the first ones the most
+var commonGenres = []string{
+	"indie rock", "pop", "hip hop", "dance pop", "alternative rock", "modern rock", "rap", "r&b",
+	"indie pop", "house", "classic rock", "soul", "edm", "trap", "neo soul", "jazz", "country",
+	"deep house", "synthpop", "singer-songwriter", "folk", "punk rock", "techno", "bedroom pop",
+}
+
+// rareGenres make up the long tail, most of them end up with a handful of songs
+var rareGenres = []string{
+	"shoegaze", "dream pop", "vaporwave", "zeuhl", "math rock", "chillwave", "dungeon synth",
+	"sea shanties", "bossa nova", "afrobeat", "city pop", "krautrock", "hyperpop", "witch house",
+	"lo-fi beats", "slowcore", "emo rap", "drill", "grime", "dancehall", "cumbia", "fado", "qawwali",
+	"enka", "mpb", "highlife", "zydeco", "bluegrass", "outlaw country", "nu jazz", "acid jazz",
+	"free jazz", "ambient", "idm", "glitch", "post-rock", "darkwave", "coldwave", "minimal techno",
+	"footwork", "jersey club", "phonk", "gqom", "amapiano", "baile funk", "reggaeton", "k-pop",
+	"j-pop", "trip hop", "downtempo", "psychedelic rock", "stoner rock", "doom metal", "black metal",
+	"death metal", "hardcore punk", "ska punk", "pop punk", "surf rock", "garage rock", "noise rock",
+	"art pop", "baroque pop", "chamber pop", "yacht rock", "soft rock", "tech house", "uk garage",
+}
+
+var (
+	adjectives = []string{
+		"Golden", "Electric", "Quiet", "Velvet", "Broken", "Midnight", "Silver", "Paper", "Neon", "Wild",
+		"Hollow", "Crimson", "Lucky", "Distant", "Sweet", "Restless", "Frozen", "Burning", "Blue", "Lonely",
+	}
+	nouns = []string{
+		"Hearts", "Rivers", "Machines", "Ghosts", "Satellites", "Horses", "Mirrors", "Tigers", "Lanterns",
+		"Oceans", "Cities", "Wolves", "Echoes", "Gardens", "Highways", "Comets", "Shadows", "Kings",
+	}
+	firstNames = []string{
+		"Maya", "Leon", "Ines", "Theo", "Aiko", "Jonas", "Zara", "Milo", "Nadia", "Oscar", "Priya", "Felix",
+	}
+	lastNames = []string{
+		"Reyes", "Lindqvist", "Okafor", "Moreau", "Tanaka", "Novak", "Hart", "Silva", "Brandt", "Quinn",
+	}
+)
+
+// generateRealistic creates a library like a real one: a few artists have many liked songs while most
+// have one or two, genres follow a long tail, some artists have no genres and some songs feature a second
+// artist. The user already has managed playlists that are partly sorted, a playlist of their own and
+// one they follow.
+func (s *Server) generateRealistic() {
+	r := rand.New(rand.NewPCG(demoSeed, demoSeed))
+
+	for i := range max(s.opts.Artists, 1) {
+		s.artists = append(s.artists, spotify.FullArtist{
+			SimpleArtist: spotify.SimpleArtist{ID: artistID(i), Name: artistName(r)},
+			Genres:       artistGenres(r),
+		})
+	}
+
+	// Popular artists come first, Zipf spreads the songs over them with a long tail
+	popularity := rand.NewZipf(r, 1.2, 8, uint64(len(s.artists)-1))
+	addedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range s.opts.LikedSongs {
+		artists := []spotify.FullArtist{s.artists[popularity.Uint64()]}
+		if r.IntN(12) == 0 {
+			artists = append(artists, s.artists[r.IntN(len(s.artists))])
+		}
+
+		name := pick(r, adjectives) + " " + pick(r, nouns)
+		album := pick(r, nouns) + " of " + pick(r, adjectives) + " " + pick(r, nouns)
+		s.like(newTrack(i, name, album, 120000+r.IntN(240000), artists...), addedAt)
+
+		// Songs are liked in bursts, minutes to days apart
+		addedAt = addedAt.Add(-time.Duration(r.Int64N(int64(72 * time.Hour))))
+	}
+
+	s.seedPlaylists(r)
+}
+
+// seedPlaylists gives the user playlists from an earlier sort: managed playlists of the most common genres
+// that miss some songs and hold a few of other genres, one of a genre no longer liked that the sort empties,
+// a duplicate of a managed playlist, and playlists the sort must leave alone. The user renamed one of the
+// managed playlists.
+func (s *Server) seedPlaylists(r *rand.Rand) {
+	if len(s.likes) == 0 {
+		return
+	}
+
+	byGenre := make(map[string][]string)
+	for _, saved := range s.likes {
+		if genres := s.artistByID(saved.Artists[0].ID).Genres; len(genres) > 0 {
+			byGenre[genres[0]] = append(byGenre[genres[0]], saved.ID.String())
+		}
+	}
+
+	liked := s.likedIDs()
+	for i, genre := range commonGenres[:3] {
+		var trackIDs []string
+		for _, id := range byGenre[genre] {
+			if r.IntN(10) < 7 {
+				trackIDs = append(trackIDs, id)
+			}
+		}
+		for range 3 {
+			trackIDs = append(trackIDs, pick(r, liked))
+		}
+		p := s.addPlaylist(genre, domain.ManagedDescription("Automatically organized "+genre+" tracks", genre), trackIDs)
+		if i == 0 {
+			p.Name = "Forever " + genre + " 🎸"
+		}
+	}
+
+	// A genre the user moved on from, all of its songs belong elsewhere now.
+	// The playlist was created before genre markers, only its name tells the genre.
+	var polka []string
+	for range 5 {
+		polka = append(polka, pick(r, liked))
+	}
+	s.addPlaylist("polka", "Automatically organized polka tracks "+domain.ManagedTag, polka)
+
+	var roadTrip []string
+	for range 40 {
+		roadTrip = append(roadTrip, pick(r, liked))
+	}
+	s.addPlaylist("Road Trip", "Songs for the drive", roadTrip)
+
+	// A copy of the second playlist, as left behind by an earlier sort that did not find it. The sort merges it.
+	duplicate := commonGenres[1]
+	var copied []string
+	for _, id := range byGenre[duplicate] {
+		if r.IntN(10) < 3 {
+			copied = append(copied, id)
+		}
+	}
+	s.addPlaylist(duplicate, domain.ManagedDescription("Automatically organized "+duplicate+" tracks", duplicate), copied)
+
+	followed := s.addPlaylist("Today's Top Hits", "The hottest songs right now", liked[:min(50, len(liked))])
+	followed.OwnerID = "spotify"
+}
+
+// artistByID returns a generated artist
+func (s *Server) artistByID(id spotify.ID) spotify.FullArtist {
+	var n int
+	fmt.Sscanf(id.String(), "artist%d", &n)
+	return s.artists[n]
+}
+
+// likedIDs returns the IDs of the liked songs. Must be called with mu held, or before the server started.
+func (s *Server) likedIDs() []string {
+	ids := make([]string, len(s.likes))
+	for i, saved := range s.likes {
+		ids[i] = saved.ID.String()
+	}
+	return ids
+}
+
+// artistGenres returns one to three genres, mostly common ones, or none for about one artist in eight
+func artistGenres(r *rand.Rand) []string {
+	if r.IntN(8) == 0 {
+		return []string{}
+	}
+
+	count := 1 + r.IntN(3)
+	genres := make([]string, 0, count)
+	for len(genres) < count {
+		var genre string
+		if r.IntN(10) < 7 {
+			// Lower indices are much more likely
+			genre = commonGenres[int(float64(len(commonGenres))*r.Float64()*r.Float64())]
+		} else {
+			genre = pick(r, rareGenres)
+		}
+		if !slices.Contains(genres, genre) {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
+
+func artistName(r *rand.Rand) string {
+	switch r.IntN(3) {
+	case 0:
+		return "The " + pick(r, adjectives) + " " + pick(r, nouns)
+	case 1:
+		return pick(r, firstNames) + " " + pick(r, lastNames)
+	default:
+		return pick(r, adjectives) + " " + pick(r, nouns)
+	}
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.IntN(len(words))]
+}
@@ -0,0 +1,63 @@
+package fake
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/domain"
+)
+
+func TestRealisticLibrary(t *testing.T) {
+	opts := Options{LikedSongs: 2000, Artists: 600, Realistic: true}
+	s := NewServer(opts)
+	defer s.Close()
+
+	if len(s.likes) != opts.LikedSongs || len(s.artists) != opts.Artists {
+		t.Fatalf("%d liked songs of %d artists, want %d of %d", len(s.likes), len(s.artists), opts.LikedSongs, opts.Artists)
+	}
+
+	// Songs per genre of their first artist, and artists without genres
+	songs := make(map[string]int)
+	withoutGenres := 0
+	for _, saved := range s.likes {
+		genres := s.artistByID(saved.Artists[0].ID).Genres
+		if len(genres) == 0 {
+			withoutGenres++
+			continue
+		}
+		songs[genres[0]]++
+	}
+	if withoutGenres == 0 {
+		t.Error("every song has an artist with genres, want some without")
+	}
+	rare := 0
+	for _, genre := range rareGenres {
+		if n := songs[genre]; n > 0 && n < songs[commonGenres[0]]/10 {
+			rare++
+		}
+	}
+	if rare < 10 {
+		t.Errorf("%d rare genres with a handful of songs, want a long tail", rare)
+	}
+
+	var managed, own int
+	for _, p := range s.playlists {
+		switch {
+		case strings.Contains(p.Description, domain.ManagedTag):
+			managed++
+		case p.OwnerID == s.UserID():
+			own++
+		}
+	}
+	if managed == 0 || own == 0 {
+		t.Errorf("%d managed and %d other own playlists, want both", managed, own)
+	}
+
+	// The same library on every start, so a demo can be repeated
+	again := NewServer(opts)
+	defer again.Close()
+	if !slices.Equal(s.likedIDs(), again.likedIDs()) || s.artists[0].Name != again.artists[0].Name {
+		t.Error("two servers generated different libraries")
+	}
+}
@@ -0,0 +1,741 @@
+// Package fake serves a generated library over the parts of the Spotify Web API the app uses,
+// so fetching, analysis and sorting can run without a Spotify account.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// DefaultUserID is the ID of the fake user when Options leave it out
+const DefaultUserID = "fake-user"
+
+// AuthCode is the authorization code the fake hands out, it can be exchanged any number of times
+const AuthCode = "fake-code"
+
+// genres are handed out to the generated artists in turn. Every sixth artist has none,
+// so part of the library ends up uncategorized.
+var genres = [][]string{
+	{"indie rock"},
+	{"deep house"},
+	{"jazz"},
+	{"hip hop"},
+	{"synthpop"},
+	{},
+}
+
+// Options describes the generated library and how the server behaves
+type Options struct {
+	UserID     string
+	LikedSongs int
+	Artists    int
+	Latency    time.Duration // Added to every response, Spotify takes 100-300ms per page
+
+	// Realistic generates a library that looks like a real one, see demo.go. Otherwise everything
+	// is numbered in order and songs are spread evenly over artists with a handful of genres.
+	Realistic bool
+}
+
+// Playlist is the state of a playlist on the fake server
+type Playlist struct {
+	ID          string
+	Name        string
+	Description string
+	OwnerID     string
+	TrackIDs    []string
+	SnapshotID  string // Changes with every change to the tracks
+	Followed    bool   // Unfollowed playlists are what Spotify calls deleted
+}
+
+// Server is a fake Spotify Web API. It keeps liked songs, playlists and artists in memory,
+// pages lists like Spotify does and can answer with 429 to exercise rate limit handling.
+type Server struct {
+	*httptest.Server
+	opts     Options
+	requests atomic.Int64
+	tokens   atomic.Int64 // Access tokens handed out
+
+	artists  []spotify.FullArtist // Index is the number in the artist ID, never changes
+	instance string               // Keeps snapshot IDs of different servers apart, caches outlive the server
+
+	mu          sync.Mutex
+	likes       []spotify.SavedTrack // Newest first
+	catalog     map[string]spotify.FullTrack
+	playlists   []*Playlist
+	snapshots   int // Source of snapshot IDs
+	rateLimited int // Responses left to answer with 429
+	retryAfter  time.Duration
+	failures    []failure
+}
+
+// failure answers requests matching a method and path with an error status
+type failure struct {
+	method string
+	path   string
+	status int
+	left   int
+}
+
+// NewServer starts a fake Spotify Web API. Close it when done.
+func NewServer(opts Options) *Server {
+	if opts.UserID == "" {
+		opts.UserID = DefaultUserID
+	}
+
+	s := &Server{
+		opts:     opts,
+		catalog:  make(map[string]spotify.FullTrack),
+		instance: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+	if opts.Realistic {
+		s.generateRealistic()
+	} else {
+		s.generate()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /api/token", s.token)
+	mux.HandleFunc("GET /me", s.me)
+	mux.HandleFunc("GET /me/tracks", s.likedSongs)
+	mux.HandleFunc("GET /me/playlists", s.listPlaylists)
+	mux.HandleFunc("POST /users/{userID}/playlists", s.createPlaylist)
+	mux.HandleFunc("PUT /playlists/{id}", s.changeDetails)
+	mux.HandleFunc("GET /playlists/{id}/tracks", s.playlistTracks)
+	mux.HandleFunc("POST /playlists/{id}/tracks", s.addTracks)
+	mux.HandleFunc("PUT /playlists/{id}/tracks", s.reorderTracks)
+	mux.HandleFunc("DELETE /playlists/{id}/tracks", s.removeTracks)
+	mux.HandleFunc("PUT /playlists/{id}/followers", s.follow)
+	mux.HandleFunc("DELETE /playlists/{id}/followers", s.unfollow)
+	mux.HandleFunc("GET /artists", s.severalArtists)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// BaseURL returns the URL to point API clients at
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+// AuthURL returns the authorization URL to log in with. There is no consent screen,
+// the URL leads straight back to redirectURL with a code the token endpoint accepts.
+func (s *Server) AuthURL(redirectURL string) string {
+	return redirectURL + "?code=" + AuthCode
+}
+
+// TokenURL returns the URL of the OAuth token endpoint
+func (s *Server) TokenURL() string {
+	return s.URL + "/api/token"
+}
+
+// UserID returns the ID of the fake user
+func (s *Server) UserID() string {
+	return s.opts.UserID
+}
+
+// Requests returns how many requests the server handled, including rate limited ones
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+// RateLimit answers the next n requests with 429 and the given Retry-After
+func (s *Server) RateLimit(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimited = n
+	s.retryAfter = retryAfter
+}
+
+// Fail answers the next n requests with the method and path, such as "/playlists/{id}", with the status
+func (s *Server) Fail(method, path string, status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failure{method: method, path: path, status: status, left: n})
+}
+
+// AddPlaylist adds a playlist owned by the fake user and returns its ID
+func (s *Server) AddPlaylist(name, description string, trackIDs []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addPlaylist(name, description, trackIDs).ID
+}
+
+// LikedTrackIDs returns the IDs of the liked songs, newest first
+func (s *Server) LikedTrackIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.likedIDs()
+}
+
+// Unlike removes tracks from the liked songs
+func (s *Server) Unlike(trackIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove := toSet(trackIDs)
+	likes := s.likes[:0]
+	for _, saved := range s.likes {
+		if !remove[saved.ID.String()] {
+			likes = append(likes, saved)
+		}
+	}
+	s.likes = likes
+}
+
+// Rename renames a playlist, like the user would in the Spotify app
+func (s *Server) Rename(playlistID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.playlists {
+		if p.ID == playlistID {
+			p.Name = name
+		}
+	}
+}
+
+// Playlists returns copies of all playlists, including unfollowed ones
+func (s *Server) Playlists() []Playlist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists := make([]Playlist, len(s.playlists))
+	for i, p := range s.playlists {
+		playlists[i] = *p
+		playlists[i].TrackIDs = append([]string(nil), p.TrackIDs...)
+	}
+	return playlists
+}
+
+// middleware counts requests, adds latency, answers with 429 while rate limiting and fails requests as asked
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.opts.Latency > 0 {
+			time.Sleep(s.opts.Latency)
+		}
+
+		s.mu.Lock()
+		limited := s.rateLimited > 0
+		if limited {
+			s.rateLimited--
+		}
+		retryAfter := s.retryAfter
+		status := 0
+		for i := range s.failures {
+			f := &s.failures[i]
+			if f.left > 0 && f.method == r.Method && f.path == r.URL.Path {
+				f.left--
+				status = f.status
+				break
+			}
+		}
+		s.mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			writeError(w, http.StatusTooManyRequests, "API rate limit exceeded")
+			return
+		}
+		if status != 0 {
+			writeError(w, status, http.StatusText(status))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize skips the consent screen and sends the user straight back with a code
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	redirect, err := url.Parse(r.URL.Query().Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		writeError(w, http.StatusBadRequest, "Invalid redirect URI")
+		return
+	}
+
+	query := redirect.Query()
+	query.Set("code", AuthCode)
+	query.Set("state", r.URL.Query().Get("state"))
+	redirect.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token exchanges the fake code or a refresh token for a new access token
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		if r.PostForm.Get("code") != AuthCode {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	case "refresh_token":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  fmt.Sprintf("fake-access-%d", s.tokens.Add(1)),
+		"token_type":    "Bearer",
+		"refresh_token": "fake-refresh",
+		"expires_in":    3600,
+		"scope":         r.PostForm.Get("scope"),
+	})
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, spotify.PrivateUser{
+		User: spotify.User{ID: s.opts.UserID, DisplayName: "Fake User"},
+	})
+}
+
+func (s *Server) likedSongs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit, offset := pageParams(r)
+	items := []spotify.SavedTrack{}
+	for i := offset; i < min(offset+limit, len(s.likes)); i++ {
+		items = append(items, s.likes[i])
+	}
+
+	writeJSON(w, http.StatusOK, page(r, items, limit, offset, len(s.likes)))
+}
+
+func (s *Server) listPlaylists(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var followed []*Playlist
+	for _, p := range s.playlists {
+		if p.Followed {
+			followed = append(followed, p)
+		}
+	}
+
+	limit, offset := pageParams(r)
+	items := []spotify.SimplePlaylist{}
+	for i := offset; i < min(offset+limit, len(followed)); i++ {
+		items = append(items, simplePlaylist(followed[i]))
+	}
+
+	writeJSON(w, http.StatusOK, page(r, items, limit, offset, len(followed)))
+}
+
+func (s *Server) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("userID") != s.opts.UserID {
+		writeError(w, http.StatusForbidden, "You cannot create a playlist for another user")
+		return
+	}
+
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, "Missing required field: name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.addPlaylist(body.Name, body.Description, nil)
+	writeJSON(w, http.StatusCreated, spotify.FullPlaylist{SimplePlaylist: simplePlaylist(p)})
+}
+
+func (s *Server) changeDetails(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	// Like Spotify, fields that are left out keep their value
+	if body.Name != "" {
+		p.Name = body.Name
+	}
+	if body.Description != "" {
+		p.Description = body.Description
+	}
+	p.SnapshotID = s.nextSnapshot()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) playlistTracks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.playlist(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := pageParams(r)
+	// Encoded by hand, spotify.PlaylistItem only knows how to decode the item types
+	items := []map[string]any{}
+	for i := offset; i < min(offset+limit, len(p.TrackIDs)); i++ {
+		items = append(items, map[string]any{
+			"added_at": time.Now().UTC().Format(spotify.TimestampLayout),
+			"track":    s.catalogTrack(p.TrackIDs[i]),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, page(r, items, limit, offset, len(p.TrackIDs)))
+}
+
+func (s *Server) addTracks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.URIs) == 0 {
+		writeError(w, http.StatusBadRequest, "No uris provided")
+		return
+	}
+	if len(body.URIs) > 100 {
+		writeError(w, http.StatusBadRequest, "Too many uris, at most 100 are allowed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	for _, uri := range body.URIs {
+		p.TrackIDs = append(p.TrackIDs, strings.TrimPrefix(uri, "spotify:track:"))
+	}
+	p.SnapshotID = s.nextSnapshot()
+
+	writeJSON(w, http.StatusCreated, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+func (s *Server) reorderTracks(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		RangeStart   int `json:"range_start"`
+		RangeLength  int `json:"range_length"`
+		InsertBefore int `json:"insert_before"`
+	}{RangeLength: 1}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	start, end := body.RangeStart, body.RangeStart+body.RangeLength
+	if start < 0 || body.RangeLength < 1 || end > len(p.TrackIDs) || body.InsertBefore < 0 || body.InsertBefore > len(p.TrackIDs) {
+		writeError(w, http.StatusBadRequest, "Range out of bounds")
+		return
+	}
+
+	moved := append([]string(nil), p.TrackIDs[start:end]...)
+	rest := append(append([]string(nil), p.TrackIDs[:start]...), p.TrackIDs[end:]...)
+	insert := body.InsertBefore
+	if insert > end {
+		insert -= len(moved)
+	} else if insert > start {
+		insert = start
+	}
+	p.TrackIDs = append(append(rest[:insert:insert], moved...), rest[insert:]...)
+	p.SnapshotID = s.nextSnapshot()
+
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+func (s *Server) removeTracks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Tracks) == 0 {
+		writeError(w, http.StatusBadRequest, "No tracks provided")
+		return
+	}
+	if len(body.Tracks) > 100 {
+		writeError(w, http.StatusBadRequest, "Too many tracks, at most 100 are allowed")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.ownPlaylist(w, r)
+	if !ok {
+		return
+	}
+
+	remove := make(map[string]bool, len(body.Tracks))
+	for _, track := range body.Tracks {
+		remove[strings.TrimPrefix(track.URI, "spotify:track:")] = true
+	}
+
+	// Like Spotify, every occurrence of a track is removed
+	trackIDs := p.TrackIDs[:0]
+	for _, id := range p.TrackIDs {
+		if !remove[id] {
+			trackIDs = append(trackIDs, id)
+		}
+	}
+	p.TrackIDs = trackIDs
+	p.SnapshotID = s.nextSnapshot()
+
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+func (s *Server) follow(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.playlist(w, r)
+	if !ok {
+		return
+	}
+	p.Followed = true
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) unfollow(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.playlist(w, r)
+	if !ok {
+		return
+	}
+	p.Followed = false
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) severalArtists(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 50 {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+
+	artists := make([]*spotify.FullArtist, 0, len(ids))
+	for _, id := range ids {
+		n, err := strconv.Atoi(strings.TrimPrefix(id, "artist"))
+		if err != nil || n < 0 || n >= len(s.artists) {
+			artists = append(artists, nil)
+			continue
+		}
+		artist := s.artists[n]
+		artists = append(artists, &artist)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"artists": artists})
+}
+
+// playlist looks up the playlist in the path, answering 404 when it does not exist.
+// Must be called with mu held.
+func (s *Server) playlist(w http.ResponseWriter, r *http.Request) (*Playlist, bool) {
+	id := r.PathValue("id")
+	for _, p := range s.playlists {
+		if p.ID == id {
+			return p, true
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Not found.")
+	return nil, false
+}
+
+// ownPlaylist looks up a playlist the fake user may change, answering 404 or 403 otherwise.
+// Must be called with mu held.
+func (s *Server) ownPlaylist(w http.ResponseWriter, r *http.Request) (*Playlist, bool) {
+	p, ok := s.playlist(w, r)
+	if !ok {
+		return nil, false
+	}
+	if p.OwnerID != s.opts.UserID {
+		writeError(w, http.StatusForbidden, "You cannot add tracks to a playlist you don't own.")
+		return nil, false
+	}
+	return p, true
+}
+
+// addPlaylist creates a followed playlist of the fake user. Must be called with mu held.
+func (s *Server) addPlaylist(name, description string, trackIDs []string) *Playlist {
+	p := &Playlist{
+		ID:          fmt.Sprintf("playlist%d", len(s.playlists)),
+		Name:        name,
+		Description: description,
+		OwnerID:     s.opts.UserID,
+		TrackIDs:    append([]string(nil), trackIDs...),
+		SnapshotID:  s.nextSnapshot(),
+		Followed:    true,
+	}
+	s.playlists = append(s.playlists, p)
+	return p
+}
+
+// nextSnapshot returns a new snapshot ID. Must be called with mu held.
+func (s *Server) nextSnapshot() string {
+	s.snapshots++
+	return fmt.Sprintf("%s-%d", s.instance, s.snapshots)
+}
+
+// catalogTrack returns a known track, or a bare one for IDs the server did not generate.
+// Must be called with mu held.
+func (s *Server) catalogTrack(id string) spotify.FullTrack {
+	if track, ok := s.catalog[id]; ok {
+		return track
+	}
+	return spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: spotify.ID(id), Name: id, Type: "track"}}
+}
+
+// generate creates the numbered library, the artist of a song cycles through the artists
+func (s *Server) generate() {
+	for i := range max(s.opts.Artists, 1) {
+		s.artists = append(s.artists, spotify.FullArtist{
+			SimpleArtist: spotify.SimpleArtist{ID: artistID(i), Name: fmt.Sprintf("Artist %d", i)},
+			Genres:       genres[i%len(genres)],
+		})
+	}
+
+	for i := range s.opts.LikedSongs {
+		artist := i % len(s.artists)
+		s.like(newTrack(i, fmt.Sprintf("Track %d", i), fmt.Sprintf("Album %d", artist), 180000, s.artists[artist]),
+			time.Unix(int64(1700000000-i*60), 0))
+	}
+}
+
+// like adds a track to the end of the liked songs, so tracks must be liked newest first
+func (s *Server) like(track spotify.FullTrack, addedAt time.Time) {
+	s.catalog[track.ID.String()] = track
+	s.likes = append(s.likes, spotify.SavedTrack{
+		AddedAt:   addedAt.UTC().Format(spotify.TimestampLayout),
+		FullTrack: track,
+	})
+}
+
+func newTrack(i int, name, album string, durationMs int, artists ...spotify.FullArtist) spotify.FullTrack {
+	track := spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:       spotify.ID(fmt.Sprintf("track%d", i)),
+			Name:     name,
+			Duration: spotify.Numeric(durationMs),
+			Type:     "track",
+		},
+		Album: spotify.SimpleAlbum{Name: album},
+	}
+	for _, artist := range artists {
+		track.Artists = append(track.Artists, artist.SimpleArtist)
+	}
+	return track
+}
+
+func artistID(i int) spotify.ID {
+	return spotify.ID(fmt.Sprintf("artist%d", i))
+}
+
+func simplePlaylist(p *Playlist) spotify.SimplePlaylist {
+	return spotify.SimplePlaylist{
+		ID:          spotify.ID(p.ID),
+		Name:        p.Name,
+		Description: p.Description,
+		Owner:       spotify.User{ID: p.OwnerID},
+		SnapshotID:  p.SnapshotID,
+		Tracks:      spotify.PlaylistTracks{Total: spotify.Numeric(len(p.TrackIDs))},
+	}
+}
+
+// page wraps items in the paging object Spotify returns for lists
+func page(r *http.Request, items any, limit, offset, total int) map[string]any {
+	next := ""
+	if offset+limit < total {
+		next = fmt.Sprintf("%s?limit=%d&offset=%d", r.URL.Path, limit, offset+limit)
+	}
+	return map[string]any{
+		"href":   r.URL.String(),
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+		"total":  total,
+		"next":   next,
+	}
+}
+
+func pageParams(r *http.Request) (limit, offset int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	offset, err = strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with the error object Spotify uses
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"status":  status,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+const (
+	minRate         = rate.Limit(0.2) // Rates never drop below one request every 5 seconds
+	decreaseFactor  = 0.5             // A 429 halves the rate
+	increaseAfter   = 20              // Successful requests in a row before the rate goes up
+	increaseStep    = 0.1             // Fraction of the configured rate added per increase
+	idleUserTimeout = 5 * time.Minute // Users without requests for this long give up their share
+	cleanupInterval = 1 * time.Minute
+	throttleWindow  = 1 * time.Second // 429s of requests that were in flight together count once
+)
+
+// RateLimits configures how fast the app calls the Spotify API
+type RateLimits struct {
+	Rate      float64 // Requests per second across all users
+	Burst     int
+	UserRate  float64 // Requests per second of a single user
+	UserBurst int
+
+	Concurrency int // Pages of a single fetch requested at once
+}
+
+// adaptiveLimit is a token bucket whose rate adapts to Spotify's feedback:
+// it is cut multiplicatively on rate limiting and grows additively after sustained success.
+type adaptiveLimit struct {
+	limiter   *rate.Limiter
+	max       rate.Limit // Configured rate, never exceeded
+	current   rate.Limit // Rate learned from feedback
+	successes int
+	lastUsed  time.Time
+	throttled time.Time // When the rate was last lowered
+}
+
+func newAdaptiveLimit(r float64, burst int) *adaptiveLimit {
+	return &adaptiveLimit{
+		limiter:  rate.NewLimiter(rate.Limit(r), burst),
+		max:      rate.Limit(r),
+		current:  rate.Limit(r),
+		lastUsed: time.Now(),
+	}
+}
+
+// throttle lowers the rate after a 429, and reports whether it changed
+func (l *adaptiveLimit) throttle() bool {
+	l.successes = 0
+	if time.Since(l.throttled) < throttleWindow {
+		return false
+	}
+	l.throttled = time.Now()
+	l.current = max(l.current*decreaseFactor, minRate)
+	return true
+}
+
+// succeeded raises the rate after enough successful requests, and reports whether it changed
+func (l *adaptiveLimit) succeeded() bool {
+	l.successes++
+	if l.successes < increaseAfter || l.current >= l.max {
+		return false
+	}
+	l.successes = 0
+	l.current = min(l.current+l.max*increaseStep, l.max)
+	return true
+}
+
+// rateLimiter paces Spotify API requests. Every user has their own adaptive limit, capped at a fair share
+// of the global limit, so one user's large library cannot starve everyone else.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limits RateLimits
+	global *adaptiveLimit
+	users  map[string]*adaptiveLimit
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	r := &rateLimiter{
+		limits: limits,
+		global: newAdaptiveLimit(limits.Rate, limits.Burst),
+		users:  make(map[string]*adaptiveLimit),
+	}
+
+	// Start cleanup goroutine
+	go r.cleanupIdleUsers()
+
+	return r
+}
+
+// Wait blocks until the user may send a request. Requests without a user, such as during login,
+// only count against the global limit.
+func (r *rateLimiter) Wait(ctx context.Context, userID string) error {
+	if userID != "" {
+		if err := r.user(userID).Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return r.global.limiter.Wait(ctx)
+}
+
+// Throttled slows down the user and the app after Spotify rate limited a request.
+// Spotify limits the app as a whole, so the global rate is lowered as well.
+func (r *rateLimiter) Throttled(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := r.global.throttle()
+	if user, exists := r.users[userID]; exists && user.throttle() {
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	r.rebalance()
+
+	log.Warn().
+		Str("userID", userID).
+		Float64("globalRate", float64(r.global.current)).
+		Msg("Rate limited by Spotify, slowing down")
+}
+
+// Succeeded records a request Spotify did not rate limit
+func (r *rateLimiter) Succeeded(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := r.global.succeeded()
+	if user, exists := r.users[userID]; exists && user.succeeded() {
+		changed = true
+	}
+	if changed {
+		r.rebalance()
+	}
+}
+
+// user returns the limit of a user, creating it on their first request
+func (r *rateLimiter) user(userID string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[userID]
+	if !exists {
+		user = newAdaptiveLimit(r.limits.UserRate, r.limits.UserBurst)
+		r.users[userID] = user
+		r.rebalance()
+	}
+	user.lastUsed = time.Now()
+
+	return user.limiter
+}
+
+// rebalance applies the learned rates, capping every user at an equal share of the global rate.
+// Must be called with mu held.
+func (r *rateLimiter) rebalance() {
+	r.global.limiter.SetLimit(r.global.current)
+
+	if len(r.users) == 0 {
+		return
+	}
+	share := max(r.global.current/rate.Limit(len(r.users)), minRate)
+	for _, user := range r.users {
+		user.limiter.SetLimit(min(user.current, share))
+	}
+}
+
+// cleanupIdleUsers periodically drops users that stopped sending requests, returning their share
+func (r *rateLimiter) cleanupIdleUsers() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.mu.Lock()
+		removed := false
+		for userID, user := range r.users {
+			if time.Since(user.lastUsed) > idleUserTimeout {
+				delete(r.users, userID)
+				removed = true
+			}
+		}
+		if removed {
+			r.rebalance()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// limitTransport waits for the rate limiter before every request, including retries,
+// and reports the outcome back so the limits adapt
+type limitTransport struct {
+	limiter *rateLimiter
+	userID  string
+	base    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), t.userID); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		t.limiter.Throttled(t.userID)
+	case resp.StatusCode < http.StatusInternalServerError:
+		t.limiter.Succeeded(t.userID)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,123 @@
+package spotify
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestAdaptiveLimitDecreasesMultiplicatively(t *testing.T) {
+	l := newAdaptiveLimit(10, 1)
+
+	if !l.throttle() || l.current != 5 {
+		t.Fatalf("rate after a 429 = %v, want 5", l.current)
+	}
+
+	// 429s of requests that were in flight together count once
+	if l.throttle() || l.current != 5 {
+		t.Errorf("rate after a second 429 in the same window = %v, want 5", l.current)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.throttled = time.Time{}
+		l.throttle()
+	}
+	if l.current != minRate {
+		t.Errorf("rate after many 429s = %v, want the minimum %v", l.current, minRate)
+	}
+}
+
+func TestAdaptiveLimitIncreasesAdditively(t *testing.T) {
+	l := newAdaptiveLimit(10, 1)
+	l.throttle()
+
+	for i := 1; i < increaseAfter; i++ {
+		if l.succeeded() {
+			t.Fatalf("rate went up after %d successes, want %d", i, increaseAfter)
+		}
+	}
+	if !l.succeeded() || l.current != 6 {
+		t.Fatalf("rate after %d successes = %v, want 6", increaseAfter, l.current)
+	}
+
+	// A 429 starts the count over
+	for i := 1; i < increaseAfter; i++ {
+		l.succeeded()
+	}
+	l.throttled = time.Time{}
+	l.throttle()
+	if l.succeeded() {
+		t.Error("rate went up right after a 429")
+	}
+
+	// The configured rate is never exceeded
+	for i := 0; i < 100*increaseAfter; i++ {
+		l.succeeded()
+	}
+	if l.current != l.max {
+		t.Errorf("rate after sustained success = %v, want the configured %v", l.current, l.max)
+	}
+}
+
+func TestRateLimiterSharesGlobalRate(t *testing.T) {
+	r := newRateLimiter(RateLimits{Rate: 10, Burst: 10, UserRate: 8, UserBurst: 8})
+
+	r.user("a")
+	if got := r.users["a"].limiter.Limit(); got != 8 {
+		t.Errorf("single user rate = %v, want the user rate 8", got)
+	}
+
+	r.user("b")
+	for _, userID := range []string{"a", "b"} {
+		if got := r.users[userID].limiter.Limit(); got != 5 {
+			t.Errorf("rate of user %s = %v, want an equal share of 5", userID, got)
+		}
+	}
+
+	// Spotify limits the app as a whole, a 429 slows everyone down
+	r.Throttled("a")
+	if got := r.global.limiter.Limit(); got != 5 {
+		t.Errorf("global rate after a 429 = %v, want 5", got)
+	}
+	if got := r.users["a"].limiter.Limit(); got != 2.5 {
+		t.Errorf("rate of the throttled user = %v, want 2.5", got)
+	}
+	if got := r.users["b"].limiter.Limit(); got != 2.5 {
+		t.Errorf("rate of the other user = %v, want its share of 2.5", got)
+	}
+}
+
+func TestLimitTransportReportsOutcome(t *testing.T) {
+	tests := []struct {
+		status int
+		want   rate.Limit
+		count  int
+	}{
+		{http.StatusOK, 10, 1},
+		{http.StatusNotFound, 10, 1},
+		{http.StatusTooManyRequests, 5, 0},
+		{http.StatusBadGateway, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			r := newRateLimiter(RateLimits{Rate: 10, Burst: 10, UserRate: 10, UserBurst: 10})
+			transport := &limitTransport{limiter: r, userID: "user", base: &stubTransport{statuses: []int{tt.status}}}
+
+			resp, err := transport.RoundTrip(newRequest(t, t.Context(), http.MethodGet))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := r.global.current; got != tt.want {
+				t.Errorf("global rate = %v, want %v", got, tt.want)
+			}
+			if got := r.global.successes; got != tt.count {
+				t.Errorf("successes = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package spotify
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxRetries       = 4
+	baseBackoff      = 500 * time.Millisecond
+	maxBackoff       = 10 * time.Second
+	maxRetryAfter    = 60 * time.Second // Longer waits are returned to the caller instead
+	defaultRetryWait = 5 * time.Second  // Used when a 429 has no usable Retry-After
+)
+
+// retryTransport retries Spotify API requests. Rate limited requests wait for the Retry-After
+// the response asks for, server errors back off exponentially with jitter. A server error may
+// come after the request was applied, so only idempotent requests are retried on one.
+// When it gives up on rate limiting it returns an *APIError carrying the Retry-After.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			// The previous attempt consumed the body
+			if req.GetBody == nil {
+				return nil, &APIError{Status: http.StatusServiceUnavailable, Message: "request cannot be retried"}
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var wait time.Duration
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait = retryAfter(resp)
+			if wait > maxRetryAfter || attempt >= maxRetries {
+				drain(resp)
+				return nil, &APIError{
+					Status:     http.StatusTooManyRequests,
+					Message:    "rate limit exceeded",
+					RetryAfter: wait,
+				}
+			}
+		case resp.StatusCode >= http.StatusInternalServerError && attempt < maxRetries && idempotent(req):
+			wait = backoff(attempt)
+		default:
+			return resp, nil
+		}
+
+		drain(resp)
+		log.Warn().
+			Int("status", resp.StatusCode).
+			Int("attempt", attempt+1).
+			Dur("wait", wait).
+			Str("path", req.URL.Path).
+			Msg("Spotify request failed, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// idempotent reports whether repeating the request cannot apply it twice
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads the Retry-After header, in seconds or as an HTTP date
+func retryAfter(resp *http.Response) time.Duration {
+	raw := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(raw); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(raw); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return defaultRetryWait
+}
+
+// backoff returns a random wait below an exponentially growing ceiling
+func backoff(attempt int) time.Duration {
+	ceiling := baseBackoff << attempt
+	if ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return ceiling/2 + rand.N(ceiling/2)
+}
+
+// drain discards a response that will not be returned, so its connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,152 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// stubTransport answers requests with the given statuses in turn and counts the calls
+type stubTransport struct {
+	statuses []int
+	header   http.Header
+	calls    int
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := t.statuses[min(t.calls, len(t.statuses)-1)]
+	t.calls++
+	header := t.header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func newRequest(t *testing.T, ctx context.Context, method string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, "https://api.spotify.com/v1/me/tracks", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRetryTransportServerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		calls  int
+		status int
+	}{
+		{"GET is retried", http.MethodGet, 2, http.StatusOK},
+		{"PUT is retried", http.MethodPut, 2, http.StatusOK},
+		{"DELETE is retried", http.MethodDelete, 2, http.StatusOK},
+		{"POST is not retried", http.MethodPost, 1, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubTransport{statuses: []int{http.StatusBadGateway, http.StatusOK}}
+			resp, err := (&retryTransport{base: stub}).RoundTrip(newRequest(t, context.Background(), tt.method))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if stub.calls != tt.calls {
+				t.Errorf("calls = %d, want %d", stub.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetryTransportRetriesRateLimitedPost(t *testing.T) {
+	stub := &stubTransport{
+		statuses: []int{http.StatusTooManyRequests, http.StatusCreated},
+		header:   http.Header{"Retry-After": {"0"}},
+	}
+	resp, err := (&retryTransport{base: stub}).RoundTrip(newRequest(t, context.Background(), http.MethodPost))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || stub.calls != 2 {
+		t.Errorf("status = %d after %d calls, want 201 after 2", resp.StatusCode, stub.calls)
+	}
+}
+
+func TestRetryTransportGivesUpOnLongRetryAfter(t *testing.T) {
+	stub := &stubTransport{
+		statuses: []int{http.StatusTooManyRequests},
+		header:   http.Header{"Retry-After": {"120"}},
+	}
+	_, err := (&retryTransport{base: stub}).RoundTrip(newRequest(t, context.Background(), http.MethodGet))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.RetryAfter != 120*time.Second {
+		t.Errorf("RetryAfter = %s, want 2m0s", apiErr.RetryAfter)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("error does not match ErrRateLimited")
+	}
+	if stub.calls != 1 {
+		t.Errorf("calls = %d, want 1", stub.calls)
+	}
+}
+
+func TestRetryTransportStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stub := &stubTransport{
+		statuses: []int{http.StatusTooManyRequests},
+		header:   http.Header{"Retry-After": {"5"}},
+	}
+	_, err := (&retryTransport{base: stub}).RoundTrip(newRequest(t, ctx, http.MethodGet))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusBadGateway, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		err := apiError(spotify.Error{Status: tt.status, Message: "failed"})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: %v does not match %v", tt.status, err, tt.want)
+		}
+	}
+
+	if err := apiError(spotify.Error{Status: http.StatusBadRequest}); errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNotFound) {
+		t.Errorf("bad request matched a sentinel: %v", err)
+	}
+
+	other := errors.New("network down")
+	if err := apiError(other); err != other {
+		t.Errorf("apiError changed a non-API error: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
+)
+
+// clientIdleTimeout is how long an unused client stays cached
+const clientIdleTimeout = 1 * time.Hour
+
+var ErrTokenRefresh = errors.New("failed to refresh token")
+
+// TokenManager hands out Spotify clients for sessions. Each session gets one cached client whose
+// token source writes every refreshed token back to the session store, including refreshes the
+// oauth2 transport does in the middle of a long operation. Refreshes of a session are serialized.
+type TokenManager struct {
+	client   *Client
+	sessions session.SessionStore
+	clients  map[string]*sessionClient // sessionID -> client
+	mu       sync.Mutex
+}
+
+// sessionClient is the cached client of a session
+type sessionClient struct {
+	userID   string
+	source   *persistingTokenSource
+	client   *spotify.Client
+	lastUsed time.Time
+}
+
+// NewTokenManager creates a new token manager
+func NewTokenManager(client *Client, sessions session.SessionStore) *TokenManager {
+	manager := &TokenManager{
+		client:   client,
+		sessions: sessions,
+		clients:  make(map[string]*sessionClient),
+	}
+
+	// Start cleanup goroutine
+	go manager.cleanupIdleClients()
+
+	return manager
+}
+
+// ClientFor returns the Spotify client of a session. The token is refreshed first if it expired,
+// ErrTokenRefresh is returned when that fails.
+func (m *TokenManager) ClientFor(sess *session.Session) (Conn, error) {
+	entry := m.entry(sess)
+
+	if _, err := entry.source.Token(); err != nil {
+		log.Error().Err(err).Str("userID", sess.UserID).Msg("Failed to refresh token")
+		return nil, ErrTokenRefresh
+	}
+
+	return entry.client, nil
+}
+
+// Forget drops the cached client of a session, for example after logout
+func (m *TokenManager) Forget(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.clients, sessionID)
+}
+
+// ForgetUser drops the cached clients of all sessions of the user
+func (m *TokenManager) ForgetUser(userID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for sessionID, entry := range m.clients {
+		if entry.userID == userID {
+			delete(m.clients, sessionID)
+		}
+	}
+}
+
+// entry returns the cached client of a session, creating it when missing or when the session
+// holds a newer token than the cache, for example after another instance refreshed it
+func (m *TokenManager) entry(sess *session.Session) *sessionClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.clients[sess.ID]
+	if exists && !entry.source.olderThan(sess.Token) {
+		entry.lastUsed = time.Now()
+		return entry
+	}
+
+	source := &persistingTokenSource{
+		sessionID: sess.ID,
+		sessions:  m.sessions,
+		// The token source outlives the request, refreshes must not be cancelled with it
+		base: m.client.TokenSource(context.Background(), sess.Token),
+	}
+	source.current.Store(sess.Token)
+
+	entry = &sessionClient{
+		userID:   sess.UserID,
+		source:   source,
+		client:   m.client.newAPIClient(source, sess.UserID),
+		lastUsed: time.Now(),
+	}
+	m.clients[sess.ID] = entry
+
+	return entry
+}
+
+// cleanupIdleClients periodically drops clients that have not been used for a while
+func (m *TokenManager) cleanupIdleClients() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.mu.Lock()
+		cutoff := time.Now().Add(-clientIdleTimeout)
+
+		for sessionID, entry := range m.clients {
+			if entry.lastUsed.Before(cutoff) {
+				delete(m.clients, sessionID)
+			}
+		}
+
+		m.mu.Unlock()
+	}
+}
+
+// persistingTokenSource refreshes a session's token and stores every new token in the session store
+type persistingTokenSource struct {
+	sessionID string
+	sessions  session.SessionStore
+	base      oauth2.TokenSource
+	mu        sync.Mutex // Serializes refreshes, so a refresh token is only used once
+
+	// current is the last token handed out. It is read without mu, so checking it does not
+	// wait for a refresh in progress, which would hold up the TokenManager for every session.
+	current atomic.Pointer[oauth2.Token]
+}
+
+// Token returns a valid token, refreshing and persisting it if needed
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if current := s.current.Load(); token.AccessToken != current.AccessToken {
+		// Spotify may rotate the refresh token, keep the previous one when it does not
+		if token.RefreshToken == "" {
+			token.RefreshToken = current.RefreshToken
+		}
+		if err := s.sessions.Update(s.sessionID, token); err != nil {
+			log.Error().Err(err).Str("sessionID", session.PublicID(s.sessionID)).Msg("Failed to store refreshed token")
+		}
+		s.current.Store(token)
+	}
+
+	return token, nil
+}
+
+// olderThan reports whether token is newer than the one the source holds
+func (s *persistingTokenSource) olderThan(token *oauth2.Token) bool {
+	current := s.current.Load()
+	return token.AccessToken != current.AccessToken && token.Expiry.After(current.Expiry)
+}
@@ -0,0 +1,207 @@
+package spotify
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/adelvecchio/spotify-playlist-sorter/internal/session"
+)
+
+// refreshServer is a token endpoint that hands out a new access token for every refresh
+type refreshServer struct {
+	*httptest.Server
+	stall    chan struct{} // Refreshes of the stalled refresh token wait until it is closed
+	stalled  chan struct{} // Receives when a stalled refresh arrives
+	mu       sync.Mutex
+	refreshs map[string]int // Refresh token -> refreshes
+}
+
+func newRefreshServer(t *testing.T, stalledRefreshToken string) *refreshServer {
+	t.Helper()
+
+	s := &refreshServer{
+		stall:    make(chan struct{}),
+		stalled:  make(chan struct{}, 10),
+		refreshs: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshToken := r.FormValue("refresh_token")
+		if refreshToken == stalledRefreshToken {
+			s.stalled <- struct{}{}
+			<-s.stall
+		}
+
+		s.mu.Lock()
+		s.refreshs[refreshToken]++
+		n := s.refreshs[refreshToken]
+		s.mu.Unlock()
+
+		// Spotify leaves out the refresh token when it keeps it
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"%s-%d","token_type":"Bearer","expires_in":3600}`, refreshToken, n)
+	}))
+	t.Cleanup(func() {
+		s.release()
+		s.Close()
+	})
+	return s
+}
+
+// release lets stalled refreshes finish
+func (s *refreshServer) release() {
+	select {
+	case <-s.stall:
+	default:
+		close(s.stall)
+	}
+}
+
+func (s *refreshServer) refreshes(refreshToken string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refreshs[refreshToken]
+}
+
+// newTestTokenManager returns a token manager refreshing tokens at the server
+func newTestTokenManager(server *refreshServer, sessions session.SessionStore) *TokenManager {
+	client := NewClient("client-id", "client-secret", "http://localhost/callback", false, testLimits)
+	client.oauth.Endpoint.TokenURL = server.URL
+	return NewTokenManager(client, sessions)
+}
+
+// createSession logs in a user whose token expires at expiry
+func createSession(t *testing.T, sessions session.SessionStore, id string, expiry time.Time) *session.Session {
+	t.Helper()
+
+	token := &oauth2.Token{AccessToken: id + "-access", RefreshToken: id + "-refresh", Expiry: expiry}
+	sess, err := sessions.Create(id, "user-"+id, token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestTokenManagerPersistsRefreshedToken(t *testing.T) {
+	server := newRefreshServer(t, "")
+	sessions := session.NewMemoryStore()
+	manager := newTestTokenManager(server, sessions)
+	sess := createSession(t, sessions, "a", time.Now().Add(-time.Minute))
+
+	if _, err := manager.ClientFor(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := sessions.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Token.AccessToken != "a-refresh-1" {
+		t.Errorf("stored access token %q, want the refreshed one", stored.Token.AccessToken)
+	}
+	if stored.Token.RefreshToken != "a-refresh" {
+		t.Errorf("stored refresh token %q, want the one Spotify kept", stored.Token.RefreshToken)
+	}
+}
+
+func TestTokenManagerSerializesRefreshes(t *testing.T) {
+	server := newRefreshServer(t, "")
+	sessions := session.NewMemoryStore()
+	manager := newTestTokenManager(server, sessions)
+	sess := createSession(t, sessions, "a", time.Now().Add(-time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := manager.ClientFor(sess); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := server.refreshes("a-refresh"); n != 1 {
+		t.Errorf("refresh token used %d times, want once", n)
+	}
+}
+
+func TestTokenManagerReplacesClientWithNewerToken(t *testing.T) {
+	server := newRefreshServer(t, "")
+	sessions := session.NewMemoryStore()
+	manager := newTestTokenManager(server, sessions)
+	sess := createSession(t, sessions, "a", time.Now().Add(time.Hour))
+
+	first, err := manager.ClientFor(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := manager.ClientFor(sess); again != first {
+		t.Error("same token got a new client")
+	}
+
+	// Another instance refreshed the token and stored it in the session
+	if err := sessions.Update("a", &oauth2.Token{AccessToken: "newer", RefreshToken: "a-refresh", Expiry: time.Now().Add(2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	sess, err = sessions.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced, _ := manager.ClientFor(sess); replaced == first {
+		t.Error("newer token kept the cached client")
+	}
+	if n := server.refreshes("a-refresh"); n != 0 {
+		t.Errorf("refreshed %d times, want none", n)
+	}
+}
+
+func TestTokenManagerRefreshDoesNotBlockOtherSessions(t *testing.T) {
+	server := newRefreshServer(t, "a-refresh")
+	sessions := session.NewMemoryStore()
+	manager := newTestTokenManager(server, sessions)
+	stalled := createSession(t, sessions, "a", time.Now().Add(-time.Minute))
+	other := createSession(t, sessions, "b", time.Now().Add(time.Hour))
+
+	// Two requests of the stalled session, one refreshing and one waiting for that refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := manager.ClientFor(stalled); err != nil {
+				t.Error(err)
+			}
+		}()
+		if i == 0 {
+			<-server.stalled
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := manager.ClientFor(other)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("another session waited for the stalled refresh")
+	}
+
+	server.release()
+	wg.Wait()
+	if n := server.refreshes("a-refresh"); n != 1 {
+		t.Errorf("stalled session refreshed %d times, want once", n)
+	}
+}
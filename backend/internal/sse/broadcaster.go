@@ -1,6 +1,7 @@
 package sse
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
 )
@@ -19,19 +20,21 @@ const (
 type ProgressPhase string
 
 const (
-	PhaseFetchingLikedSongs  ProgressPhase = "fetching_liked_songs"
-	PhaseFetchingPlaylists   ProgressPhase = "fetching_playlists"
-	PhaseFetchingArtists     ProgressPhase = "fetching_artists"
-	PhaseAnalyzing           ProgressPhase = "analyzing"
-	PhaseGeneratingPlan      ProgressPhase = "generating_plan"
-	PhaseCreatingPlaylists   ProgressPhase = "creating_playlists"
-	PhaseAddingTracks        ProgressPhase = "adding_tracks"
-	PhaseRemovingTracks      ProgressPhase = "removing_tracks"
-	PhaseComplete            ProgressPhase = "complete"
+	PhaseFetchingLikedSongs ProgressPhase = "fetching_liked_songs"
+	PhaseFetchingPlaylists  ProgressPhase = "fetching_playlists"
+	PhaseFetchingArtists    ProgressPhase = "fetching_artists"
+	PhaseAnalyzing          ProgressPhase = "analyzing"
+	PhaseGeneratingPlan     ProgressPhase = "generating_plan"
+	PhaseCreatingPlaylists  ProgressPhase = "creating_playlists"
+	PhaseAddingTracks       ProgressPhase = "adding_tracks"
+	PhaseRemovingTracks     ProgressPhase = "removing_tracks"
+	PhaseRollingBack        ProgressPhase = "rolling_back"
+	PhaseComplete           ProgressPhase = "complete"
 )
 
 // ProgressEvent represents a progress update event
 type ProgressEvent struct {
+	JobID   string        `json:"jobId,omitempty"` // Job that produced the event, if any
 	Type    EventType     `json:"type"`
 	Phase   ProgressPhase `json:"phase,omitempty"`
 	Current int           `json:"current,omitempty"`
@@ -39,6 +42,20 @@ type ProgressEvent struct {
 	Message string        `json:"message"`
 }
 
+// jobIDKey is the context key for the job ID events are tagged with
+type jobIDKey struct{}
+
+// ContextWithJobID returns a context whose events are tagged with the job ID
+func ContextWithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey{}, jobID)
+}
+
+// JobIDFromContext returns the job ID stored in the context, or an empty string
+func JobIDFromContext(ctx context.Context) string {
+	jobID, _ := ctx.Value(jobIDKey{}).(string)
+	return jobID
+}
+
 // Client represents a connected SSE client
 type Client struct {
 	UserID  string
@@ -110,8 +127,9 @@ func (b *Broadcaster) Broadcast(userID string, event *ProgressEvent) {
 }
 
 // SendProgress sends a progress update
-func (b *Broadcaster) SendProgress(userID string, phase ProgressPhase, current, total int, message string) {
+func (b *Broadcaster) SendProgress(ctx context.Context, userID string, phase ProgressPhase, current, total int, message string) {
 	b.Broadcast(userID, &ProgressEvent{
+		JobID:   JobIDFromContext(ctx),
 		Type:    EventTypeProgress,
 		Phase:   phase,
 		Current: current,
@@ -121,24 +139,27 @@ func (b *Broadcaster) SendProgress(userID string, phase ProgressPhase, current,
 }
 
 // SendInfo sends an info message
-func (b *Broadcaster) SendInfo(userID string, message string) {
+func (b *Broadcaster) SendInfo(ctx context.Context, userID string, message string) {
 	b.Broadcast(userID, &ProgressEvent{
+		JobID:   JobIDFromContext(ctx),
 		Type:    EventTypeInfo,
 		Message: message,
 	})
 }
 
 // SendError sends an error message
-func (b *Broadcaster) SendError(userID string, message string) {
+func (b *Broadcaster) SendError(ctx context.Context, userID string, message string) {
 	b.Broadcast(userID, &ProgressEvent{
+		JobID:   JobIDFromContext(ctx),
 		Type:    EventTypeError,
 		Message: message,
 	})
 }
 
 // SendComplete sends a completion message
-func (b *Broadcaster) SendComplete(userID string, message string) {
+func (b *Broadcaster) SendComplete(ctx context.Context, userID string, message string) {
 	b.Broadcast(userID, &ProgressEvent{
+		JobID:   JobIDFromContext(ctx),
 		Type:    EventTypeComplete,
 		Phase:   PhaseComplete,
 		Message: message,
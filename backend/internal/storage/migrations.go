@@ -0,0 +1,93 @@
+package storage
+
+// migrations are applied in order, one statement each.
+// Never edit a migration that has been released, append a new one instead.
+var migrations = []string{
+	// 1: plan executions and their checkpoints
+	`CREATE TABLE executions (
+		id          TEXT PRIMARY KEY,
+		user_id     TEXT NOT NULL,
+		plan_id     TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		started_at  INTEGER NOT NULL,
+		data        TEXT NOT NULL,
+		plan        TEXT NOT NULL
+	)`,
+	// 2: list executions per user
+	`CREATE INDEX idx_executions_user ON executions (user_id, started_at)`,
+	// 3: execution journal, one row per mutation applied to Spotify
+	`CREATE TABLE execution_journal (
+		execution_id TEXT NOT NULL REFERENCES executions (id) ON DELETE CASCADE,
+		seq          INTEGER NOT NULL,
+		data         TEXT NOT NULL,
+		PRIMARY KEY (execution_id, seq)
+	)`,
+	// 4: user sessions with their Spotify tokens, by the hash of the session cookie
+	`CREATE TABLE sessions (
+		id_hash     TEXT PRIMARY KEY,
+		user_id     TEXT NOT NULL,
+		token       TEXT NOT NULL,
+		created_at  INTEGER NOT NULL,
+		expires_at  INTEGER NOT NULL
+	)`,
+	// 5: look up sessions per user
+	`CREATE INDEX idx_sessions_user ON sessions (user_id, created_at)`,
+	// 6: device of a session
+	`ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT ''`,
+	// 7: last request made with a session
+	`ALTER TABLE sessions ADD COLUMN last_seen_at INTEGER NOT NULL DEFAULT 0`,
+	// 8: short-lived single-use values such as OAuth states, one namespace per store
+	`CREATE TABLE ephemeral (
+		namespace   TEXT NOT NULL,
+		key         TEXT NOT NULL,
+		value       TEXT NOT NULL,
+		expires_at  INTEGER NOT NULL,
+		PRIMARY KEY (namespace, key)
+	)`,
+	// 9: personal API tokens, stored by the hash of their secret
+	`CREATE TABLE api_tokens (
+		id            TEXT PRIMARY KEY,
+		hash          TEXT NOT NULL UNIQUE,
+		user_id       TEXT NOT NULL,
+		session_id    TEXT NOT NULL,
+		name          TEXT NOT NULL,
+		hint          TEXT NOT NULL,
+		scopes        TEXT NOT NULL,
+		created_at    INTEGER NOT NULL,
+		last_used_at  INTEGER NOT NULL DEFAULT 0,
+		expires_at    INTEGER NOT NULL DEFAULT 0
+	)`,
+	// 10: list API tokens per user
+	`CREATE INDEX idx_api_tokens_user ON api_tokens (user_id, created_at)`,
+	// 11: artist genres shared by all analyses, genres are a JSON array
+	`CREATE TABLE artists (
+		id          TEXT PRIMARY KEY,
+		name        TEXT NOT NULL,
+		genres      TEXT NOT NULL,
+		fetched_at  INTEGER NOT NULL
+	)`,
+	// 12: last known liked songs per user for incremental syncs, tracks are a JSON array
+	`CREATE TABLE library_snapshots (
+		user_id       TEXT PRIMARY KEY,
+		tracks        TEXT NOT NULL,
+		synced_at     INTEGER NOT NULL,
+		full_sync_at  INTEGER NOT NULL
+	)`,
+	// 13: managed playlist contents by snapshot ID, track IDs are a JSON array
+	`CREATE TABLE playlist_contents (
+		playlist_id  TEXT PRIMARY KEY,
+		snapshot_id  TEXT NOT NULL,
+		track_ids    TEXT NOT NULL,
+		fetched_at   INTEGER NOT NULL
+	)`,
+	// 14: managed playlist of every genre per user, by playlist ID so renames keep the genre
+	`CREATE TABLE genre_playlists (
+		user_id        TEXT NOT NULL,
+		genre_key      TEXT NOT NULL,
+		playlist_id    TEXT NOT NULL,
+		registered_at  INTEGER NOT NULL,
+		PRIMARY KEY (user_id, genre_key)
+	)`,
+	// 15: songs unliked since the snapshot was synced, a JSON array like tracks
+	`ALTER TABLE library_snapshots ADD COLUMN unliked TEXT NOT NULL DEFAULT '[]'`,
+}
@@ -25,6 +25,6 @@ export function useExecuteSortPlan() {
   const disabledPlaylists = useUIStore((state) => state.disabledPlaylists);
 
   return useMutation({
-    mutationFn: () => api.executeSortPlan(isDryRun, enabledGroups, disabledPlaylists),
+    mutationFn: (planId?: string) => api.executeSortPlan(isDryRun, enabledGroups, disabledPlaylists, planId),
   });
 }
@@ -1,17 +1,27 @@
-import type { AuthResponse, User, SortPlan, LibraryStats } from './types';
+import type { AuthResponse, User, UserSession, ApiToken, CreatedApiToken, SortPlan, LibraryStats } from './types';
 
 const API_BASE = '/api';
 
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+// The backend sets a CSRF token cookie that state-changing requests echo in a header
+function getCsrfToken(): string {
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : '';
+}
+
 class ApiClient {
   private async fetch<T>(
     endpoint: string,
     options?: RequestInit
   ): Promise<T> {
+    const method = (options?.method ?? 'GET').toUpperCase();
     const response = await fetch(`${API_BASE}${endpoint}`, {
       ...options,
       credentials: 'include',
       headers: {
         'Content-Type': 'application/json',
+        ...(SAFE_METHODS.includes(method) ? {} : { 'X-CSRF-Token': getCsrfToken() }),
         ...options?.headers,
       },
     });
@@ -45,6 +55,35 @@ class ApiClient {
     await this.fetch('/auth/logout', { method: 'POST' });
   }
 
+  async listSessions(): Promise<UserSession[]> {
+    const response = await this.fetch<{ sessions: UserSession[] }>('/auth/sessions');
+    return response.sessions;
+  }
+
+  async revokeSession(id: string): Promise<void> {
+    await this.fetch(`/auth/sessions/${id}`, { method: 'DELETE' });
+  }
+
+  async revokeAllSessions(): Promise<void> {
+    await this.fetch('/auth/sessions', { method: 'DELETE' });
+  }
+
+  async listApiTokens(): Promise<ApiToken[]> {
+    const response = await this.fetch<{ tokens: ApiToken[] }>('/auth/tokens');
+    return response.tokens;
+  }
+
+  async createApiToken(name: string, scopes: ApiToken['scopes'], expiresInDays?: number): Promise<CreatedApiToken> {
+    return this.fetch<CreatedApiToken>('/auth/tokens', {
+      method: 'POST',
+      body: JSON.stringify({ name, scopes, expiresInDays }),
+    });
+  }
+
+  async revokeApiToken(id: string): Promise<void> {
+    await this.fetch(`/auth/tokens/${id}`, { method: 'DELETE' });
+  }
+
   // Library
   async getLibraryStats(): Promise<LibraryStats> {
     return this.fetch<LibraryStats>('/library/stats');
@@ -58,13 +97,17 @@ class ApiClient {
     });
   }
 
-  async executeSortPlan(dryRun: boolean, enabledGroups: string[] = [], disabledPlaylists: string[] = []): Promise<void> {
+  async executeSortPlan(dryRun: boolean, enabledGroups: string[] = [], disabledPlaylists: string[] = [], planId?: string): Promise<void> {
     await this.fetch(`/sort/execute`, {
       method: 'POST',
-      body: JSON.stringify({ dryRun, enabledGroups, disabledPlaylists }),
+      body: JSON.stringify({ planId, dryRun, enabledGroups, disabledPlaylists }),
     });
   }
 
+  async cancelSort(): Promise<void> {
+    await this.fetch('/sort/cancel', { method: 'POST' });
+  }
+
   // SSE for progress
   createEventSource(endpoint: string): EventSource {
     return new EventSource(`${API_BASE}${endpoint}`, {
@@ -13,6 +13,7 @@ export interface Track {
 }
 
 export interface TrackMove {
+  id: string;
   trackId: string;
   trackName: string;
   artistName: string;
@@ -21,6 +22,7 @@ export interface TrackMove {
   fromPlaylist: string;
   toPlaylist: string;
   reason: string;
+  approved: boolean;
 }
 
 export interface GenreStat {
@@ -35,11 +37,37 @@ export interface SortPlan {
   tracksToAdd: TrackMove[];
   tracksToRemove: TrackMove[];
   playlistsToCreate: string[];
+  playlistMerges: PlaylistMerge[];
+  groupMerges: GroupMerge[];
   uncategorizedTracks: Track[];
   genreStats: GenreStat[];
+  rejectedPlaylists: Record<string, boolean>;
+  rejectedUncategorized: Record<string, boolean>;
   groupingSuggestions?: GroupSuggestion[];
 }
 
+export interface PlaylistMerge {
+  id: string;
+  genre: string;
+  survivorId: string;
+  survivorName: string;
+  duplicates: { id: string; name: string; trackCount: number }[];
+  trackIds: string[];
+  approved: boolean;
+}
+
+export interface GroupMerge {
+  id: string;
+  parentGenre: string;
+  targetId: string;
+  targetName: string;
+  targetDescription?: string;
+  rename: boolean;
+  children: { id: string; name: string; genre?: string; trackCount: number }[];
+  trackIds: string[];
+  approved: boolean;
+}
+
 export interface GroupSuggestion {
   parentGenre: string;
   childGenres: string[];
@@ -54,6 +82,29 @@ export interface User {
   imageUrl: string;
 }
 
+export interface UserSession {
+  id: string;
+  userAgent: string;
+  createdAt: string;
+  lastSeenAt: string;
+  expiresAt: string;
+  current: boolean;
+}
+
+export interface ApiToken {
+  id: string;
+  name: string;
+  hint: string;
+  scopes: ('read' | 'execute')[];
+  createdAt: string;
+  lastUsedAt?: string;
+  expiresAt?: string;
+}
+
+export interface CreatedApiToken extends ApiToken {
+  token: string;
+}
+
 export interface LibraryStats {
   totalTracks: number;
   totalPlaylists: number;
@@ -62,6 +113,7 @@ export interface LibraryStats {
 }
 
 export interface ProgressEvent {
+  jobId?: string;
   type: 'progress' | 'complete' | 'error';
   message: string;
   current?: number;